@@ -0,0 +1,310 @@
+// Package client is a typed client for the students API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+	defaultPageSize   = 100
+)
+
+// Client calls the students API. The zero values of its fields are replaced by sensible defaults, so only BaseURL and
+// the credentials need setting.
+type Client struct {
+	// BaseURL is where the API is served, e.g. "http://localhost:8000".
+	BaseURL string
+	// APIKey is sent in the X-API-Key header. Token, when set, is sent as a bearer token instead.
+	APIKey string
+	Token  string
+	// HTTPClient sends the requests, http.DefaultClient by default.
+	HTTPClient *http.Client
+
+	// MaxRetries is how many times a request answered with a 429, 500, 502, 503 or 504, or that failed to be sent, is
+	// retried. Mutations are sent with an Idempotency-Key, so they're safe to retry. Defaults to 3, negative values
+	// disable retries.
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled on every retry up to MaxBackoff. Each delay is jittered,
+	// picked at random up to its full length. A Retry-After header takes precedence, up to MaxBackoff. Default to
+	// 100ms and 5s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// New returns a client for the API at baseURL, authenticated with an API key.
+func New(baseURL, apiKey string) *Client {
+	return &Client{BaseURL: baseURL, APIKey: apiKey}
+}
+
+// Create creates a student. Only its name and age are sent.
+func (c *Client) Create(ctx context.Context, s student.Student) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/students/add", nil, studentBody(s), nil)
+}
+
+// Get reads a live student.
+func (c *Client) Get(ctx context.Context, id int) (*student.Student, error) {
+	var s student.Student
+	if err := c.do(ctx, http.MethodGet, studentPath(id), nil, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Update replaces a student's name and age.
+func (c *Client) Update(ctx context.Context, id int, s student.Student) error {
+	return c.do(ctx, http.MethodPatch, studentPath(id), nil, studentBody(s), nil)
+}
+
+// Delete moves a student to the trash.
+func (c *Client) Delete(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, studentPath(id), nil, nil, nil)
+}
+
+// List lists the students opts asks for, a single page of them when opts.Limit is set.
+func (c *Client) List(ctx context.Context, opts student.ListOptions) ([]student.Student, error) {
+	var students []student.Student
+	if err := c.do(ctx, http.MethodGet, "/api/v1/students", listQuery(opts), nil, &students); err != nil {
+		return nil, err
+	}
+	return students, nil
+}
+
+// Search lists the students matching q, most relevant first, a single page of them when opts.Limit is set. Only
+// opts.AfterId and opts.Limit are used.
+func (c *Client) Search(ctx context.Context, q string, opts student.ListOptions) ([]student.SearchResult, error) {
+	query := listQuery(student.ListOptions{AfterId: opts.AfterId, Limit: opts.Limit})
+	query.Set("q", q)
+
+	var results []student.SearchResult
+	if err := c.do(ctx, http.MethodGet, "/api/v1/students/search", query, nil, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// All iterates over every student opts asks for, fetching them a page of opts.Limit (100 by default) at a time.
+// Iteration stops at the first error.
+func (c *Client) All(ctx context.Context, opts student.ListOptions) iter.Seq2[student.Student, error] {
+	if opts.Limit < 1 {
+		opts.Limit = defaultPageSize
+	}
+
+	return func(yield func(student.Student, error) bool) {
+		for {
+			page, err := c.List(ctx, opts)
+			if err != nil {
+				yield(student.Student{}, err)
+				return
+			}
+
+			for _, s := range page {
+				if !yield(s, nil) {
+					return
+				}
+			}
+			// the server caps the page size, so a short page isn't necessarily the last one. An empty one is.
+			if len(page) == 0 {
+				return
+			}
+			opts.AfterId = page[len(page)-1].Id
+		}
+	}
+}
+
+func studentPath(id int) string {
+	return "/api/v1/students/" + strconv.Itoa(id)
+}
+
+func studentBody(s student.Student) any {
+	return struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}{s.Name, s.Age}
+}
+
+func listQuery(opts student.ListOptions) url.Values {
+	q := url.Values{}
+	if opts.OnlyDeleted {
+		q.Set("deleted", "only")
+	}
+	if opts.AfterId > 0 {
+		q.Set("after_id", strconv.Itoa(opts.AfterId))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	return q
+}
+
+// do sends a request, retrying it if need be, and decodes a JSON response into out, unless it's nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	target := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	// the same key is sent with every attempt, so that a mutation that went through isn't made twice.
+	var idempotencyKey string
+	if method != http.MethodGet {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	maxRetries := c.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.send(ctx, method, target, body, idempotencyKey)
+		if err == nil && !retryable(response.StatusCode) {
+			defer response.Body.Close()
+			return decodeResponse(response, out)
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			retryAfter = parseRetryAfter(response.Header.Get("Retry-After"))
+			if attempt >= maxRetries {
+				defer response.Body.Close()
+				return decodeResponse(response, out)
+			}
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+		} else if ctx.Err() != nil || attempt >= maxRetries {
+			return err
+		}
+
+		if err := sleep(ctx, c.delay(attempt, retryAfter)); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, target string, body []byte, idempotencyKey string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		request.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.APIKey != "" {
+		request.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(request)
+}
+
+// retryable reports whether a response is worth trying again. 501 and 505 won't go away by retrying, so they're not.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay is how long to wait before retrying after attempt: what the server asked for, or a jittered exponential
+// backoff.
+func (c *Client) delay(attempt int, retryAfter time.Duration) time.Duration {
+	backoff, maxBackoff := c.Backoff, c.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	if retryAfter > 0 {
+		return min(retryAfter, maxBackoff)
+	}
+
+	d := min(backoff<<attempt, maxBackoff)
+	if d <= 0 {
+		d = maxBackoff
+	}
+	return mathrand.N(d) + 1
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or as an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func decodeResponse(response *http.Response, out any) error {
+	if response.StatusCode >= 400 {
+		return newError(response)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// Errors responses can be matched against with errors.Is, by status.
+var (
+	ErrBadRequest           = errors.New("bad request")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrNotFound             = errors.New("not found")
+	ErrNotAcceptable        = errors.New("not acceptable")
+	ErrConflict             = errors.New("conflict")
+	ErrGone                 = errors.New("gone")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrUnprocessable        = errors.New("unprocessable")
+	ErrRateLimited          = errors.New("rate limited")
+	ErrServer               = errors.New("server error")
+	ErrNotImplemented       = errors.New("not implemented")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:           ErrBadRequest,
+	http.StatusUnauthorized:         ErrUnauthorized,
+	http.StatusForbidden:            ErrForbidden,
+	http.StatusNotFound:             ErrNotFound,
+	http.StatusNotAcceptable:        ErrNotAcceptable,
+	http.StatusConflict:             ErrConflict,
+	http.StatusGone:                 ErrGone,
+	http.StatusUnsupportedMediaType: ErrUnsupportedMediaType,
+	http.StatusUnprocessableEntity:  ErrUnprocessable,
+	http.StatusTooManyRequests:      ErrRateLimited,
+	http.StatusNotImplemented:       ErrNotImplemented,
+}
+
+// Error is an error response from the API. Structured errors (see student.ErrorResponse) carry a Code to act on, e.g.
+// "forbidden" or "invalid_request", and sometimes Details. The others only have a Message.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    map[string]any
+	// RequestId is the X-Request-ID of the request, to find it in the server's logs with.
+	RequestId string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("students api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is matches the error against the Err variables for its status. ErrServer matches every 5xx.
+func (e *Error) Is(target error) bool {
+	if target == ErrServer {
+		return e.StatusCode >= 500
+	}
+	return statusErrors[e.StatusCode] == target
+}
+
+func newError(response *http.Response) error {
+	e := &Error{StatusCode: response.StatusCode, RequestId: response.Header.Get("X-Request-ID")}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 64<<10))
+	if err != nil {
+		return e
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	var structured student.ErrorResponse
+	if mediaType == "application/json" && json.Unmarshal(body, &structured) == nil && structured.Error != "" {
+		e.Code, e.Message, e.Details = structured.Error, structured.Message, structured.Details
+		return e
+	}
+
+	e.Message = strings.TrimSpace(string(body))
+	return e
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+const apiKeyUsage = `usage:
+  main apikey create -name <name> [-scopes students:read,students:write]
+  main apikey list
+  main apikey revoke <id>`
+
+// runAPIKeyCommand implements the `apikey` subcommands used to manage API keys out of band, since there's no way to
+// authenticate against the API before the first key exists.
+func runAPIKeyCommand(keys student.APIKeyStore, args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := fs.String("name", "", "name to identify the key by")
+		scopes := fs.String("scopes", student.ScopeStudentsRead, "comma separated list of scopes")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New("-name is required")
+		}
+
+		plain, key, err := student.GenerateAPIKey(*name, strings.Split(*scopes, ","))
+		if err != nil {
+			return err
+		}
+		id, err := keys.CreateAPIKey(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("created api key %d (%s)\n", id, key.Name)
+		fmt.Println("store it somewhere safe, it won't be shown again:")
+		fmt.Println(plain)
+		return nil
+
+	case "list":
+		list, err := keys.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\tREVOKED")
+		for _, k := range list {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", k.Id, k.Name, k.Prefix, strings.Join(k.Scopes, ","),
+				k.CreatedAt.Format(time.RFC3339), formatOptionalTime(k.LastUsedAt), formatOptionalTime(k.RevokedAt))
+		}
+		return tw.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(apiKeyUsage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid api key id %q", args[1])
+		}
+		if err := keys.RevokeAPIKey(ctx, id); err != nil {
+			return err
+		}
+
+		fmt.Printf("revoked api key %d\n", id)
+		return nil
+
+	default:
+		return errors.New(apiKeyUsage)
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
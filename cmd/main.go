@@ -1,32 +1,92 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"os"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
 )
 
-func NewRequestMultiplexer(server *student.Server) http.Handler {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/api/v1/students", server.ListStudents)
-	mux.HandleFunc("/api/v1/students/add", server.CreateStudent)
-	mux.HandleFunc("/api/v1/students/{id}", server.StudentHandler)
-	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	})
-	return mux
-}
-
 func main() {
 	pgStore := student.NewPostgresDataStore()
 	defer pgStore.Pool.Close()
 
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "apikey":
+			err = runAPIKeyCommand(pgStore, os.Args[2:])
+		case "purge":
+			err = runPurgeCommand(pgStore, os.Args[2:])
+		default:
+			err = fmt.Errorf("unknown command %q", os.Args[1])
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	server := student.NewServer(pgStore)
+	server.JWT = student.NewJWTVerifier()
+	server.Policy = student.NewPolicy()
+	server.IdempotencyTTL = student.NewIdempotencyTTL()
+	server.Validator = student.NewOpenAPIValidator()
+
+	if retention, ok := student.PurgeRetention(); ok {
+		go student.PurgeTrash(context.Background(), pgStore, retention, time.Hour, server.Logger)
+	}
+	go student.ExpireIdempotencyKeys(context.Background(), pgStore, time.Hour, server.Logger)
+
+	// change events are always fanned out to webhooks, and to whichever other sinks are configured.
+	dispatcher := &student.Dispatcher{
+		Outbox:    pgStore,
+		Sinks:     append(student.NewEventSinks(), &student.WebhookSink{Webhooks: pgStore}),
+		Logger:    server.Logger,
+		Retention: student.NewOutboxRetention(),
+	}
+	go dispatcher.Run(context.Background())
+
+	deliverer := &student.WebhookDeliverer{Webhooks: pgStore, Logger: server.Logger}
+	go deliverer.Run(context.Background())
+
+	// event streams follow the outbox, and hear about changes made through any replica.
+	server.Events = student.NewEventBroker(pgStore, server.Logger)
+	if err := server.Events.Start(context.Background()); err != nil {
+		log.Fatalf("unable to start the event broker: %v", err)
+	}
+	go pgStore.ListenForEvents(context.Background(), server.Events.Notify, server.Logger)
+
+	// gRPC is served on its own port when one is configured, and on the http server's otherwise.
+	handler := student.NewRequestMultiplexer(server)
+	grpcServer := student.NewGRPCServer(server)
+	if addr, ok := student.GRPCAddr(); ok {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("unable to listen for grpc: %v", err)
+		}
+
+		fmt.Printf("Running grpc server on %s\n", addr)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				fmt.Println(err)
+			}
+		}()
+	} else {
+		handler = student.MultiplexGRPC(grpcServer, handler)
+	}
+
 	httpServer := &http.Server{
 		Addr:    ":8000",
-		Handler: NewRequestMultiplexer(server),
+		Handler: handler,
 	}
 
 	fmt.Println("Running http server on port 8000")
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// runPurgeCommand implements the `purge` subcommand, which permanently deletes students that have been soft deleted
+// for longer than the retention window.
+func runPurgeCommand(trash student.TrashStore, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	retention := fs.Duration("retention", 30*24*time.Hour, "how long deleted students are kept for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	n, err := trash.PurgeStudents(context.Background(), time.Now().Add(-*retention))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d deleted students\n", n)
+	return nil
+}
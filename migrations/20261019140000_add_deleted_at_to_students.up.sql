@@ -0,0 +1,3 @@
+ALTER TABLE students ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS students_deleted_at_idx ON students (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS student_revisions
\ No newline at end of file
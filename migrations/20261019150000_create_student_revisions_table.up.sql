@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS student_revisions (
+	student_id INTEGER NOT NULL,
+	revision INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	name TEXT NOT NULL,
+	age INTEGER,
+	deleted_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (student_id, revision)
+);
+
+-- students that existed before revisions were tracked start their history at their current state.
+INSERT INTO student_revisions (student_id, revision, action, actor, name, age, deleted_at)
+SELECT id, 1, 'student.created', 'system', name, age, deleted_at FROM students
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS outbox_state
\ No newline at end of file
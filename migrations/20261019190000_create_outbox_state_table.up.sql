@@ -0,0 +1,7 @@
+-- outbox_state has a single row, tracking how far the outbox has been compacted.
+CREATE TABLE IF NOT EXISTS outbox_state (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	compacted_through BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO outbox_state (id) VALUES (1) ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS students_name_trgm_idx;
+DROP INDEX IF EXISTS students_search_idx;
+ALTER TABLE students DROP COLUMN IF EXISTS search;
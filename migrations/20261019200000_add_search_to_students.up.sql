@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE students ADD COLUMN IF NOT EXISTS search TSVECTOR
+	GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED;
+
+CREATE INDEX IF NOT EXISTS students_search_idx ON students USING GIN (search);
+CREATE INDEX IF NOT EXISTS students_name_trgm_idx ON students USING GIN (name gin_trgm_ops);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS courses (
+	id SERIAL PRIMARY KEY,
+	code TEXT NOT NULL UNIQUE,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	capacity INTEGER NOT NULL CHECK (capacity > 0),
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	CHECK (end_date >= start_date)
+);
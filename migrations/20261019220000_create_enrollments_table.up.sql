@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS enrollments (
+	id SERIAL PRIMARY KEY,
+	course_id INTEGER NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+	student_id INTEGER NOT NULL REFERENCES students (id) ON DELETE CASCADE,
+	status TEXT NOT NULL CHECK (status IN ('enrolled', 'waitlisted')),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (course_id, student_id)
+);
+
+CREATE INDEX IF NOT EXISTS enrollments_student_id_idx ON enrollments (student_id);
+CREATE INDEX IF NOT EXISTS enrollments_waitlist_idx ON enrollments (course_id, id) WHERE status = 'waitlisted';
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS assessments (
+	id SERIAL PRIMARY KEY,
+	course_id INTEGER NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+	title TEXT NOT NULL,
+	weight DOUBLE PRECISION NOT NULL CHECK (weight > 0),
+	max_score DOUBLE PRECISION NOT NULL CHECK (max_score > 0)
+);
+
+CREATE INDEX IF NOT EXISTS assessments_course_id_idx ON assessments (course_id);
+
+CREATE TABLE IF NOT EXISTS scores (
+	assessment_id INTEGER NOT NULL REFERENCES assessments (id) ON DELETE CASCADE,
+	student_id INTEGER NOT NULL REFERENCES students (id) ON DELETE CASCADE,
+	score DOUBLE PRECISION NOT NULL CHECK (score >= 0),
+	graded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (assessment_id, student_id)
+);
+
+CREATE INDEX IF NOT EXISTS scores_student_id_idx ON scores (student_id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS class_sessions (
+	id SERIAL PRIMARY KEY,
+	course_id INTEGER NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+	topic TEXT NOT NULL,
+	starts_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS class_sessions_course_id_idx ON class_sessions (course_id, starts_at);
+
+CREATE TABLE IF NOT EXISTS attendance (
+	session_id INTEGER NOT NULL REFERENCES class_sessions (id) ON DELETE CASCADE,
+	student_id INTEGER NOT NULL REFERENCES students (id) ON DELETE CASCADE,
+	status TEXT NOT NULL CHECK (status IN ('present', 'absent', 'late', 'excused')),
+	marked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (session_id, student_id)
+);
+
+CREATE INDEX IF NOT EXISTS attendance_student_id_idx ON attendance (student_id);
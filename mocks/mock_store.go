@@ -1,16 +1,19 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/swagnikdutta/one2n-sre-bootcamp/student (interfaces: Store)
+// Source: github.com/swagnikdutta/one2n-sre-bootcamp/student (interfaces: Store,CourseStore,EnrollmentStore,GradeStore,AttendanceStore,BatchStore,SearchStore,StatsStore,TrashStore,RevisionStore,IdempotencyStore,OutboxStore,WatchStore,StudentStreamer,ImportStore,StudentImport,WebhookStore,APIKeyStore,AuditStore)
 //
 // Generated by this command:
 //
-//	mockgen -destination=mocks/mock_store.go -package=mocks github.com/swagnikdutta/one2n-sre-bootcamp/student Store
+//	mockgen -destination=mocks/mock_store.go -package=mocks github.com/swagnikdutta/one2n-sre-bootcamp/student Store,CourseStore,EnrollmentStore,GradeStore,AttendanceStore,BatchStore,SearchStore,StatsStore,TrashStore,RevisionStore,IdempotencyStore,OutboxStore,WatchStore,StudentStreamer,ImportStore,StudentImport,WebhookStore,APIKeyStore,AuditStore
 //
 
 // Package mocks is a generated GoMock package.
 package mocks
 
 import (
+	context "context"
+	iter "iter"
 	reflect "reflect"
+	time "time"
 
 	student "github.com/swagnikdutta/one2n-sre-bootcamp/student"
 	gomock "go.uber.org/mock/gomock"
@@ -41,73 +44,1518 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 }
 
 // CreateStudent mocks base method.
-func (m *MockStore) CreateStudent(s student.Student) error {
+func (m *MockStore) CreateStudent(ctx context.Context, s student.Student) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateStudent", s)
+	ret := m.ctrl.Call(m, "CreateStudent", ctx, s)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateStudent indicates an expected call of CreateStudent.
-func (mr *MockStoreMockRecorder) CreateStudent(s any) *gomock.Call {
+func (mr *MockStoreMockRecorder) CreateStudent(ctx, s any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStudent", reflect.TypeOf((*MockStore)(nil).CreateStudent), s)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStudent", reflect.TypeOf((*MockStore)(nil).CreateStudent), ctx, s)
 }
 
 // DeleteStudent mocks base method.
-func (m *MockStore) DeleteStudent(id int) error {
+func (m *MockStore) DeleteStudent(ctx context.Context, id int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteStudent", id)
+	ret := m.ctrl.Call(m, "DeleteStudent", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteStudent indicates an expected call of DeleteStudent.
-func (mr *MockStoreMockRecorder) DeleteStudent(id any) *gomock.Call {
+func (mr *MockStoreMockRecorder) DeleteStudent(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStudent", reflect.TypeOf((*MockStore)(nil).DeleteStudent), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStudent", reflect.TypeOf((*MockStore)(nil).DeleteStudent), ctx, id)
 }
 
 // GetStudent mocks base method.
-func (m *MockStore) GetStudent(studentId int) (*student.Student, error) {
+func (m *MockStore) GetStudent(ctx context.Context, studentId int) (*student.Student, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStudent", studentId)
+	ret := m.ctrl.Call(m, "GetStudent", ctx, studentId)
 	ret0, _ := ret[0].(*student.Student)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetStudent indicates an expected call of GetStudent.
-func (mr *MockStoreMockRecorder) GetStudent(studentId any) *gomock.Call {
+func (mr *MockStoreMockRecorder) GetStudent(ctx, studentId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStudent", reflect.TypeOf((*MockStore)(nil).GetStudent), studentId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStudent", reflect.TypeOf((*MockStore)(nil).GetStudent), ctx, studentId)
 }
 
 // ListStudents mocks base method.
-func (m *MockStore) ListStudents() ([]student.Student, error) {
+func (m *MockStore) ListStudents(ctx context.Context, opts student.ListOptions) ([]student.Student, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListStudents")
+	ret := m.ctrl.Call(m, "ListStudents", ctx, opts)
 	ret0, _ := ret[0].([]student.Student)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListStudents indicates an expected call of ListStudents.
-func (mr *MockStoreMockRecorder) ListStudents() *gomock.Call {
+func (mr *MockStoreMockRecorder) ListStudents(ctx, opts any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStudents", reflect.TypeOf((*MockStore)(nil).ListStudents))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStudents", reflect.TypeOf((*MockStore)(nil).ListStudents), ctx, opts)
 }
 
 // UpdateStudent mocks base method.
-func (m *MockStore) UpdateStudent(id int, s student.Student) error {
+func (m *MockStore) UpdateStudent(ctx context.Context, id int, s student.Student) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateStudent", id, s)
+	ret := m.ctrl.Call(m, "UpdateStudent", ctx, id, s)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateStudent indicates an expected call of UpdateStudent.
-func (mr *MockStoreMockRecorder) UpdateStudent(id, s any) *gomock.Call {
+func (mr *MockStoreMockRecorder) UpdateStudent(ctx, id, s any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStudent", reflect.TypeOf((*MockStore)(nil).UpdateStudent), id, s)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStudent", reflect.TypeOf((*MockStore)(nil).UpdateStudent), ctx, id, s)
+}
+
+// MockCourseStore is a mock of CourseStore interface.
+type MockCourseStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockCourseStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockCourseStoreMockRecorder is the mock recorder for MockCourseStore.
+type MockCourseStoreMockRecorder struct {
+	mock *MockCourseStore
+}
+
+// NewMockCourseStore creates a new mock instance.
+func NewMockCourseStore(ctrl *gomock.Controller) *MockCourseStore {
+	mock := &MockCourseStore{ctrl: ctrl}
+	mock.recorder = &MockCourseStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCourseStore) EXPECT() *MockCourseStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateCourse mocks base method.
+func (m *MockCourseStore) CreateCourse(ctx context.Context, c student.Course) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCourse", ctx, c)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCourse indicates an expected call of CreateCourse.
+func (mr *MockCourseStoreMockRecorder) CreateCourse(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCourse", reflect.TypeOf((*MockCourseStore)(nil).CreateCourse), ctx, c)
+}
+
+// DeleteCourse mocks base method.
+func (m *MockCourseStore) DeleteCourse(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCourse", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCourse indicates an expected call of DeleteCourse.
+func (mr *MockCourseStoreMockRecorder) DeleteCourse(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCourse", reflect.TypeOf((*MockCourseStore)(nil).DeleteCourse), ctx, id)
+}
+
+// GetCourse mocks base method.
+func (m *MockCourseStore) GetCourse(ctx context.Context, id int) (*student.Course, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCourse", ctx, id)
+	ret0, _ := ret[0].(*student.Course)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCourse indicates an expected call of GetCourse.
+func (mr *MockCourseStoreMockRecorder) GetCourse(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCourse", reflect.TypeOf((*MockCourseStore)(nil).GetCourse), ctx, id)
+}
+
+// ListCourses mocks base method.
+func (m *MockCourseStore) ListCourses(ctx context.Context, opts student.ListOptions) ([]student.Course, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCourses", ctx, opts)
+	ret0, _ := ret[0].([]student.Course)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCourses indicates an expected call of ListCourses.
+func (mr *MockCourseStoreMockRecorder) ListCourses(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCourses", reflect.TypeOf((*MockCourseStore)(nil).ListCourses), ctx, opts)
+}
+
+// UpdateCourse mocks base method.
+func (m *MockCourseStore) UpdateCourse(ctx context.Context, id int, c student.Course) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCourse", ctx, id, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCourse indicates an expected call of UpdateCourse.
+func (mr *MockCourseStoreMockRecorder) UpdateCourse(ctx, id, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCourse", reflect.TypeOf((*MockCourseStore)(nil).UpdateCourse), ctx, id, c)
+}
+
+// MockEnrollmentStore is a mock of EnrollmentStore interface.
+type MockEnrollmentStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockEnrollmentStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockEnrollmentStoreMockRecorder is the mock recorder for MockEnrollmentStore.
+type MockEnrollmentStoreMockRecorder struct {
+	mock *MockEnrollmentStore
+}
+
+// NewMockEnrollmentStore creates a new mock instance.
+func NewMockEnrollmentStore(ctrl *gomock.Controller) *MockEnrollmentStore {
+	mock := &MockEnrollmentStore{ctrl: ctrl}
+	mock.recorder = &MockEnrollmentStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEnrollmentStore) EXPECT() *MockEnrollmentStoreMockRecorder {
+	return m.recorder
+}
+
+// Enroll mocks base method.
+func (m *MockEnrollmentStore) Enroll(ctx context.Context, courseId, studentId int) (*student.Enrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enroll", ctx, courseId, studentId)
+	ret0, _ := ret[0].(*student.Enrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enroll indicates an expected call of Enroll.
+func (mr *MockEnrollmentStoreMockRecorder) Enroll(ctx, courseId, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enroll", reflect.TypeOf((*MockEnrollmentStore)(nil).Enroll), ctx, courseId, studentId)
+}
+
+// GetEnrollment mocks base method.
+func (m *MockEnrollmentStore) GetEnrollment(ctx context.Context, courseId, studentId int) (*student.Enrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEnrollment", ctx, courseId, studentId)
+	ret0, _ := ret[0].(*student.Enrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEnrollment indicates an expected call of GetEnrollment.
+func (mr *MockEnrollmentStoreMockRecorder) GetEnrollment(ctx, courseId, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEnrollment", reflect.TypeOf((*MockEnrollmentStore)(nil).GetEnrollment), ctx, courseId, studentId)
+}
+
+// ListEnrollments mocks base method.
+func (m *MockEnrollmentStore) ListEnrollments(ctx context.Context, courseId int) ([]student.Enrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEnrollments", ctx, courseId)
+	ret0, _ := ret[0].([]student.Enrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEnrollments indicates an expected call of ListEnrollments.
+func (mr *MockEnrollmentStoreMockRecorder) ListEnrollments(ctx, courseId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEnrollments", reflect.TypeOf((*MockEnrollmentStore)(nil).ListEnrollments), ctx, courseId)
+}
+
+// ListStudentCourses mocks base method.
+func (m *MockEnrollmentStore) ListStudentCourses(ctx context.Context, studentId int) ([]student.StudentCourse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStudentCourses", ctx, studentId)
+	ret0, _ := ret[0].([]student.StudentCourse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListStudentCourses indicates an expected call of ListStudentCourses.
+func (mr *MockEnrollmentStoreMockRecorder) ListStudentCourses(ctx, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStudentCourses", reflect.TypeOf((*MockEnrollmentStore)(nil).ListStudentCourses), ctx, studentId)
+}
+
+// Withdraw mocks base method.
+func (m *MockEnrollmentStore) Withdraw(ctx context.Context, courseId, studentId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Withdraw", ctx, courseId, studentId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Withdraw indicates an expected call of Withdraw.
+func (mr *MockEnrollmentStoreMockRecorder) Withdraw(ctx, courseId, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockEnrollmentStore)(nil).Withdraw), ctx, courseId, studentId)
+}
+
+// MockGradeStore is a mock of GradeStore interface.
+type MockGradeStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockGradeStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockGradeStoreMockRecorder is the mock recorder for MockGradeStore.
+type MockGradeStoreMockRecorder struct {
+	mock *MockGradeStore
+}
+
+// NewMockGradeStore creates a new mock instance.
+func NewMockGradeStore(ctrl *gomock.Controller) *MockGradeStore {
+	mock := &MockGradeStore{ctrl: ctrl}
+	mock.recorder = &MockGradeStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGradeStore) EXPECT() *MockGradeStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateAssessment mocks base method.
+func (m *MockGradeStore) CreateAssessment(ctx context.Context, a student.Assessment) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAssessment", ctx, a)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAssessment indicates an expected call of CreateAssessment.
+func (mr *MockGradeStoreMockRecorder) CreateAssessment(ctx, a any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAssessment", reflect.TypeOf((*MockGradeStore)(nil).CreateAssessment), ctx, a)
+}
+
+// DeleteAssessment mocks base method.
+func (m *MockGradeStore) DeleteAssessment(ctx context.Context, courseId, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAssessment", ctx, courseId, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAssessment indicates an expected call of DeleteAssessment.
+func (mr *MockGradeStoreMockRecorder) DeleteAssessment(ctx, courseId, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAssessment", reflect.TypeOf((*MockGradeStore)(nil).DeleteAssessment), ctx, courseId, id)
+}
+
+// GetAssessment mocks base method.
+func (m *MockGradeStore) GetAssessment(ctx context.Context, courseId, id int) (*student.Assessment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAssessment", ctx, courseId, id)
+	ret0, _ := ret[0].(*student.Assessment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAssessment indicates an expected call of GetAssessment.
+func (mr *MockGradeStoreMockRecorder) GetAssessment(ctx, courseId, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAssessment", reflect.TypeOf((*MockGradeStore)(nil).GetAssessment), ctx, courseId, id)
+}
+
+// ListAssessments mocks base method.
+func (m *MockGradeStore) ListAssessments(ctx context.Context, courseId int) ([]student.Assessment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAssessments", ctx, courseId)
+	ret0, _ := ret[0].([]student.Assessment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAssessments indicates an expected call of ListAssessments.
+func (mr *MockGradeStoreMockRecorder) ListAssessments(ctx, courseId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAssessments", reflect.TypeOf((*MockGradeStore)(nil).ListAssessments), ctx, courseId)
+}
+
+// ListScores mocks base method.
+func (m *MockGradeStore) ListScores(ctx context.Context, courseId, assessmentId int) ([]student.Score, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListScores", ctx, courseId, assessmentId)
+	ret0, _ := ret[0].([]student.Score)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListScores indicates an expected call of ListScores.
+func (mr *MockGradeStoreMockRecorder) ListScores(ctx, courseId, assessmentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScores", reflect.TypeOf((*MockGradeStore)(nil).ListScores), ctx, courseId, assessmentId)
+}
+
+// RecordScores mocks base method.
+func (m *MockGradeStore) RecordScores(ctx context.Context, courseId, assessmentId int, scores []student.Score) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordScores", ctx, courseId, assessmentId, scores)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordScores indicates an expected call of RecordScores.
+func (mr *MockGradeStoreMockRecorder) RecordScores(ctx, courseId, assessmentId, scores any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordScores", reflect.TypeOf((*MockGradeStore)(nil).RecordScores), ctx, courseId, assessmentId, scores)
+}
+
+// StudentGrades mocks base method.
+func (m *MockGradeStore) StudentGrades(ctx context.Context, studentId int) ([]student.CourseGrades, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StudentGrades", ctx, studentId)
+	ret0, _ := ret[0].([]student.CourseGrades)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StudentGrades indicates an expected call of StudentGrades.
+func (mr *MockGradeStoreMockRecorder) StudentGrades(ctx, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StudentGrades", reflect.TypeOf((*MockGradeStore)(nil).StudentGrades), ctx, studentId)
+}
+
+// UpdateAssessment mocks base method.
+func (m *MockGradeStore) UpdateAssessment(ctx context.Context, courseId, id int, a student.Assessment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAssessment", ctx, courseId, id, a)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAssessment indicates an expected call of UpdateAssessment.
+func (mr *MockGradeStoreMockRecorder) UpdateAssessment(ctx, courseId, id, a any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAssessment", reflect.TypeOf((*MockGradeStore)(nil).UpdateAssessment), ctx, courseId, id, a)
+}
+
+// MockAttendanceStore is a mock of AttendanceStore interface.
+type MockAttendanceStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAttendanceStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockAttendanceStoreMockRecorder is the mock recorder for MockAttendanceStore.
+type MockAttendanceStoreMockRecorder struct {
+	mock *MockAttendanceStore
+}
+
+// NewMockAttendanceStore creates a new mock instance.
+func NewMockAttendanceStore(ctrl *gomock.Controller) *MockAttendanceStore {
+	mock := &MockAttendanceStore{ctrl: ctrl}
+	mock.recorder = &MockAttendanceStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAttendanceStore) EXPECT() *MockAttendanceStoreMockRecorder {
+	return m.recorder
+}
+
+// CourseAttendance mocks base method.
+func (m *MockAttendanceStore) CourseAttendance(ctx context.Context, courseId int) ([]student.AttendanceSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CourseAttendance", ctx, courseId)
+	ret0, _ := ret[0].([]student.AttendanceSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CourseAttendance indicates an expected call of CourseAttendance.
+func (mr *MockAttendanceStoreMockRecorder) CourseAttendance(ctx, courseId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CourseAttendance", reflect.TypeOf((*MockAttendanceStore)(nil).CourseAttendance), ctx, courseId)
+}
+
+// CreateSession mocks base method.
+func (m *MockAttendanceStore) CreateSession(ctx context.Context, s student.ClassSession) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, s)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockAttendanceStoreMockRecorder) CreateSession(ctx, s any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockAttendanceStore)(nil).CreateSession), ctx, s)
+}
+
+// DeleteSession mocks base method.
+func (m *MockAttendanceStore) DeleteSession(ctx context.Context, courseId, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, courseId, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockAttendanceStoreMockRecorder) DeleteSession(ctx, courseId, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockAttendanceStore)(nil).DeleteSession), ctx, courseId, id)
+}
+
+// GetSession mocks base method.
+func (m *MockAttendanceStore) GetSession(ctx context.Context, courseId, id int) (*student.ClassSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSession", ctx, courseId, id)
+	ret0, _ := ret[0].(*student.ClassSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSession indicates an expected call of GetSession.
+func (mr *MockAttendanceStoreMockRecorder) GetSession(ctx, courseId, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockAttendanceStore)(nil).GetSession), ctx, courseId, id)
+}
+
+// ListAttendance mocks base method.
+func (m *MockAttendanceStore) ListAttendance(ctx context.Context, courseId, sessionId int) ([]student.AttendanceMark, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAttendance", ctx, courseId, sessionId)
+	ret0, _ := ret[0].([]student.AttendanceMark)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAttendance indicates an expected call of ListAttendance.
+func (mr *MockAttendanceStoreMockRecorder) ListAttendance(ctx, courseId, sessionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAttendance", reflect.TypeOf((*MockAttendanceStore)(nil).ListAttendance), ctx, courseId, sessionId)
+}
+
+// ListSessions mocks base method.
+func (m *MockAttendanceStore) ListSessions(ctx context.Context, courseId int) ([]student.ClassSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, courseId)
+	ret0, _ := ret[0].([]student.ClassSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockAttendanceStoreMockRecorder) ListSessions(ctx, courseId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockAttendanceStore)(nil).ListSessions), ctx, courseId)
+}
+
+// MarkAttendance mocks base method.
+func (m *MockAttendanceStore) MarkAttendance(ctx context.Context, courseId, sessionId int, marks []student.AttendanceMark, others string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAttendance", ctx, courseId, sessionId, marks, others)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkAttendance indicates an expected call of MarkAttendance.
+func (mr *MockAttendanceStoreMockRecorder) MarkAttendance(ctx, courseId, sessionId, marks, others any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAttendance", reflect.TypeOf((*MockAttendanceStore)(nil).MarkAttendance), ctx, courseId, sessionId, marks, others)
+}
+
+// StudentAttendance mocks base method.
+func (m *MockAttendanceStore) StudentAttendance(ctx context.Context, studentId int) ([]student.AttendanceSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StudentAttendance", ctx, studentId)
+	ret0, _ := ret[0].([]student.AttendanceSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StudentAttendance indicates an expected call of StudentAttendance.
+func (mr *MockAttendanceStoreMockRecorder) StudentAttendance(ctx, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StudentAttendance", reflect.TypeOf((*MockAttendanceStore)(nil).StudentAttendance), ctx, studentId)
+}
+
+// UpdateSession mocks base method.
+func (m *MockAttendanceStore) UpdateSession(ctx context.Context, courseId, id int, s student.ClassSession) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSession", ctx, courseId, id, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSession indicates an expected call of UpdateSession.
+func (mr *MockAttendanceStoreMockRecorder) UpdateSession(ctx, courseId, id, s any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSession", reflect.TypeOf((*MockAttendanceStore)(nil).UpdateSession), ctx, courseId, id, s)
+}
+
+// MockBatchStore is a mock of BatchStore interface.
+type MockBatchStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockBatchStoreMockRecorder is the mock recorder for MockBatchStore.
+type MockBatchStoreMockRecorder struct {
+	mock *MockBatchStore
+}
+
+// NewMockBatchStore creates a new mock instance.
+func NewMockBatchStore(ctrl *gomock.Controller) *MockBatchStore {
+	mock := &MockBatchStore{ctrl: ctrl}
+	mock.recorder = &MockBatchStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatchStore) EXPECT() *MockBatchStoreMockRecorder {
+	return m.recorder
+}
+
+// GetStudents mocks base method.
+func (m *MockBatchStore) GetStudents(ctx context.Context, ids []int) ([]student.Student, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStudents", ctx, ids)
+	ret0, _ := ret[0].([]student.Student)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStudents indicates an expected call of GetStudents.
+func (mr *MockBatchStoreMockRecorder) GetStudents(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStudents", reflect.TypeOf((*MockBatchStore)(nil).GetStudents), ctx, ids)
+}
+
+// MockSearchStore is a mock of SearchStore interface.
+type MockSearchStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockSearchStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockSearchStoreMockRecorder is the mock recorder for MockSearchStore.
+type MockSearchStoreMockRecorder struct {
+	mock *MockSearchStore
+}
+
+// NewMockSearchStore creates a new mock instance.
+func NewMockSearchStore(ctrl *gomock.Controller) *MockSearchStore {
+	mock := &MockSearchStore{ctrl: ctrl}
+	mock.recorder = &MockSearchStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSearchStore) EXPECT() *MockSearchStoreMockRecorder {
+	return m.recorder
+}
+
+// SearchStudents mocks base method.
+func (m *MockSearchStore) SearchStudents(ctx context.Context, query string, opts student.ListOptions) ([]student.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchStudents", ctx, query, opts)
+	ret0, _ := ret[0].([]student.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchStudents indicates an expected call of SearchStudents.
+func (mr *MockSearchStoreMockRecorder) SearchStudents(ctx, query, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchStudents", reflect.TypeOf((*MockSearchStore)(nil).SearchStudents), ctx, query, opts)
+}
+
+// MockStatsStore is a mock of StatsStore interface.
+type MockStatsStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStatsStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStatsStoreMockRecorder is the mock recorder for MockStatsStore.
+type MockStatsStoreMockRecorder struct {
+	mock *MockStatsStore
+}
+
+// NewMockStatsStore creates a new mock instance.
+func NewMockStatsStore(ctrl *gomock.Controller) *MockStatsStore {
+	mock := &MockStatsStore{ctrl: ctrl}
+	mock.recorder = &MockStatsStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStatsStore) EXPECT() *MockStatsStoreMockRecorder {
+	return m.recorder
+}
+
+// StudentStats mocks base method.
+func (m *MockStatsStore) StudentStats(ctx context.Context, ageBuckets []int) (*student.StudentStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StudentStats", ctx, ageBuckets)
+	ret0, _ := ret[0].(*student.StudentStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StudentStats indicates an expected call of StudentStats.
+func (mr *MockStatsStoreMockRecorder) StudentStats(ctx, ageBuckets any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StudentStats", reflect.TypeOf((*MockStatsStore)(nil).StudentStats), ctx, ageBuckets)
+}
+
+// MockTrashStore is a mock of TrashStore interface.
+type MockTrashStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockTrashStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockTrashStoreMockRecorder is the mock recorder for MockTrashStore.
+type MockTrashStoreMockRecorder struct {
+	mock *MockTrashStore
+}
+
+// NewMockTrashStore creates a new mock instance.
+func NewMockTrashStore(ctrl *gomock.Controller) *MockTrashStore {
+	mock := &MockTrashStore{ctrl: ctrl}
+	mock.recorder = &MockTrashStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTrashStore) EXPECT() *MockTrashStoreMockRecorder {
+	return m.recorder
+}
+
+// PurgeStudents mocks base method.
+func (m *MockTrashStore) PurgeStudents(ctx context.Context, deletedBefore time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeStudents", ctx, deletedBefore)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeStudents indicates an expected call of PurgeStudents.
+func (mr *MockTrashStoreMockRecorder) PurgeStudents(ctx, deletedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeStudents", reflect.TypeOf((*MockTrashStore)(nil).PurgeStudents), ctx, deletedBefore)
+}
+
+// RestoreStudent mocks base method.
+func (m *MockTrashStore) RestoreStudent(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreStudent", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreStudent indicates an expected call of RestoreStudent.
+func (mr *MockTrashStoreMockRecorder) RestoreStudent(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreStudent", reflect.TypeOf((*MockTrashStore)(nil).RestoreStudent), ctx, id)
+}
+
+// MockRevisionStore is a mock of RevisionStore interface.
+type MockRevisionStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockRevisionStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockRevisionStoreMockRecorder is the mock recorder for MockRevisionStore.
+type MockRevisionStoreMockRecorder struct {
+	mock *MockRevisionStore
+}
+
+// NewMockRevisionStore creates a new mock instance.
+func NewMockRevisionStore(ctrl *gomock.Controller) *MockRevisionStore {
+	mock := &MockRevisionStore{ctrl: ctrl}
+	mock.recorder = &MockRevisionStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRevisionStore) EXPECT() *MockRevisionStoreMockRecorder {
+	return m.recorder
+}
+
+// GetStudentAsOf mocks base method.
+func (m *MockRevisionStore) GetStudentAsOf(ctx context.Context, studentId int, asOf time.Time) (*student.Student, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStudentAsOf", ctx, studentId, asOf)
+	ret0, _ := ret[0].(*student.Student)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStudentAsOf indicates an expected call of GetStudentAsOf.
+func (mr *MockRevisionStoreMockRecorder) GetStudentAsOf(ctx, studentId, asOf any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStudentAsOf", reflect.TypeOf((*MockRevisionStore)(nil).GetStudentAsOf), ctx, studentId, asOf)
+}
+
+// ListRevisions mocks base method.
+func (m *MockRevisionStore) ListRevisions(ctx context.Context, studentId int) ([]student.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRevisions", ctx, studentId)
+	ret0, _ := ret[0].([]student.Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRevisions indicates an expected call of ListRevisions.
+func (mr *MockRevisionStoreMockRecorder) ListRevisions(ctx, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRevisions", reflect.TypeOf((*MockRevisionStore)(nil).ListRevisions), ctx, studentId)
+}
+
+// ListRevisionsOf mocks base method.
+func (m *MockRevisionStore) ListRevisionsOf(ctx context.Context, studentIds []int) (map[int][]student.Revision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRevisionsOf", ctx, studentIds)
+	ret0, _ := ret[0].(map[int][]student.Revision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRevisionsOf indicates an expected call of ListRevisionsOf.
+func (mr *MockRevisionStoreMockRecorder) ListRevisionsOf(ctx, studentIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRevisionsOf", reflect.TypeOf((*MockRevisionStore)(nil).ListRevisionsOf), ctx, studentIds)
+}
+
+// RevertStudent mocks base method.
+func (m *MockRevisionStore) RevertStudent(ctx context.Context, studentId, revision int, trash bool) (*student.Student, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertStudent", ctx, studentId, revision, trash)
+	ret0, _ := ret[0].(*student.Student)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevertStudent indicates an expected call of RevertStudent.
+func (mr *MockRevisionStoreMockRecorder) RevertStudent(ctx, studentId, revision, trash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertStudent", reflect.TypeOf((*MockRevisionStore)(nil).RevertStudent), ctx, studentId, revision, trash)
+}
+
+// MockIdempotencyStore is a mock of IdempotencyStore interface.
+type MockIdempotencyStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockIdempotencyStoreMockRecorder is the mock recorder for MockIdempotencyStore.
+type MockIdempotencyStoreMockRecorder struct {
+	mock *MockIdempotencyStore
+}
+
+// NewMockIdempotencyStore creates a new mock instance.
+func NewMockIdempotencyStore(ctrl *gomock.Controller) *MockIdempotencyStore {
+	mock := &MockIdempotencyStore{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyStore) EXPECT() *MockIdempotencyStoreMockRecorder {
+	return m.recorder
+}
+
+// CompleteIdempotencyKey mocks base method.
+func (m *MockIdempotencyStore) CompleteIdempotencyKey(ctx context.Context, record student.IdempotencyRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteIdempotencyKey", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotencyKey indicates an expected call of CompleteIdempotencyKey.
+func (mr *MockIdempotencyStoreMockRecorder) CompleteIdempotencyKey(ctx, record any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotencyKey", reflect.TypeOf((*MockIdempotencyStore)(nil).CompleteIdempotencyKey), ctx, record)
+}
+
+// DeleteExpiredIdempotencyKeys mocks base method.
+func (m *MockIdempotencyStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredIdempotencyKeys", ctx, now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredIdempotencyKeys indicates an expected call of DeleteExpiredIdempotencyKeys.
+func (mr *MockIdempotencyStoreMockRecorder) DeleteExpiredIdempotencyKeys(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredIdempotencyKeys", reflect.TypeOf((*MockIdempotencyStore)(nil).DeleteExpiredIdempotencyKeys), ctx, now)
+}
+
+// ReleaseIdempotencyKey mocks base method.
+func (m *MockIdempotencyStore) ReleaseIdempotencyKey(ctx context.Context, principal, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIdempotencyKey", ctx, principal, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIdempotencyKey indicates an expected call of ReleaseIdempotencyKey.
+func (mr *MockIdempotencyStoreMockRecorder) ReleaseIdempotencyKey(ctx, principal, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIdempotencyKey", reflect.TypeOf((*MockIdempotencyStore)(nil).ReleaseIdempotencyKey), ctx, principal, key)
+}
+
+// ReserveIdempotencyKey mocks base method.
+func (m *MockIdempotencyStore) ReserveIdempotencyKey(ctx context.Context, record student.IdempotencyRecord) (*student.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveIdempotencyKey", ctx, record)
+	ret0, _ := ret[0].(*student.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveIdempotencyKey indicates an expected call of ReserveIdempotencyKey.
+func (mr *MockIdempotencyStoreMockRecorder) ReserveIdempotencyKey(ctx, record any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveIdempotencyKey", reflect.TypeOf((*MockIdempotencyStore)(nil).ReserveIdempotencyKey), ctx, record)
+}
+
+// MockOutboxStore is a mock of OutboxStore interface.
+type MockOutboxStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockOutboxStoreMockRecorder is the mock recorder for MockOutboxStore.
+type MockOutboxStoreMockRecorder struct {
+	mock *MockOutboxStore
+}
+
+// NewMockOutboxStore creates a new mock instance.
+func NewMockOutboxStore(ctrl *gomock.Controller) *MockOutboxStore {
+	mock := &MockOutboxStore{ctrl: ctrl}
+	mock.recorder = &MockOutboxStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxStore) EXPECT() *MockOutboxStoreMockRecorder {
+	return m.recorder
+}
+
+// DeleteDispatchedEvents mocks base method.
+func (m *MockOutboxStore) DeleteDispatchedEvents(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDispatchedEvents", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteDispatchedEvents indicates an expected call of DeleteDispatchedEvents.
+func (mr *MockOutboxStoreMockRecorder) DeleteDispatchedEvents(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDispatchedEvents", reflect.TypeOf((*MockOutboxStore)(nil).DeleteDispatchedEvents), ctx, before)
+}
+
+// EventsAfter mocks base method.
+func (m *MockOutboxStore) EventsAfter(ctx context.Context, afterId int64, limit int) ([]student.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EventsAfter", ctx, afterId, limit)
+	ret0, _ := ret[0].([]student.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EventsAfter indicates an expected call of EventsAfter.
+func (mr *MockOutboxStoreMockRecorder) EventsAfter(ctx, afterId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EventsAfter", reflect.TypeOf((*MockOutboxStore)(nil).EventsAfter), ctx, afterId, limit)
+}
+
+// LatestEventId mocks base method.
+func (m *MockOutboxStore) LatestEventId(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestEventId", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LatestEventId indicates an expected call of LatestEventId.
+func (mr *MockOutboxStoreMockRecorder) LatestEventId(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestEventId", reflect.TypeOf((*MockOutboxStore)(nil).LatestEventId), ctx)
+}
+
+// MarkEventsDispatched mocks base method.
+func (m *MockOutboxStore) MarkEventsDispatched(ctx context.Context, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEventsDispatched", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEventsDispatched indicates an expected call of MarkEventsDispatched.
+func (mr *MockOutboxStoreMockRecorder) MarkEventsDispatched(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEventsDispatched", reflect.TypeOf((*MockOutboxStore)(nil).MarkEventsDispatched), ctx, ids)
+}
+
+// PendingEvents mocks base method.
+func (m *MockOutboxStore) PendingEvents(ctx context.Context, limit int, skip []int) ([]student.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingEvents", ctx, limit, skip)
+	ret0, _ := ret[0].([]student.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingEvents indicates an expected call of PendingEvents.
+func (mr *MockOutboxStoreMockRecorder) PendingEvents(ctx, limit, skip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingEvents", reflect.TypeOf((*MockOutboxStore)(nil).PendingEvents), ctx, limit, skip)
+}
+
+// MockWatchStore is a mock of WatchStore interface.
+type MockWatchStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockWatchStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockWatchStoreMockRecorder is the mock recorder for MockWatchStore.
+type MockWatchStoreMockRecorder struct {
+	mock *MockWatchStore
+}
+
+// NewMockWatchStore creates a new mock instance.
+func NewMockWatchStore(ctrl *gomock.Controller) *MockWatchStore {
+	mock := &MockWatchStore{ctrl: ctrl}
+	mock.recorder = &MockWatchStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWatchStore) EXPECT() *MockWatchStoreMockRecorder {
+	return m.recorder
+}
+
+// CompactedEventId mocks base method.
+func (m *MockWatchStore) CompactedEventId(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompactedEventId", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompactedEventId indicates an expected call of CompactedEventId.
+func (mr *MockWatchStoreMockRecorder) CompactedEventId(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompactedEventId", reflect.TypeOf((*MockWatchStore)(nil).CompactedEventId), ctx)
+}
+
+// ListStudentsVersioned mocks base method.
+func (m *MockWatchStore) ListStudentsVersioned(ctx context.Context, opts student.ListOptions) ([]student.Student, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStudentsVersioned", ctx, opts)
+	ret0, _ := ret[0].([]student.Student)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListStudentsVersioned indicates an expected call of ListStudentsVersioned.
+func (mr *MockWatchStoreMockRecorder) ListStudentsVersioned(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStudentsVersioned", reflect.TypeOf((*MockWatchStore)(nil).ListStudentsVersioned), ctx, opts)
+}
+
+// MockStudentStreamer is a mock of StudentStreamer interface.
+type MockStudentStreamer struct {
+	ctrl     *gomock.Controller
+	recorder *MockStudentStreamerMockRecorder
+	isgomock struct{}
+}
+
+// MockStudentStreamerMockRecorder is the mock recorder for MockStudentStreamer.
+type MockStudentStreamerMockRecorder struct {
+	mock *MockStudentStreamer
+}
+
+// NewMockStudentStreamer creates a new mock instance.
+func NewMockStudentStreamer(ctrl *gomock.Controller) *MockStudentStreamer {
+	mock := &MockStudentStreamer{ctrl: ctrl}
+	mock.recorder = &MockStudentStreamerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStudentStreamer) EXPECT() *MockStudentStreamerMockRecorder {
+	return m.recorder
+}
+
+// StreamStudents mocks base method.
+func (m *MockStudentStreamer) StreamStudents(ctx context.Context, opts student.ListOptions) iter.Seq2[student.Student, error] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamStudents", ctx, opts)
+	ret0, _ := ret[0].(iter.Seq2[student.Student, error])
+	return ret0
+}
+
+// StreamStudents indicates an expected call of StreamStudents.
+func (mr *MockStudentStreamerMockRecorder) StreamStudents(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamStudents", reflect.TypeOf((*MockStudentStreamer)(nil).StreamStudents), ctx, opts)
+}
+
+// MockImportStore is a mock of ImportStore interface.
+type MockImportStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockImportStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockImportStoreMockRecorder is the mock recorder for MockImportStore.
+type MockImportStoreMockRecorder struct {
+	mock *MockImportStore
+}
+
+// NewMockImportStore creates a new mock instance.
+func NewMockImportStore(ctrl *gomock.Controller) *MockImportStore {
+	mock := &MockImportStore{ctrl: ctrl}
+	mock.recorder = &MockImportStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImportStore) EXPECT() *MockImportStoreMockRecorder {
+	return m.recorder
+}
+
+// BeginImport mocks base method.
+func (m *MockImportStore) BeginImport(ctx context.Context) (student.StudentImport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginImport", ctx)
+	ret0, _ := ret[0].(student.StudentImport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginImport indicates an expected call of BeginImport.
+func (mr *MockImportStoreMockRecorder) BeginImport(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginImport", reflect.TypeOf((*MockImportStore)(nil).BeginImport), ctx)
+}
+
+// MockStudentImport is a mock of StudentImport interface.
+type MockStudentImport struct {
+	ctrl     *gomock.Controller
+	recorder *MockStudentImportMockRecorder
+	isgomock struct{}
+}
+
+// MockStudentImportMockRecorder is the mock recorder for MockStudentImport.
+type MockStudentImportMockRecorder struct {
+	mock *MockStudentImport
+}
+
+// NewMockStudentImport creates a new mock instance.
+func NewMockStudentImport(ctrl *gomock.Controller) *MockStudentImport {
+	mock := &MockStudentImport{ctrl: ctrl}
+	mock.recorder = &MockStudentImportMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStudentImport) EXPECT() *MockStudentImportMockRecorder {
+	return m.recorder
+}
+
+// Commit mocks base method.
+func (m *MockStudentImport) Commit(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Commit", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Commit indicates an expected call of Commit.
+func (mr *MockStudentImportMockRecorder) Commit(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockStudentImport)(nil).Commit), ctx)
+}
+
+// Insert mocks base method.
+func (m *MockStudentImport) Insert(ctx context.Context, students []student.Student) ([]student.Student, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, students)
+	ret0, _ := ret[0].([]student.Student)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockStudentImportMockRecorder) Insert(ctx, students any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockStudentImport)(nil).Insert), ctx, students)
+}
+
+// Rollback mocks base method.
+func (m *MockStudentImport) Rollback(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rollback", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rollback indicates an expected call of Rollback.
+func (mr *MockStudentImportMockRecorder) Rollback(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rollback", reflect.TypeOf((*MockStudentImport)(nil).Rollback), ctx)
+}
+
+// MockWebhookStore is a mock of WebhookStore interface.
+type MockWebhookStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockWebhookStoreMockRecorder is the mock recorder for MockWebhookStore.
+type MockWebhookStoreMockRecorder struct {
+	mock *MockWebhookStore
+}
+
+// NewMockWebhookStore creates a new mock instance.
+func NewMockWebhookStore(ctrl *gomock.Controller) *MockWebhookStore {
+	mock := &MockWebhookStore{ctrl: ctrl}
+	mock.recorder = &MockWebhookStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookStore) EXPECT() *MockWebhookStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateWebhook mocks base method.
+func (m *MockWebhookStore) CreateWebhook(ctx context.Context, wh student.Webhook) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", ctx, wh)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockWebhookStoreMockRecorder) CreateWebhook(ctx, wh any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockWebhookStore)(nil).CreateWebhook), ctx, wh)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockWebhookStore) DeleteWebhook(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockWebhookStoreMockRecorder) DeleteWebhook(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockWebhookStore)(nil).DeleteWebhook), ctx, id)
+}
+
+// DueWebhookDeliveries mocks base method.
+func (m *MockWebhookStore) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]student.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DueWebhookDeliveries", ctx, now, limit)
+	ret0, _ := ret[0].([]student.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DueWebhookDeliveries indicates an expected call of DueWebhookDeliveries.
+func (mr *MockWebhookStoreMockRecorder) DueWebhookDeliveries(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DueWebhookDeliveries", reflect.TypeOf((*MockWebhookStore)(nil).DueWebhookDeliveries), ctx, now, limit)
+}
+
+// EnqueueWebhookDeliveries mocks base method.
+func (m *MockWebhookStore) EnqueueWebhookDeliveries(ctx context.Context, e student.Event, payload []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueWebhookDeliveries", ctx, e, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnqueueWebhookDeliveries indicates an expected call of EnqueueWebhookDeliveries.
+func (mr *MockWebhookStoreMockRecorder) EnqueueWebhookDeliveries(ctx, e, payload any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueWebhookDeliveries", reflect.TypeOf((*MockWebhookStore)(nil).EnqueueWebhookDeliveries), ctx, e, payload)
+}
+
+// GetWebhook mocks base method.
+func (m *MockWebhookStore) GetWebhook(ctx context.Context, id int) (*student.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhook", ctx, id)
+	ret0, _ := ret[0].(*student.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhook indicates an expected call of GetWebhook.
+func (mr *MockWebhookStoreMockRecorder) GetWebhook(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhook", reflect.TypeOf((*MockWebhookStore)(nil).GetWebhook), ctx, id)
+}
+
+// ListDeadWebhookDeliveries mocks base method.
+func (m *MockWebhookStore) ListDeadWebhookDeliveries(ctx context.Context) ([]student.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeadWebhookDeliveries", ctx)
+	ret0, _ := ret[0].([]student.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeadWebhookDeliveries indicates an expected call of ListDeadWebhookDeliveries.
+func (mr *MockWebhookStoreMockRecorder) ListDeadWebhookDeliveries(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeadWebhookDeliveries", reflect.TypeOf((*MockWebhookStore)(nil).ListDeadWebhookDeliveries), ctx)
+}
+
+// ListWebhookDeliveries mocks base method.
+func (m *MockWebhookStore) ListWebhookDeliveries(ctx context.Context, webhookId int) ([]student.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookDeliveries", ctx, webhookId)
+	ret0, _ := ret[0].([]student.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookDeliveries indicates an expected call of ListWebhookDeliveries.
+func (mr *MockWebhookStoreMockRecorder) ListWebhookDeliveries(ctx, webhookId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookDeliveries", reflect.TypeOf((*MockWebhookStore)(nil).ListWebhookDeliveries), ctx, webhookId)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockWebhookStore) ListWebhooks(ctx context.Context) ([]student.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx)
+	ret0, _ := ret[0].([]student.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockWebhookStoreMockRecorder) ListWebhooks(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockWebhookStore)(nil).ListWebhooks), ctx)
+}
+
+// RecordWebhookAttempt mocks base method.
+func (m *MockWebhookStore) RecordWebhookAttempt(ctx context.Context, d student.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordWebhookAttempt", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordWebhookAttempt indicates an expected call of RecordWebhookAttempt.
+func (mr *MockWebhookStoreMockRecorder) RecordWebhookAttempt(ctx, d any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordWebhookAttempt", reflect.TypeOf((*MockWebhookStore)(nil).RecordWebhookAttempt), ctx, d)
+}
+
+// RetryWebhookDelivery mocks base method.
+func (m *MockWebhookStore) RetryWebhookDelivery(ctx context.Context, webhookId int, deliveryId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetryWebhookDelivery", ctx, webhookId, deliveryId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RetryWebhookDelivery indicates an expected call of RetryWebhookDelivery.
+func (mr *MockWebhookStoreMockRecorder) RetryWebhookDelivery(ctx, webhookId, deliveryId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryWebhookDelivery", reflect.TypeOf((*MockWebhookStore)(nil).RetryWebhookDelivery), ctx, webhookId, deliveryId)
+}
+
+// MockAPIKeyStore is a mock of APIKeyStore interface.
+type MockAPIKeyStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockAPIKeyStoreMockRecorder is the mock recorder for MockAPIKeyStore.
+type MockAPIKeyStoreMockRecorder struct {
+	mock *MockAPIKeyStore
+}
+
+// NewMockAPIKeyStore creates a new mock instance.
+func NewMockAPIKeyStore(ctrl *gomock.Controller) *MockAPIKeyStore {
+	mock := &MockAPIKeyStore{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyStore) EXPECT() *MockAPIKeyStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockAPIKeyStore) CreateAPIKey(ctx context.Context, k student.APIKey) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, k)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockAPIKeyStoreMockRecorder) CreateAPIKey(ctx, k any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockAPIKeyStore)(nil).CreateAPIKey), ctx, k)
+}
+
+// GetAPIKeyByHash mocks base method.
+func (m *MockAPIKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (*student.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", ctx, hash)
+	ret0, _ := ret[0].(*student.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockAPIKeyStoreMockRecorder) GetAPIKeyByHash(ctx, hash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockAPIKeyStore)(nil).GetAPIKeyByHash), ctx, hash)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockAPIKeyStore) ListAPIKeys(ctx context.Context) ([]student.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx)
+	ret0, _ := ret[0].([]student.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockAPIKeyStoreMockRecorder) ListAPIKeys(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockAPIKeyStore)(nil).ListAPIKeys), ctx)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockAPIKeyStore) RevokeAPIKey(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockAPIKeyStoreMockRecorder) RevokeAPIKey(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockAPIKeyStore)(nil).RevokeAPIKey), ctx, id)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockAPIKeyStore) TouchAPIKey(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockAPIKeyStoreMockRecorder) TouchAPIKey(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockAPIKeyStore)(nil).TouchAPIKey), ctx, id)
+}
+
+// MockAuditStore is a mock of AuditStore interface.
+type MockAuditStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockAuditStoreMockRecorder is the mock recorder for MockAuditStore.
+type MockAuditStoreMockRecorder struct {
+	mock *MockAuditStore
+}
+
+// NewMockAuditStore creates a new mock instance.
+func NewMockAuditStore(ctrl *gomock.Controller) *MockAuditStore {
+	mock := &MockAuditStore{ctrl: ctrl}
+	mock.recorder = &MockAuditStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditStore) EXPECT() *MockAuditStoreMockRecorder {
+	return m.recorder
+}
+
+// ListAuditEvents mocks base method.
+func (m *MockAuditStore) ListAuditEvents(ctx context.Context, f student.AuditFilter) ([]student.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", ctx, f)
+	ret0, _ := ret[0].([]student.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents.
+func (mr *MockAuditStoreMockRecorder) ListAuditEvents(ctx, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockAuditStore)(nil).ListAuditEvents), ctx, f)
+}
+
+// RecordAuditEvent mocks base method.
+func (m *MockAuditStore) RecordAuditEvent(ctx context.Context, e student.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAuditEvent", ctx, e)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAuditEvent indicates an expected call of RecordAuditEvent.
+func (mr *MockAuditStoreMockRecorder) RecordAuditEvent(ctx, e any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAuditEvent", reflect.TypeOf((*MockAuditStore)(nil).RecordAuditEvent), ctx, e)
 }
@@ -0,0 +1,474 @@
+package student
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// attendance marks.
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+// EventAttendanceLow is the change event written when marking attendance takes a student's attendance rate in a
+// course below the AttendanceThreshold.
+const EventAttendanceLow = "student.attendance_low"
+
+const (
+	maxSessionTopicLength = 255
+
+	// defaultAttendanceThreshold is the attendance rate, in percent, students are alerted on below.
+	defaultAttendanceThreshold = 75
+)
+
+var attendanceStatuses = []string{AttendancePresent, AttendanceAbsent, AttendanceLate, AttendanceExcused}
+
+// ClassSession is a meeting of a course, that students attend.
+type ClassSession struct {
+	Id       int       `json:"id"`
+	CourseId int       `json:"course_id"`
+	Topic    string    `json:"topic"`
+	StartsAt time.Time `json:"starts_at"`
+}
+
+// AttendanceMark is whether a student attended a session.
+type AttendanceMark struct {
+	SessionId int       `json:"session_id"`
+	StudentId int       `json:"student_id"`
+	Status    string    `json:"status"`
+	MarkedAt  time.Time `json:"marked_at"`
+}
+
+// AttendanceSummary is how often a student attended the sessions of a course it's enrolled in.
+type AttendanceSummary struct {
+	CourseId  int `json:"course_id"`
+	StudentId int `json:"student_id"`
+	// Sessions is how many sessions the student was marked in. Sessions nobody marked it in don't count.
+	Sessions int `json:"sessions"`
+	Present  int `json:"present"`
+	Late     int `json:"late"`
+	Absent   int `json:"absent"`
+	Excused  int `json:"excused"`
+	// Rate is the percentage of the sessions the student was expected at that it attended, on time or late. Excused
+	// absences aren't expected. It's nil until the student is expected at one.
+	Rate           *float64 `json:"rate"`
+	BelowThreshold bool     `json:"below_threshold"`
+}
+
+// AttendanceThreshold is the attendance rate, in percent, below which students are alerted on.
+type AttendanceThreshold float64
+
+// NewAttendanceThreshold reads the threshold from ATTENDANCE_THRESHOLD, 75 when it isn't set.
+func NewAttendanceThreshold() AttendanceThreshold {
+	v := os.Getenv(attendanceThreshold)
+	if v == "" {
+		return defaultAttendanceThreshold
+	}
+
+	threshold, err := strconv.ParseFloat(v, 64)
+	if err != nil || threshold < 0 || threshold > 100 {
+		log.Fatalf("invalid %q: %q, expected a percentage", attendanceThreshold, v)
+	}
+	return AttendanceThreshold(threshold)
+}
+
+// rate fills in the rate of a, from its counts, and whether it's below threshold.
+func (a *AttendanceSummary) rate(threshold AttendanceThreshold) {
+	expected := a.Sessions - a.Excused
+	if expected == 0 {
+		a.Rate, a.BelowThreshold = nil, false
+		return
+	}
+
+	rate := round2(float64(a.Present+a.Late) / float64(expected) * 100)
+	a.Rate, a.BelowThreshold = &rate, rate < float64(threshold)
+}
+
+// attendanceAlerts returns the summaries of after that are below the threshold, but whose summary in before wasn't.
+func attendanceAlerts(before, after []AttendanceSummary) []AttendanceSummary {
+	wasBelow := make(map[int]bool, len(before))
+	for _, a := range before {
+		wasBelow[a.StudentId] = a.BelowThreshold
+	}
+
+	var alerts []AttendanceSummary
+	for _, a := range after {
+		if a.BelowThreshold && !wasBelow[a.StudentId] {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts
+}
+
+// attendanceEvent is the change event alerting on a, which just fell below the threshold.
+func attendanceEvent(ctx context.Context, a AttendanceSummary) (Event, []byte, error) {
+	e := Event{
+		Type:       EventAttendanceLow,
+		StudentId:  a.StudentId,
+		Attendance: &a,
+		Actor:      actorFromContext(ctx),
+		RequestId:  RequestIdFromContext(ctx),
+		OccurredAt: time.Now().UTC(),
+	}
+
+	payload, err := json.Marshal(e)
+	return e, payload, err
+}
+
+// scanAttendanceSummaries scans the rows of the attendance query of either store: the course, the student, and how many
+// sessions it was marked in, present, late, absent and excused.
+func scanAttendanceSummaries(rows interface {
+	Next() bool
+	Scan(...any) error
+	Err() error
+}, threshold AttendanceThreshold) ([]AttendanceSummary, error) {
+	var summaries []AttendanceSummary
+	for rows.Next() {
+		var a AttendanceSummary
+		if err := rows.Scan(&a.CourseId, &a.StudentId, &a.Sessions, &a.Present, &a.Late, &a.Absent, &a.Excused); err != nil {
+			return nil, err
+		}
+		a.rate(threshold)
+		summaries = append(summaries, a)
+	}
+	return summaries, rows.Err()
+}
+
+func validateSession(s ClassSession) map[string]any {
+	details := make(map[string]any)
+	if strings.TrimSpace(s.Topic) == "" {
+		details["topic"] = "is required"
+	} else if len(s.Topic) > maxSessionTopicLength {
+		details["topic"] = fmt.Sprintf("must be at most %d characters", maxSessionTopicLength)
+	}
+	if s.StartsAt.IsZero() {
+		details["starts_at"] = "is required"
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// validateMarks checks the marks of a session that are about to be recorded, along with the status every other
+// student is marked with, if any.
+func validateMarks(marks []AttendanceMark, others string) map[string]any {
+	details := make(map[string]any)
+	if len(marks) == 0 && others == "" {
+		details["marks"] = "must not be empty unless status is given"
+	} else if len(marks) > maxCourseCapacity {
+		details["marks"] = fmt.Sprintf("must be at most %d", maxCourseCapacity)
+	}
+	if others != "" && !slices.Contains(attendanceStatuses, others) {
+		details["status"] = "must be one of " + strings.Join(attendanceStatuses, ", ")
+	}
+
+	seen := make(map[int]bool)
+	for i, mark := range marks {
+		field := fmt.Sprintf("marks[%d]", i)
+		if mark.StudentId < 1 {
+			details[field+".student_id"] = "is required"
+		} else if seen[mark.StudentId] {
+			details[field+".student_id"] = "is marked twice"
+		}
+		seen[mark.StudentId] = true
+
+		if !slices.Contains(attendanceStatuses, mark.Status) {
+			details[field+".status"] = "must be one of " + strings.Join(attendanceStatuses, ", ")
+		}
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// SessionsHandler lists the sessions of a course, or schedules one.
+func (s *Server) SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid courseId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Attendance == nil {
+		RespondWithError(w, "Attendance is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		sessions, err := s.Attendance.ListSessions(r.Context(), courseId)
+		if err != nil {
+			s.respondAttendanceError(w, "error listing sessions", courseId, err)
+			return
+		}
+		if sessions == nil {
+			sessions = []ClassSession{}
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, sessions)
+	case http.MethodPost:
+		session, ok := s.decodeSession(w, r)
+		if !ok {
+			return
+		}
+		session.CourseId = courseId
+
+		id, err := s.Attendance.CreateSession(r.Context(), session)
+		if err != nil {
+			s.respondAttendanceError(w, "error creating session", courseId, err)
+			return
+		}
+		session.Id = id
+
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/courses/%d/sessions/%d", courseId, id))
+		respondWithJSON(w, s.Logger, http.StatusCreated, session)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// SessionHandler reads, replaces or deletes a single session.
+func (s *Server) SessionHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, sessionId, ok := sessionPath(w, r)
+	if !ok {
+		return
+	}
+
+	if s.Attendance == nil {
+		RespondWithError(w, "Attendance is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		session, err := s.Attendance.GetSession(r.Context(), courseId, sessionId)
+		if err != nil {
+			s.respondAttendanceError(w, "error reading session", courseId, err)
+			return
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, session)
+	case http.MethodPatch:
+		session, ok := s.decodeSession(w, r)
+		if !ok {
+			return
+		}
+		if err := s.Attendance.UpdateSession(r.Context(), courseId, sessionId, session); err != nil {
+			s.respondAttendanceError(w, "error updating session", courseId, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := s.Attendance.DeleteSession(r.Context(), courseId, sessionId); err != nil {
+			s.respondAttendanceError(w, "error deleting session", courseId, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// AttendanceHandler lists the attendance of a session, or marks it.
+func (s *Server) AttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, sessionId, ok := sessionPath(w, r)
+	if !ok {
+		return
+	}
+
+	if s.Attendance == nil {
+		RespondWithError(w, "Attendance is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		marks, err := s.Attendance.ListAttendance(r.Context(), courseId, sessionId)
+		if err != nil {
+			s.respondAttendanceError(w, "error listing attendance", courseId, err)
+			return
+		}
+		if marks == nil {
+			marks = []AttendanceMark{}
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, marks)
+	case http.MethodPut:
+		s.markAttendance(w, r, courseId, sessionId)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// markAttendance marks a whole session at once: the students listed in marks, and every other enrolled student with
+// status, when it's given.
+func (s *Server) markAttendance(w http.ResponseWriter, r *http.Request, courseId, sessionId int) {
+	var req struct {
+		Status string `json:"status"`
+		Marks  []struct {
+			StudentId int    `json:"student_id"`
+			Status    string `json:"status"`
+		} `json:"marks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.Logger.Error("error unmarshalling request body", "error", err)
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	marks := make([]AttendanceMark, 0, len(req.Marks))
+	for _, mark := range req.Marks {
+		marks = append(marks, AttendanceMark{SessionId: sessionId, StudentId: mark.StudentId, Status: mark.Status})
+	}
+	if details := validateMarks(marks, req.Status); details != nil {
+		RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_attendance",
+			Message: "the attendance is invalid",
+			Details: details,
+		})
+		return
+	}
+
+	if err := s.Attendance.MarkAttendance(r.Context(), courseId, sessionId, marks, req.Status); err != nil {
+		s.respondAttendanceError(w, "error marking attendance", courseId, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CourseAttendance sums up the attendance of the students enrolled in a course.
+func (s *Server) CourseAttendance(w http.ResponseWriter, r *http.Request) {
+	courseId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid courseId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Attendance == nil {
+		RespondWithError(w, "Attendance is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	summaries, err := s.Attendance.CourseAttendance(r.Context(), courseId)
+	if err != nil {
+		s.respondAttendanceError(w, "error summing up attendance", courseId, err)
+		return
+	}
+	s.respondWithSummaries(w, r, summaries)
+}
+
+// StudentAttendance sums up the attendance of a student, in every course it's enrolled in.
+func (s *Server) StudentAttendance(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid studentId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Attendance == nil {
+		RespondWithError(w, "Attendance is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	summaries, err := s.Attendance.StudentAttendance(r.Context(), studentId)
+	if err != nil {
+		s.Logger.Error("error summing up attendance", "studentId", studentId, "error", err)
+		if err.Error() == errStudentNotFound {
+			RespondWithError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		RespondWithError(w, "error summing up attendance", http.StatusInternalServerError)
+		return
+	}
+	s.respondWithSummaries(w, r, summaries)
+}
+
+// respondWithSummaries responds with the summaries, only those below the threshold with ?below_threshold=true.
+func (s *Server) respondWithSummaries(w http.ResponseWriter, r *http.Request, summaries []AttendanceSummary) {
+	if v := r.URL.Query().Get("below_threshold"); v != "" {
+		below, err := strconv.ParseBool(v)
+		if err != nil {
+			RespondWithError(w, "Invalid below_threshold", http.StatusBadRequest)
+			return
+		}
+		summaries = slices.DeleteFunc(summaries, func(a AttendanceSummary) bool { return a.BelowThreshold != below })
+	}
+
+	if summaries == nil {
+		summaries = []AttendanceSummary{}
+	}
+	respondWithJSON(w, s.Logger, http.StatusOK, summaries)
+}
+
+// sessionPath reads the course and session ids off the path. It responds itself when they're invalid.
+func sessionPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	courseId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid courseId", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	sessionId, err := strconv.Atoi(r.PathValue("sessionId"))
+	if err != nil {
+		RespondWithError(w, "Invalid sessionId", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return courseId, sessionId, true
+}
+
+// decodeSession reads and validates the session in the request body. It responds itself when the session is invalid.
+func (s *Server) decodeSession(w http.ResponseWriter, r *http.Request) (ClassSession, bool) {
+	var req struct {
+		Topic    string    `json:"topic"`
+		StartsAt time.Time `json:"starts_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.Logger.Error("error unmarshalling request body", "error", err)
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return ClassSession{}, false
+	}
+
+	session := ClassSession{Topic: strings.TrimSpace(req.Topic), StartsAt: req.StartsAt.UTC()}
+	if details := validateSession(session); details != nil {
+		RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_session",
+			Message: "the session is invalid",
+			Details: details,
+		})
+		return ClassSession{}, false
+	}
+	return session, true
+}
+
+func (s *Server) respondAttendanceError(w http.ResponseWriter, msg string, courseId int, err error) {
+	s.Logger.Error(msg, "courseId", courseId, "error", err)
+
+	var notEnrolled errNotEnrolled
+	if errors.As(err, &notEnrolled) {
+		RespondWithJSONError(w, http.StatusConflict, ErrorResponse{
+			Error:   "not_enrolled",
+			Message: "only students enrolled in the course can be marked",
+			Details: map[string]any{"student_id": notEnrolled.studentId},
+		})
+		return
+	}
+
+	switch err.Error() {
+	case errCourseNotFound, errSessionNotFound:
+		RespondWithError(w, err.Error(), http.StatusNotFound)
+	default:
+		RespondWithError(w, msg, http.StatusInternalServerError)
+	}
+}
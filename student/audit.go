@@ -0,0 +1,215 @@
+package student
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	AuditStudentCreated  = "student.created"
+	AuditStudentUpdated  = "student.updated"
+	AuditStudentDeleted  = "student.deleted"
+	AuditStudentRestored = "student.restored"
+	AuditStudentPurged   = "student.purged"
+	AuditAccessDenied    = "access.denied"
+
+	// actor recorded for changes made outside of an http request, e.g. from the cli.
+	systemActor = "system"
+
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditEvent is an entry in the append-only audit trail, answering who changed what and when.
+type AuditEvent struct {
+	Id        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	StudentId *int            `json:"student_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	RequestId string          `json:"request_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows down ListAuditEvents. Zero values don't filter. Events come back newest first; pass the id of
+// the last event seen as BeforeId to page through them.
+type AuditFilter struct {
+	StudentId *int
+	Actor     string
+	Action    string
+	Since     *time.Time
+	Until     *time.Time
+	BeforeId  int64
+	Limit     int
+}
+
+// mutation describes a change to a student, as the stores record it alongside the change itself.
+type mutation struct {
+	action    string
+	studentId int
+	before    *Student
+	after     *Student
+	// state is what's left of the student when there's no after snapshot to show for it, e.g. a soft deleted student.
+	state     *Student
+	actor     string
+	requestId string
+}
+
+func newMutation(ctx context.Context, action string, studentId int, before, after *Student) mutation {
+	return mutation{
+		action:    action,
+		studentId: studentId,
+		before:    before,
+		after:     after,
+		actor:     actorFromContext(ctx),
+		requestId: RequestIdFromContext(ctx),
+	}
+}
+
+// auditEvent turns the mutation into the event recorded for it.
+func (m mutation) auditEvent() (AuditEvent, error) {
+	studentId := m.studentId
+	e := AuditEvent{
+		Actor:     m.actor,
+		Action:    m.action,
+		StudentId: &studentId,
+		RequestId: m.requestId,
+	}
+
+	var err error
+	if m.before != nil {
+		if e.Before, err = json.Marshal(m.before); err != nil {
+			return e, err
+		}
+	}
+	if m.after != nil {
+		if e.After, err = json.Marshal(m.after); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+func actorFromContext(ctx context.Context) string {
+	if p, ok := PrincipalFromContext(ctx); ok {
+		return p.Subject
+	}
+	return systemActor
+}
+
+// nullableJSON lets empty snapshots be stored as NULL rather than an empty string.
+func nullableJSON(b json.RawMessage) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+// StudentAudit serves the audit trail of a single student.
+func (s *Server) StudentAudit(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid studentId", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		RespondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.StudentId = &studentId
+
+	s.respondWithAuditEvents(w, r, filter)
+}
+
+// ListAuditEvents serves the whole audit trail, filtered by the student_id, actor, action, since, until, before_id and
+// limit query parameters.
+func (s *Server) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		RespondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.respondWithAuditEvents(w, r, filter)
+}
+
+func (s *Server) respondWithAuditEvents(w http.ResponseWriter, r *http.Request, filter AuditFilter) {
+	if s.Audit == nil {
+		RespondWithError(w, "Audit log is not available", http.StatusNotImplemented)
+		return
+	}
+
+	events, err := s.Audit.ListAuditEvents(r.Context(), filter)
+	if err != nil {
+		s.Logger.Error("error listing audit events", "error", err)
+		RespondWithError(w, "Failed to list audit events", http.StatusInternalServerError)
+		return
+	}
+
+	if events == nil {
+		events = []AuditEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		s.Logger.Error("error encoding response", "error", err)
+	}
+}
+
+func parseAuditFilter(r *http.Request) (AuditFilter, error) {
+	q := r.URL.Query()
+	f := AuditFilter{
+		Actor:  q.Get("actor"),
+		Action: q.Get("action"),
+		Limit:  defaultAuditLimit,
+	}
+
+	if v := q.Get("student_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return f, errInvalidParam("student_id")
+		}
+		f.StudentId = &id
+	}
+
+	for name, dst := range map[string]**time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, errInvalidParam(name)
+			}
+			*dst = &t
+		}
+	}
+
+	if v := q.Get("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return f, errInvalidParam("before_id")
+		}
+		f.BeforeId = id
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return f, errInvalidParam("limit")
+		}
+		f.Limit = min(limit, maxAuditLimit)
+	}
+	return f, nil
+}
+
+func auditLimit(limit int) int {
+	if limit < 1 {
+		return defaultAuditLimit
+	}
+	return min(limit, maxAuditLimit)
+}
@@ -0,0 +1,97 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// errUnauthenticated is returned by the authenticators when the credentials presented are missing, invalid, expired
+// or revoked. Any other error is treated as a server side failure.
+var errUnauthenticated = errors.New("unauthenticated")
+
+// Principal is whoever is making a request, however they authenticated.
+type Principal struct {
+	// Subject identifies the caller: "apikey:<id>" for API keys, the sub claim for bearer tokens.
+	Subject string         `json:"subject"`
+	Method  string         `json:"method"`
+	Roles   []string       `json:"roles,omitempty"`
+	Scopes  []string       `json:"scopes,omitempty"`
+	Claims  map[string]any `json:"claims,omitempty"`
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// PrincipalFromContext returns the principal Authenticate attached to the request context.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(PrincipalKey).(*Principal)
+	return p, ok
+}
+
+// Authenticate rejects requests that don't carry valid credentials — either an API key in the X-API-Key header or a
+// bearer token that verifies against the configured JWKS. The caller is made available to downstream handlers through
+// PrincipalFromContext; what it's allowed to do is left to Authorize.
+func (s *Server) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, key, err := s.authenticate(r.Context(), r.Header.Get("Authorization"), r.Header.Get(apiKeyHeader))
+		if err != nil {
+			if errors.Is(err, errUnauthenticated) {
+				s.challenge(w)
+				RespondWithError(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			s.Logger.Error("error authenticating request", "error", err)
+			RespondWithError(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), PrincipalKey, principal)
+		if key != nil {
+			s.touchAPIKey(ctx, key)
+			ctx = context.WithValue(ctx, APIKeyKey, key)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// challenge tells the client which credentials the server accepts.
+func (s *Server) challenge(w http.ResponseWriter) {
+	w.Header().Add("WWW-Authenticate", apiKeyHeader)
+	if s.JWT != nil {
+		w.Header().Add("WWW-Authenticate", `Bearer realm="students"`)
+	}
+}
+
+// authenticate resolves the credentials presented, an Authorization header or an API key, to the caller. The key is
+// only returned when the caller authenticated with one.
+func (s *Server) authenticate(ctx context.Context, authorization, apiKey string) (*Principal, *APIKey, error) {
+	if token, ok := bearerToken(authorization); ok {
+		principal, err := s.authenticateBearer(token)
+		return principal, nil, err
+	}
+	return s.authenticateAPIKey(ctx, apiKey)
+}
+
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func (s *Server) authenticateBearer(token string) (*Principal, error) {
+	if s.JWT == nil {
+		return nil, errUnauthenticated
+	}
+	return s.JWT.Verify(token)
+}
@@ -2,12 +2,51 @@ package student
 
 const (
 	StudentIdKey     contextKey = "studentId"
+	APIKeyKey        contextKey = "apiKey"
+	PrincipalKey     contextKey = "principal"
+	RequestIdKey     contextKey = "requestId"
 	sqliteDriverName            = "sqlite3"
 
 	// env variables
 	dbPath      = "DB_PATH"
 	databaseUrl = "DATABASE_URL"
 
+	jwksUrl             = "JWKS_URL"
+	jwksRefreshInterval = "JWKS_REFRESH_INTERVAL"
+	jwtIssuer           = "JWT_ISSUER"
+	jwtAudience         = "JWT_AUDIENCE"
+	jwtRolesClaim       = "JWT_ROLES_CLAIM"
+	rbacPolicyFile      = "RBAC_POLICY_FILE"
+	purgeRetention      = "PURGE_RETENTION"
+
+	revisionRetentionCount = "REVISION_RETENTION_COUNT"
+	revisionRetentionAge   = "REVISION_RETENTION_AGE"
+
+	idempotencyTTL = "IDEMPOTENCY_TTL"
+
+	outboxSinks      = "OUTBOX_SINKS"
+	outboxNDJSONPath = "OUTBOX_NDJSON_PATH"
+	outboxRetention  = "OUTBOX_RETENTION"
+
+	openAPIValidation = "OPENAPI_VALIDATION"
+
+	grpcAddr = "GRPC_ADDR"
+
+	enrolledStudentDeletion = "ENROLLED_STUDENT_DELETION"
+	attendanceThreshold     = "ATTENDANCE_THRESHOLD"
+
 	// errors
-	errStudentNotFound = "student not found"
+	errStudentNotFound         = "student not found"
+	errAPIKeyNotFound          = "api key not found"
+	errRevisionNotFound        = "revision not found"
+	errRevertMovesTrash        = "revert moves student in or out of the trash"
+	errWebhookNotFound         = "webhook not found"
+	errWebhookDeliveryNotFound = "webhook delivery not found"
+	errCourseNotFound          = "course not found"
+	errCourseCodeTaken         = "course code taken"
+	errEnrollmentNotFound      = "enrollment not found"
+	errAlreadyEnrolled         = "student already enrolled"
+	errStudentEnrolled         = "student enrolled in courses"
+	errAssessmentNotFound      = "assessment not found"
+	errSessionNotFound         = "session not found"
 )
@@ -0,0 +1,257 @@
+package student
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxCourseCodeLength        = 32
+	maxCourseTitleLength       = 255
+	maxCourseDescriptionLength = 4000
+	maxCourseCapacity          = 10_000
+)
+
+// dateLayout is how dates are rendered, and stored where there's no date type.
+const dateLayout = time.DateOnly
+
+// Date is a calendar day, rendered as 2006-01-02. The time of day is always midnight UTC.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the given day.
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads a date, leaving null as the zero Date.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return fmt.Errorf("%q is not a date, expected YYYY-MM-DD", s)
+	}
+	d.Time = t
+	return nil
+}
+
+// Course is a course students can take, running from StartDate to EndDate, both included.
+type Course struct {
+	Id int `json:"id"`
+	// Code identifies the course to people, e.g. "SRE-101". No two courses share one.
+	Code        string `json:"code"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Capacity is how many students the course can take.
+	Capacity  int  `json:"capacity"`
+	StartDate Date `json:"start_date"`
+	EndDate   Date `json:"end_date"`
+}
+
+// validateCourse checks a course that's about to be created, or to replace one.
+func validateCourse(c Course) map[string]any {
+	details := make(map[string]any)
+	if strings.TrimSpace(c.Code) == "" {
+		details["code"] = "is required"
+	} else if len(c.Code) > maxCourseCodeLength {
+		details["code"] = fmt.Sprintf("must be at most %d characters", maxCourseCodeLength)
+	} else if strings.ContainsFunc(c.Code, unicode.IsSpace) {
+		details["code"] = "must not contain whitespace"
+	}
+
+	if strings.TrimSpace(c.Title) == "" {
+		details["title"] = "is required"
+	} else if len(c.Title) > maxCourseTitleLength {
+		details["title"] = fmt.Sprintf("must be at most %d characters", maxCourseTitleLength)
+	}
+	if len(c.Description) > maxCourseDescriptionLength {
+		details["description"] = fmt.Sprintf("must be at most %d characters", maxCourseDescriptionLength)
+	}
+	if c.Capacity < 1 || c.Capacity > maxCourseCapacity {
+		details["capacity"] = fmt.Sprintf("must be between 1 and %d", maxCourseCapacity)
+	}
+
+	if c.StartDate.IsZero() {
+		details["start_date"] = "is required"
+	}
+	if c.EndDate.IsZero() {
+		details["end_date"] = "is required"
+	} else if c.EndDate.Before(c.StartDate.Time) {
+		details["end_date"] = "must not be before start_date"
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// CoursesHandler lists the courses, or creates one.
+func (s *Server) CoursesHandler(w http.ResponseWriter, r *http.Request) {
+	if s.Courses == nil {
+		RespondWithError(w, "Courses are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.listCourses(w, r)
+	case http.MethodPost:
+		s.createCourse(w, r)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// listCourses lists courses in id order, paged like students are.
+func (s *Server) listCourses(w http.ResponseWriter, r *http.Request) {
+	opts, ok := s.parseListOptions(w, r)
+	if !ok {
+		return
+	}
+	if opts.OnlyDeleted {
+		RespondWithError(w, "Courses have no trash", http.StatusBadRequest)
+		return
+	}
+
+	courses, err := s.Courses.ListCourses(r.Context(), opts)
+	if err != nil {
+		s.Logger.Error("error listing courses", "error", err)
+		RespondWithError(w, "Failed to list courses", http.StatusInternalServerError)
+		return
+	}
+	if courses == nil {
+		courses = []Course{}
+	}
+	respondWithJSON(w, s.Logger, http.StatusOK, courses)
+}
+
+func (s *Server) createCourse(w http.ResponseWriter, r *http.Request) {
+	course, ok := s.decodeCourse(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := s.Courses.CreateCourse(r.Context(), course)
+	if err != nil {
+		s.respondCourseError(w, "error creating course", 0, err)
+		return
+	}
+	course.Id = id
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/courses/%d", id))
+	respondWithJSON(w, s.Logger, http.StatusCreated, course)
+}
+
+// CourseHandler reads, replaces or deletes a single course.
+func (s *Server) CourseHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid courseId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Courses == nil {
+		RespondWithError(w, "Courses are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		course, err := s.Courses.GetCourse(r.Context(), courseId)
+		if err != nil {
+			s.respondCourseError(w, "error reading course", courseId, err)
+			return
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, course)
+	case http.MethodPatch:
+		course, ok := s.decodeCourse(w, r)
+		if !ok {
+			return
+		}
+		if err := s.Courses.UpdateCourse(r.Context(), courseId, course); err != nil {
+			s.respondCourseError(w, "error updating course", courseId, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := s.Courses.DeleteCourse(r.Context(), courseId); err != nil {
+			s.respondCourseError(w, "error deleting course", courseId, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// decodeCourse reads and validates the course in the request body. It responds itself when the course is invalid.
+func (s *Server) decodeCourse(w http.ResponseWriter, r *http.Request) (Course, bool) {
+	var req struct {
+		Code        string `json:"code"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Capacity    int    `json:"capacity"`
+		StartDate   Date   `json:"start_date"`
+		EndDate     Date   `json:"end_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.Logger.Error("error unmarshalling request body", "error", err)
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return Course{}, false
+	}
+
+	course := Course{
+		Code:        strings.TrimSpace(req.Code),
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		Capacity:    req.Capacity,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+	}
+	if details := validateCourse(course); details != nil {
+		RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_course",
+			Message: "the course is invalid",
+			Details: details,
+		})
+		return Course{}, false
+	}
+	return course, true
+}
+
+func (s *Server) respondCourseError(w http.ResponseWriter, msg string, courseId int, err error) {
+	s.Logger.Error(msg, "courseId", courseId, "error", err)
+
+	switch err.Error() {
+	case errCourseNotFound:
+		RespondWithError(w, "course not found", http.StatusNotFound)
+	case errCourseCodeTaken:
+		RespondWithJSONError(w, http.StatusConflict, ErrorResponse{
+			Error:   "course_code_taken",
+			Message: "another course already has this code",
+		})
+	default:
+		RespondWithError(w, msg, http.StatusInternalServerError)
+	}
+}
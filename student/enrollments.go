@@ -0,0 +1,197 @@
+package student
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// EnrollmentEnrolled is the status of a student that has a seat in a course.
+	EnrollmentEnrolled = "enrolled"
+	// EnrollmentWaitlisted is the status of a student waiting for a seat in a full course.
+	EnrollmentWaitlisted = "waitlisted"
+)
+
+// Enrollment is a student's place in a course: a seat, or a place on its waitlist.
+type Enrollment struct {
+	CourseId  int    `json:"course_id"`
+	StudentId int    `json:"student_id"`
+	Status    string `json:"status"`
+	// Position is the place of a waitlisted student on the waitlist, from 1. It's 0 for enrolled students.
+	Position  int       `json:"position,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StudentCourse is a course a student is enrolled, or waitlisted, in.
+type StudentCourse struct {
+	Course     Course     `json:"course"`
+	Enrollment Enrollment `json:"enrollment"`
+}
+
+// EnrolledStudentPolicy decides what deleting a student that's enrolled, or waitlisted, in courses does.
+type EnrolledStudentPolicy string
+
+const (
+	// BlockEnrolledStudents fails the deletion with errStudentEnrolled until the student has been withdrawn from every
+	// course. The zero value blocks too.
+	BlockEnrolledStudents EnrolledStudentPolicy = "block"
+	// WithdrawEnrolledStudents withdraws the student from every course as it's deleted, handing its seats to the
+	// waitlists.
+	WithdrawEnrolledStudents EnrolledStudentPolicy = "cascade"
+)
+
+// NewEnrolledStudentPolicy reads the policy from ENROLLED_STUDENT_DELETION, blocking when it isn't set.
+func NewEnrolledStudentPolicy() EnrolledStudentPolicy {
+	switch v := EnrolledStudentPolicy(os.Getenv(enrolledStudentDeletion)); v {
+	case "":
+		return BlockEnrolledStudents
+	case BlockEnrolledStudents, WithdrawEnrolledStudents:
+		return v
+	default:
+		log.Fatalf("invalid %q: %q, expected %q or %q", enrolledStudentDeletion, v, BlockEnrolledStudents,
+			WithdrawEnrolledStudents)
+		return ""
+	}
+}
+
+// EnrollmentsHandler lists the students of a course, or enrolls one.
+func (s *Server) EnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid courseId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Enrollments == nil {
+		RespondWithError(w, "Enrollments are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		enrollments, err := s.Enrollments.ListEnrollments(r.Context(), courseId)
+		if err != nil {
+			s.respondEnrollmentError(w, "error listing enrollments", courseId, 0, err)
+			return
+		}
+		if enrollments == nil {
+			enrollments = []Enrollment{}
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, enrollments)
+	case http.MethodPost:
+		s.enroll(w, r, courseId)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// enroll gives the student in the request body a seat in the course, or a place on its waitlist when it's full.
+func (s *Server) enroll(w http.ResponseWriter, r *http.Request, courseId int) {
+	var req struct {
+		StudentId int `json:"student_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.Logger.Error("error unmarshalling request body", "error", err)
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.StudentId < 1 {
+		RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_enrollment",
+			Message: "the enrollment is invalid",
+			Details: map[string]any{"student_id": "is required"},
+		})
+		return
+	}
+
+	enrollment, err := s.Enrollments.Enroll(r.Context(), courseId, req.StudentId)
+	if err != nil {
+		s.respondEnrollmentError(w, "error enrolling student", courseId, req.StudentId, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/courses/%d/enrollments/%d", courseId, req.StudentId))
+	respondWithJSON(w, s.Logger, http.StatusCreated, enrollment)
+}
+
+// EnrollmentHandler reads a student's enrollment in a course, or withdraws the student from it.
+func (s *Server) EnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid courseId", http.StatusBadRequest)
+		return
+	}
+	studentId, err := strconv.Atoi(r.PathValue("studentId"))
+	if err != nil {
+		RespondWithError(w, "Invalid studentId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Enrollments == nil {
+		RespondWithError(w, "Enrollments are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		enrollment, err := s.Enrollments.GetEnrollment(r.Context(), courseId, studentId)
+		if err != nil {
+			s.respondEnrollmentError(w, "error reading enrollment", courseId, studentId, err)
+			return
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, enrollment)
+	case http.MethodDelete:
+		if err := s.Enrollments.Withdraw(r.Context(), courseId, studentId); err != nil {
+			s.respondEnrollmentError(w, "error withdrawing student", courseId, studentId, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// StudentCourses lists the courses a student is enrolled, or waitlisted, in.
+func (s *Server) StudentCourses(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid studentId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Enrollments == nil {
+		RespondWithError(w, "Enrollments are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	courses, err := s.Enrollments.ListStudentCourses(r.Context(), studentId)
+	if err != nil {
+		s.respondEnrollmentError(w, "error listing courses of student", 0, studentId, err)
+		return
+	}
+	if courses == nil {
+		courses = []StudentCourse{}
+	}
+	respondWithJSON(w, s.Logger, http.StatusOK, courses)
+}
+
+func (s *Server) respondEnrollmentError(w http.ResponseWriter, msg string, courseId, studentId int, err error) {
+	s.Logger.Error(msg, "courseId", courseId, "studentId", studentId, "error", err)
+
+	switch err.Error() {
+	case errCourseNotFound, errStudentNotFound, errEnrollmentNotFound:
+		RespondWithError(w, err.Error(), http.StatusNotFound)
+	case errAlreadyEnrolled:
+		RespondWithJSONError(w, http.StatusConflict, ErrorResponse{
+			Error:   "already_enrolled",
+			Message: "the student is already enrolled, or waitlisted, in this course",
+		})
+	default:
+		RespondWithError(w, msg, http.StatusInternalServerError)
+	}
+}
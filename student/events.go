@@ -0,0 +1,348 @@
+package student
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSubscriberBuffer = 64
+	defaultEventHeartbeat   = 15 * time.Second
+	defaultEventPoll        = 5 * time.Second
+	eventReplayBatch        = 500
+	// eventWriteTimeout is how long a client gets to take a single write off our hands before it's disconnected.
+	eventWriteTimeout = 30 * time.Second
+)
+
+// EventBroker fans new outbox events out to in-process subscribers, such as event stream connections. It reads them
+// from the outbox whenever it's notified of a change — by the handlers after a mutation, or by other replicas through
+// the database — and every poll interval in case a notification went missing.
+type EventBroker struct {
+	Outbox OutboxStore
+	Logger *slog.Logger
+
+	// Buffer is how many events a subscriber can fall behind by before it's dropped.
+	Buffer       int
+	Heartbeat    time.Duration
+	PollInterval time.Duration
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	notify chan struct{}
+	lastId int64
+}
+
+// Subscription receives the events published after it was created. C is closed when the subscriber has fallen too far
+// behind; it should catch up from the outbox and subscribe again.
+type Subscription struct {
+	C <-chan Event
+	c chan Event
+}
+
+func NewEventBroker(outbox OutboxStore, logger *slog.Logger) *EventBroker {
+	return &EventBroker{
+		Outbox:       outbox,
+		Logger:       logger,
+		Buffer:       defaultSubscriberBuffer,
+		Heartbeat:    defaultEventHeartbeat,
+		PollInterval: defaultEventPoll,
+		subs:         make(map[*Subscription]struct{}),
+		notify:       make(chan struct{}, 1),
+	}
+}
+
+// Start publishes new events to the subscribers in the background, until ctx is done. Only events from now on are
+// published, subscribers replay older ones from the outbox themselves; it has to be started before anyone subscribes,
+// so that nothing falls in between.
+func (b *EventBroker) Start(ctx context.Context) error {
+	lastId, err := b.Outbox.LatestEventId(ctx)
+	if err != nil {
+		return err
+	}
+	b.lastId = lastId
+
+	go b.run(ctx)
+	return nil
+}
+
+func (b *EventBroker) run(ctx context.Context) {
+	ticker := time.NewTicker(b.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.notify:
+		case <-ticker.C:
+		}
+
+		if err := b.publishNew(ctx); err != nil {
+			b.Logger.Error("error publishing events", "error", err)
+		}
+	}
+}
+
+// Notify tells the broker there are new events in the outbox. It never blocks.
+func (b *EventBroker) Notify() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (b *EventBroker) publishNew(ctx context.Context) error {
+	for {
+		events, err := b.Outbox.EventsAfter(ctx, b.lastId, eventReplayBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			b.publish(e)
+			b.lastId = e.Id
+		}
+		if len(events) < eventReplayBatch {
+			return nil
+		}
+	}
+}
+
+// publish hands e to every subscriber without waiting on any of them. Subscribers with a full buffer are dropped.
+func (b *EventBroker) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.c <- e:
+		default:
+			delete(b.subs, sub)
+			close(sub.c)
+		}
+	}
+}
+
+func (b *EventBroker) Subscribe() *Subscription {
+	c := make(chan Event, b.Buffer)
+	sub := &Subscription{C: c, c: c}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+func (b *EventBroker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
+
+// notifyEvents lets the broker know a mutation has just been committed.
+func (s *Server) notifyEvents() {
+	if s.Events != nil {
+		s.Events.Notify()
+	}
+}
+
+// StudentEvents streams changes to students as server-sent events. Clients resuming with a Last-Event-ID get the events
+// they missed first; others start with the next change.
+func (s *Server) StudentEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	if s.Events == nil {
+		RespondWithError(w, "Event streams are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	ctx := r.Context()
+	var lastId int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			RespondWithError(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastId = id
+	} else {
+		id, err := s.Events.Outbox.LatestEventId(ctx)
+		if err != nil {
+			s.Logger.Error("error reading latest event id", "error", err)
+			RespondWithError(w, "Failed to stream events", http.StatusInternalServerError)
+			return
+		}
+		lastId = id
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	sw := newStreamWriter(w)
+	if err := sw.write("retry: 3000\n\n"); err != nil {
+		s.Logger.Error("error flushing event stream", "error", err)
+		return
+	}
+
+	s.streamEvents(ctx, sw.eventStream(lastId, encodeSSE, sseHeartbeat))
+}
+
+func sseHeartbeat(int64) string {
+	return ": heartbeat\n\n"
+}
+
+func encodeSSE(e Event) (string, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data), nil
+}
+
+// streamEvents sends the events after the stream's last one, and then new ones as they come, until the client goes
+// away. Alerts about the attendance of students are skipped for clients that aren't allowed to read it.
+func (s *Server) streamEvents(ctx context.Context, stream *eventStream) {
+	if principal, ok := PrincipalFromContext(ctx); !ok || !s.policy().Grants(principal, ScopeAttendanceRead) {
+		deliver := stream.deliver
+		stream.deliver = func(e Event) error {
+			if e.Type == EventAttendanceLow {
+				return nil
+			}
+			return deliver(e)
+		}
+	}
+
+	heartbeat := time.NewTicker(s.Events.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		// subscribe before catching up, so nothing published in between is missed.
+		sub := s.Events.Subscribe()
+		err := s.replayEvents(ctx, stream)
+		if err == nil {
+			err = stream.follow(ctx, sub, heartbeat.C)
+		}
+		s.Events.Unsubscribe(sub)
+
+		if err != nil {
+			if ctx.Err() == nil {
+				s.Logger.Info("event stream closed", "lastEventId", stream.lastId, "error", err)
+			}
+			return
+		}
+		// the subscription was dropped for falling behind, catch up from the outbox again.
+	}
+}
+
+// replayEvents sends everything in the outbox after the last event sent.
+func (s *Server) replayEvents(ctx context.Context, stream *eventStream) error {
+	for {
+		events, err := s.Events.Outbox.EventsAfter(ctx, stream.lastId, eventReplayBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			if err := stream.send(e); err != nil {
+				return err
+			}
+		}
+		if len(events) < eventReplayBatch {
+			return nil
+		}
+	}
+}
+
+// eventStream sends events to a client, in whatever form it takes them.
+type eventStream struct {
+	lastId int64
+	// deliver sends an event, or skips it if the client isn't interested in it.
+	deliver func(e Event) error
+	// heartbeat sends what keeps an idle stream open.
+	heartbeat func(lastId int64) error
+}
+
+// follow sends the subscription's events until it's dropped, which returns nil, or the client goes away.
+func (es *eventStream) follow(ctx context.Context, sub *Subscription, heartbeat <-chan time.Time) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			// anything already replayed is skipped.
+			if e.Id <= es.lastId {
+				continue
+			}
+			if err := es.send(e); err != nil {
+				return err
+			}
+		case <-heartbeat:
+			if err := es.heartbeat(es.lastId); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (es *eventStream) send(e Event) error {
+	if err := es.deliver(e); err != nil {
+		return err
+	}
+	es.lastId = e.Id
+	return nil
+}
+
+// streamWriter writes a streamed response, flushing every write.
+type streamWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func newStreamWriter(w http.ResponseWriter) *streamWriter {
+	return &streamWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+func (sw *streamWriter) write(s string) error {
+	// a write deadline is unsupported by some writers, like the ones in tests, which is fine.
+	_ = sw.rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+	if _, err := io.WriteString(sw.w, s); err != nil {
+		return err
+	}
+	return sw.rc.Flush()
+}
+
+// eventStream streams events from lastId on in the format encode renders them in. Events it renders as an empty
+// string are skipped.
+func (sw *streamWriter) eventStream(lastId int64, encode func(e Event) (string, error), heartbeat func(lastId int64) string) *eventStream {
+	return &eventStream{
+		lastId: lastId,
+		deliver: func(e Event) error {
+			msg, err := encode(e)
+			if err != nil || msg == "" {
+				return err
+			}
+			return sw.write(msg)
+		},
+		heartbeat: func(lastId int64) error {
+			return sw.write(heartbeat(lastId))
+		},
+	}
+}
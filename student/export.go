@@ -0,0 +1,293 @@
+package student
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize bounds how many rows a parquet export holds in memory before writing them out.
+const parquetRowGroupSize = 10_000
+
+// exportFormat describes a format students can be exported in.
+type exportFormat struct {
+	contentType string
+	extension   string
+	newEncoder  func(w io.Writer) studentEncoder
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":     {"text/csv; charset=utf-8", "csv", newCSVStudentEncoder},
+	"ndjson":  {"application/x-ndjson", "ndjson", newNDJSONStudentEncoder},
+	"json":    {"application/json", "json", newJSONStudentEncoder},
+	"parquet": {"application/vnd.apache.parquet", "parquet", newParquetStudentEncoder},
+}
+
+// studentEncoder writes students out one at a time. close finishes the output, it isn't complete before then.
+type studentEncoder interface {
+	encode(s Student) error
+	close() error
+}
+
+type csvStudentEncoder struct {
+	w *csv.Writer
+	// fields are the columns to write, from studentFields. Empty writes them all.
+	fields []string
+	header bool
+}
+
+func newCSVStudentEncoder(w io.Writer) studentEncoder {
+	return &csvStudentEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvStudentEncoder) writeHeader() error {
+	header := e.fields
+	if len(header) == 0 {
+		header = studentFields
+	}
+	if err := e.w.Write(header); err != nil {
+		return err
+	}
+	e.header = true
+	return nil
+}
+
+func (e *csvStudentEncoder) encode(s Student) error {
+	if !e.header {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	if len(e.fields) == 0 {
+		var deletedAt string
+		if s.DeletedAt != nil {
+			deletedAt = s.DeletedAt.UTC().Format(time.RFC3339)
+		}
+		return e.w.Write([]string{strconv.Itoa(s.Id), s.Name, strconv.Itoa(s.Age), deletedAt})
+	}
+
+	record := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		switch field {
+		case "id":
+			record[i] = strconv.Itoa(s.Id)
+		case "name":
+			record[i] = s.Name
+		case "age":
+			record[i] = strconv.Itoa(s.Age)
+		case "deleted_at":
+			if s.DeletedAt != nil {
+				record[i] = s.DeletedAt.UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvStudentEncoder) close() error {
+	// an empty export still gets its header.
+	if !e.header {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonStudentEncoder struct {
+	enc *json.Encoder
+}
+
+func newNDJSONStudentEncoder(w io.Writer) studentEncoder {
+	return &ndjsonStudentEncoder{enc: json.NewEncoder(w)}
+}
+
+func (e *ndjsonStudentEncoder) encode(s Student) error {
+	return e.enc.Encode(s)
+}
+
+func (e *ndjsonStudentEncoder) close() error {
+	return nil
+}
+
+// jsonStudentEncoder writes a single JSON array, an element at a time.
+type jsonStudentEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONStudentEncoder(w io.Writer) studentEncoder {
+	return &jsonStudentEncoder{w: w}
+}
+
+func (e *jsonStudentEncoder) encode(s Student) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	sep := ","
+	if e.count == 0 {
+		sep = "["
+	}
+	e.count++
+	_, err = fmt.Fprintf(e.w, "%s\n%s", sep, data)
+	return err
+}
+
+func (e *jsonStudentEncoder) close() error {
+	if e.count == 0 {
+		_, err := io.WriteString(e.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(e.w, "\n]\n")
+	return err
+}
+
+// parquetStudent is the parquet schema of a student.
+type parquetStudent struct {
+	Id   int64  `parquet:"id"`
+	Name string `parquet:"name"`
+	Age  int32  `parquet:"age"`
+	// DeletedAt is null when it's the zero time.
+	DeletedAt time.Time `parquet:"deleted_at,optional,timestamp(millisecond)"`
+}
+
+type parquetStudentEncoder struct {
+	w   *parquet.GenericWriter[parquetStudent]
+	row []parquetStudent
+}
+
+func newParquetStudentEncoder(w io.Writer) studentEncoder {
+	return &parquetStudentEncoder{
+		w:   parquet.NewGenericWriter[parquetStudent](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+		row: make([]parquetStudent, 1),
+	}
+}
+
+func (e *parquetStudentEncoder) encode(s Student) error {
+	e.row[0] = parquetStudent{Id: int64(s.Id), Name: s.Name, Age: int32(s.Age)}
+	if s.DeletedAt != nil {
+		e.row[0].DeletedAt = *s.DeletedAt
+	}
+	_, err := e.w.Write(e.row)
+	return err
+}
+
+func (e *parquetStudentEncoder) close() error {
+	return e.w.Close()
+}
+
+// ExportStudents streams the students matching the same filters as ListStudents, in the format asked for by
+// ?format=csv|ndjson|json|parquet (csv by default), compressed with gzip when the client accepts it.
+func (s *Server) ExportStudents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		RespondWithError(w, "Invalid format, expected one of csv, ndjson, json or parquet", http.StatusBadRequest)
+		return
+	}
+
+	opts, ok := s.parseListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	// the first student is read before responding, so that a failing query can still be answered with an error.
+	next, stop := iter.Pull2(s.streamStudents(r, opts))
+	defer stop()
+	first, err, more := next()
+	if err != nil {
+		s.Logger.Error("error exporting students", "error", err)
+		RespondWithError(w, "Failed to export students", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("students-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format.extension)
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	var out io.Writer = w
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+
+	enc := format.newEncoder(out)
+	for student := first; more; student, err, more = next() {
+		if err == nil {
+			err = enc.encode(student)
+		}
+		if err != nil {
+			// the response has started, all that's left is to make sure it doesn't look complete.
+			s.Logger.Error("error exporting students", "error", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+	if err := enc.close(); err != nil {
+		s.Logger.Error("error exporting students", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// streamStudents iterates over the students, from a cursor when the store has one.
+func (s *Server) streamStudents(r *http.Request, opts ListOptions) iter.Seq2[Student, error] {
+	if s.Streamer != nil {
+		return s.Streamer.StreamStudents(r.Context(), opts)
+	}
+
+	return func(yield func(Student, error) bool) {
+		students, err := s.Store.ListStudents(r.Context(), opts)
+		if err != nil {
+			yield(Student{}, err)
+			return
+		}
+		for _, student := range students {
+			if !yield(student, nil) {
+				return
+			}
+		}
+	}
+}
+
+// acceptsGzip tells if the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if strings.TrimSpace(name) != "gzip" {
+				continue
+			}
+
+			// "gzip;q=0" means anything but gzip.
+			if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				weight, err := strconv.ParseFloat(q, 64)
+				return err == nil && weight > 0
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package student
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// studentFields are the fields of a student, named as they're rendered and as their columns are, in column order.
+var studentFields = []string{"id", "name", "age", "deleted_at"}
+
+// studentIncludes are the related resources ?include= can embed in a student.
+var studentIncludes = []string{"revisions"}
+
+// studentColumnsFor is the column list to select fields with, studentColumns if they're all wanted.
+func studentColumnsFor(fields []string) string {
+	if len(fields) == 0 {
+		return studentColumns
+	}
+	return strings.Join(fields, ", ")
+}
+
+// scanStudentFields scans a row selected with studentColumnsFor(fields). Fields that weren't selected are left zero.
+func scanStudentFields(row interface{ Scan(...any) error }, fields []string) (*Student, error) {
+	if len(fields) == 0 {
+		fields = studentFields
+	}
+
+	var s Student
+	dest := make([]any, len(fields))
+	for i, field := range fields {
+		switch field {
+		case "id":
+			dest[i] = &s.Id
+		case "name":
+			dest[i] = &s.Name
+		case "age":
+			dest[i] = &s.Age
+		case "deleted_at":
+			dest[i] = &s.DeletedAt
+		}
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// projection is what a client asked to see of students: only some of their fields, with ?fields=, and related
+// resources embedded in them, with ?include=. The zero value is every field, with nothing embedded.
+type projection struct {
+	// fields are in studentFields order.
+	fields  []string
+	include []string
+}
+
+func (p projection) empty() bool {
+	return len(p.fields) == 0 && len(p.include) == 0
+}
+
+// columns are the fields to read from the store, nil for all of them. The id is always read: lists are paged by it,
+// and related resources are looked up by it.
+func (p projection) columns() []string {
+	if len(p.fields) == 0 || slices.Contains(p.fields, "id") {
+		return p.fields
+	}
+	return append([]string{"id"}, p.fields...)
+}
+
+// parseProjection reads ?fields= and ?include=, comma separated lists. It responds itself when they name something a
+// student doesn't have, or that can't be rendered in rep.
+func (s *Server) parseProjection(w http.ResponseWriter, r *http.Request, rep representation) (projection, bool) {
+	var p projection
+	if v := r.URL.Query().Get("fields"); v != "" {
+		requested := strings.Split(v, ",")
+		for i, field := range requested {
+			requested[i] = strings.TrimSpace(field)
+			if !slices.Contains(studentFields, requested[i]) {
+				RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+					Error:   "invalid_fields",
+					Message: "students have no field " + requested[i],
+					Details: map[string]any{"supported": studentFields},
+				})
+				return p, false
+			}
+		}
+		for _, field := range studentFields {
+			if slices.Contains(requested, field) {
+				p.fields = append(p.fields, field)
+			}
+		}
+	}
+
+	if v := r.URL.Query().Get("include"); v != "" {
+		for _, include := range strings.Split(v, ",") {
+			include = strings.TrimSpace(include)
+			if !slices.Contains(studentIncludes, include) {
+				RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+					Error:   "invalid_include",
+					Message: "students have no related resource " + include,
+					Details: map[string]any{"supported": studentIncludes},
+				})
+				return p, false
+			}
+			if !slices.Contains(p.include, include) {
+				p.include = append(p.include, include)
+			}
+		}
+
+		if rep.mediaType == "text/csv" {
+			RespondWithError(w, "Related resources can't be embedded in csv", http.StatusBadRequest)
+			return p, false
+		}
+		if slices.Contains(p.include, "revisions") && s.Revisions == nil {
+			RespondWithError(w, "Revision history is not available", http.StatusNotImplemented)
+			return p, false
+		}
+	}
+	return p, true
+}
+
+// studentView is a student as a projection renders it: only the fields asked for, along with the related resources
+// embedded in it. What's left nil isn't rendered.
+type studentView struct {
+	Id        *int       `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty" msgpack:"id,omitempty"`
+	Name      *string    `json:"name,omitempty" xml:"name,omitempty" yaml:"name,omitempty" msgpack:"name,omitempty"`
+	Age       *int       `json:"age,omitempty" xml:"age,omitempty" yaml:"age,omitempty" msgpack:"age,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" xml:"deleted_at,omitempty" yaml:"deleted_at,omitempty" msgpack:"deleted_at,omitempty"`
+	Revisions []Revision `json:"revisions,omitempty" xml:"revision,omitempty" yaml:"revisions,omitempty" msgpack:"revisions,omitempty"`
+}
+
+// student is the student the view was rendered from, with the fields it doesn't hold left zero.
+func (v studentView) student() Student {
+	var s Student
+	if v.Id != nil {
+		s.Id = *v.Id
+	}
+	if v.Name != nil {
+		s.Name = *v.Name
+	}
+	if v.Age != nil {
+		s.Age = *v.Age
+	}
+	s.DeletedAt = v.DeletedAt
+	return s
+}
+
+// studentViews is a list of students as a projection renders it. It's rendered as the list of views, but for csv,
+// which needs to know the fields for its header.
+type studentViews struct {
+	fields []string
+	views  []studentView
+}
+
+func (v studentViews) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.views)
+}
+
+func (v studentViews) MarshalYAML() (any, error) {
+	return v.views, nil
+}
+
+func (v studentViews) EncodeMsgpack(enc *msgpack.Encoder) error {
+	return enc.Encode(v.views)
+}
+
+// view renders a student, which is expected to hold the fields of p, along with its revisions if p embeds them.
+func (p projection) view(student Student, revisions []Revision) studentView {
+	var v studentView
+	fields := p.fields
+	if len(fields) == 0 {
+		fields = studentFields
+	}
+	for _, field := range fields {
+		switch field {
+		case "id":
+			v.Id = &student.Id
+		case "name":
+			v.Name = &student.Name
+		case "age":
+			v.Age = &student.Age
+		case "deleted_at":
+			v.DeletedAt = student.DeletedAt
+		}
+	}
+
+	if slices.Contains(p.include, "revisions") {
+		v.Revisions = revisions
+	}
+	return v
+}
+
+// project renders a student as p asks, or as it is when p is empty.
+func (s *Server) project(ctx context.Context, p projection, student Student) (any, error) {
+	if p.empty() {
+		return student, nil
+	}
+
+	var revisions []Revision
+	if slices.Contains(p.include, "revisions") {
+		var err error
+		if revisions, err = s.Revisions.ListRevisions(ctx, student.Id); err != nil {
+			return nil, err
+		}
+	}
+	return p.view(student, revisions), nil
+}
+
+// projectList renders a list of students as p asks, or as it is when p is empty. What they embed is read for the whole
+// list at once.
+func (s *Server) projectList(ctx context.Context, p projection, students []Student) (any, error) {
+	if p.empty() {
+		return students, nil
+	}
+
+	var revisions map[int][]Revision
+	if slices.Contains(p.include, "revisions") && len(students) > 0 {
+		ids := make([]int, len(students))
+		for i, student := range students {
+			ids[i] = student.Id
+		}
+		var err error
+		if revisions, err = s.Revisions.ListRevisionsOf(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	views := studentViews{fields: p.fields, views: make([]studentView, 0, len(students))}
+	for _, student := range students {
+		views.views = append(views.views, p.view(student, revisions[student.Id]))
+	}
+	return views, nil
+}
@@ -0,0 +1,392 @@
+package student
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxAssessmentTitleLength = 255
+	maxAssessmentWeight      = 100
+	maxAssessmentScore       = 10_000
+)
+
+// Assessment is a piece of graded work in a course: an exam, an assignment, a project.
+type Assessment struct {
+	Id       int    `json:"id"`
+	CourseId int    `json:"course_id"`
+	Title    string `json:"title"`
+	// Weight is how much the assessment counts towards the grade of the course, relative to its other assessments.
+	Weight float64 `json:"weight"`
+	// MaxScore is full marks.
+	MaxScore float64 `json:"max_score"`
+}
+
+// Score is what a student got in an assessment, out of its MaxScore.
+type Score struct {
+	AssessmentId int       `json:"assessment_id"`
+	StudentId    int       `json:"student_id"`
+	Score        float64   `json:"score"`
+	GradedAt     time.Time `json:"graded_at"`
+}
+
+// GradedAssessment is an assessment along with a student's score in it, nil until it's scored.
+type GradedAssessment struct {
+	Assessment Assessment `json:"assessment"`
+	Score      *float64   `json:"score"`
+}
+
+// CourseGrades is a course a student is enrolled in, with the student's scores in its assessments.
+type CourseGrades struct {
+	Course      Course
+	Assessments []GradedAssessment
+}
+
+// errNotEnrolled fails scoring a student that isn't enrolled in the course of the assessment.
+type errNotEnrolled struct {
+	studentId int
+}
+
+func (e errNotEnrolled) Error() string {
+	return fmt.Sprintf("student %d is not enrolled in the course", e.studentId)
+}
+
+// letterGrades are the letters percentages are graded with, and the grade points each is worth, best first.
+var letterGrades = []struct {
+	min    float64
+	letter string
+	points float64
+}{
+	{90, "A", 4},
+	{80, "B", 3},
+	{70, "C", 2},
+	{60, "D", 1},
+	{0, "F", 0},
+}
+
+// courseGrade is the weighted percentage of the scored assessments, nil if none is scored yet. Assessments that aren't
+// scored are left out, so that the grade of a course in progress is the grade so far.
+func courseGrade(assessments []GradedAssessment) *float64 {
+	var total, weights float64
+	for _, a := range assessments {
+		if a.Score == nil {
+			continue
+		}
+		total += a.Assessment.Weight * *a.Score / a.Assessment.MaxScore
+		weights += a.Assessment.Weight
+	}
+	if weights == 0 {
+		return nil
+	}
+
+	grade := round2(total / weights * 100)
+	return &grade
+}
+
+// letterGrade returns the letter a percentage is graded with, and the grade points it's worth.
+func letterGrade(percentage float64) (string, float64) {
+	for _, g := range letterGrades {
+		if percentage >= g.min {
+			return g.letter, g.points
+		}
+	}
+	last := letterGrades[len(letterGrades)-1]
+	return last.letter, last.points
+}
+
+// gpa is the mean of the grade points of the graded courses, nil if none is graded yet.
+func gpa(courses []TranscriptCourse) *float64 {
+	var total float64
+	var graded int
+	for _, c := range courses {
+		if c.GradePoints != nil {
+			total += *c.GradePoints
+			graded++
+		}
+	}
+	if graded == 0 {
+		return nil
+	}
+
+	gpa := round2(total / float64(graded))
+	return &gpa
+}
+
+func validateAssessment(a Assessment) map[string]any {
+	details := make(map[string]any)
+	if strings.TrimSpace(a.Title) == "" {
+		details["title"] = "is required"
+	} else if len(a.Title) > maxAssessmentTitleLength {
+		details["title"] = fmt.Sprintf("must be at most %d characters", maxAssessmentTitleLength)
+	}
+	if a.Weight <= 0 || a.Weight > maxAssessmentWeight {
+		details["weight"] = fmt.Sprintf("must be more than 0 and at most %d", maxAssessmentWeight)
+	}
+	if a.MaxScore <= 0 || a.MaxScore > maxAssessmentScore {
+		details["max_score"] = fmt.Sprintf("must be more than 0 and at most %d", maxAssessmentScore)
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// validateScores checks scores that are about to be recorded for a.
+func validateScores(a Assessment, scores []Score) map[string]any {
+	details := make(map[string]any)
+	if len(scores) == 0 {
+		details["scores"] = "must not be empty"
+	} else if len(scores) > maxCourseCapacity {
+		details["scores"] = fmt.Sprintf("must be at most %d", maxCourseCapacity)
+	}
+
+	seen := make(map[int]bool)
+	for i, score := range scores {
+		field := fmt.Sprintf("scores[%d]", i)
+		if score.StudentId < 1 {
+			details[field+".student_id"] = "is required"
+		} else if seen[score.StudentId] {
+			details[field+".student_id"] = "is scored twice"
+		}
+		seen[score.StudentId] = true
+
+		if score.Score < 0 || score.Score > a.MaxScore {
+			details[field+".score"] = fmt.Sprintf("must be between 0 and %g", a.MaxScore)
+		}
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// AssessmentsHandler lists the assessments of a course, or creates one.
+func (s *Server) AssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid courseId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Grades == nil {
+		RespondWithError(w, "Grades are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		assessments, err := s.Grades.ListAssessments(r.Context(), courseId)
+		if err != nil {
+			s.respondGradeError(w, "error listing assessments", courseId, err)
+			return
+		}
+		if assessments == nil {
+			assessments = []Assessment{}
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, assessments)
+	case http.MethodPost:
+		assessment, ok := s.decodeAssessment(w, r)
+		if !ok {
+			return
+		}
+		assessment.CourseId = courseId
+
+		id, err := s.Grades.CreateAssessment(r.Context(), assessment)
+		if err != nil {
+			s.respondGradeError(w, "error creating assessment", courseId, err)
+			return
+		}
+		assessment.Id = id
+
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/courses/%d/assessments/%d", courseId, id))
+		respondWithJSON(w, s.Logger, http.StatusCreated, assessment)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// AssessmentHandler reads, replaces or deletes a single assessment.
+func (s *Server) AssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, assessmentId, ok := assessmentPath(w, r)
+	if !ok {
+		return
+	}
+
+	if s.Grades == nil {
+		RespondWithError(w, "Grades are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		assessment, err := s.Grades.GetAssessment(r.Context(), courseId, assessmentId)
+		if err != nil {
+			s.respondGradeError(w, "error reading assessment", courseId, err)
+			return
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, assessment)
+	case http.MethodPatch:
+		assessment, ok := s.decodeAssessment(w, r)
+		if !ok {
+			return
+		}
+		if err := s.Grades.UpdateAssessment(r.Context(), courseId, assessmentId, assessment); err != nil {
+			s.respondGradeError(w, "error updating assessment", courseId, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := s.Grades.DeleteAssessment(r.Context(), courseId, assessmentId); err != nil {
+			s.respondGradeError(w, "error deleting assessment", courseId, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// ScoresHandler lists the scores of an assessment, or records some.
+func (s *Server) ScoresHandler(w http.ResponseWriter, r *http.Request) {
+	courseId, assessmentId, ok := assessmentPath(w, r)
+	if !ok {
+		return
+	}
+
+	if s.Grades == nil {
+		RespondWithError(w, "Grades are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		scores, err := s.Grades.ListScores(r.Context(), courseId, assessmentId)
+		if err != nil {
+			s.respondGradeError(w, "error listing scores", courseId, err)
+			return
+		}
+		if scores == nil {
+			scores = []Score{}
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, scores)
+	case http.MethodPut:
+		s.recordScores(w, r, courseId, assessmentId)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// recordScores records the scores of a whole sheet at once, replacing those the students had.
+func (s *Server) recordScores(w http.ResponseWriter, r *http.Request, courseId, assessmentId int) {
+	var req struct {
+		Scores []struct {
+			StudentId int     `json:"student_id"`
+			Score     float64 `json:"score"`
+		} `json:"scores"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.Logger.Error("error unmarshalling request body", "error", err)
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	assessment, err := s.Grades.GetAssessment(r.Context(), courseId, assessmentId)
+	if err != nil {
+		s.respondGradeError(w, "error reading assessment", courseId, err)
+		return
+	}
+
+	scores := make([]Score, 0, len(req.Scores))
+	for _, score := range req.Scores {
+		scores = append(scores, Score{AssessmentId: assessmentId, StudentId: score.StudentId, Score: score.Score})
+	}
+	if details := validateScores(*assessment, scores); details != nil {
+		RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_scores",
+			Message: "the scores are invalid",
+			Details: details,
+		})
+		return
+	}
+
+	if err := s.Grades.RecordScores(r.Context(), courseId, assessmentId, scores); err != nil {
+		s.respondGradeError(w, "error recording scores", courseId, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// assessmentPath reads the course and assessment ids off the path. It responds itself when they're invalid.
+func assessmentPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	courseId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid courseId", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	assessmentId, err := strconv.Atoi(r.PathValue("assessmentId"))
+	if err != nil {
+		RespondWithError(w, "Invalid assessmentId", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return courseId, assessmentId, true
+}
+
+// decodeAssessment reads and validates the assessment in the request body. It responds itself when the assessment is
+// invalid.
+func (s *Server) decodeAssessment(w http.ResponseWriter, r *http.Request) (Assessment, bool) {
+	var req struct {
+		Title    string  `json:"title"`
+		Weight   float64 `json:"weight"`
+		MaxScore float64 `json:"max_score"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.Logger.Error("error unmarshalling request body", "error", err)
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return Assessment{}, false
+	}
+
+	assessment := Assessment{Title: strings.TrimSpace(req.Title), Weight: req.Weight, MaxScore: req.MaxScore}
+	if details := validateAssessment(assessment); details != nil {
+		RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_assessment",
+			Message: "the assessment is invalid",
+			Details: details,
+		})
+		return Assessment{}, false
+	}
+	return assessment, true
+}
+
+func (s *Server) respondGradeError(w http.ResponseWriter, msg string, courseId int, err error) {
+	s.Logger.Error(msg, "courseId", courseId, "error", err)
+
+	var notEnrolled errNotEnrolled
+	if errors.As(err, &notEnrolled) {
+		RespondWithJSONError(w, http.StatusConflict, ErrorResponse{
+			Error:   "not_enrolled",
+			Message: "only students enrolled in the course can be scored",
+			Details: map[string]any{"student_id": notEnrolled.studentId},
+		})
+		return
+	}
+
+	switch err.Error() {
+	case errCourseNotFound, errAssessmentNotFound, errStudentNotFound:
+		RespondWithError(w, err.Error(), http.StatusNotFound)
+	default:
+		RespondWithError(w, msg, http.StatusInternalServerError)
+	}
+}
+
+// round2 rounds to two decimals, which is as precise as grades are given.
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,907 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// limits on what a single GraphQL operation can ask for. Depth counts nested selections, complexity counts fields, with
+// those under a paginated field counted once for every item of the page it asks for. Introspection nests deeper than
+// any query of students needs to, down the ofType of type references, so it's held to a depth limit of its own that
+// the introspection query of GraphQL clients fits in.
+const (
+	graphQLMaxDepth              = 8
+	graphQLMaxIntrospectionDepth = 15
+	graphQLMaxComplexity         = 2000
+
+	graphQLDefaultPageSize = 20
+)
+
+// graphQLPaginatedFields are the fields that take a first argument, and return a page of graphQLDefaultPageSize without
+// one.
+var graphQLPaginatedFields = []string{"students", "auditEvents"}
+
+// GraphQLRequest is a GraphQL operation, as sent in the body of a POST or the query string of a GET.
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// graphQLError is an error resolving a field, with a code in its extensions that clients can act on.
+type graphQLError struct {
+	code    string
+	message string
+	details map[string]any
+}
+
+func (e *graphQLError) Error() string {
+	return e.message
+}
+
+func (e *graphQLError) Extensions() map[string]any {
+	extensions := map[string]any{"code": e.code}
+	for k, v := range e.details {
+		extensions[k] = v
+	}
+	return extensions
+}
+
+var errGraphQLInternal = &graphQLError{code: "INTERNAL", message: "internal error"}
+
+// graphQLContext is what resolvers share over the course of a request.
+type graphQLContext struct {
+	server   *Server
+	students *studentLoader
+	// subscribedAt is the latest change event when a subscription was requested, which it follows on from unless it's
+	// told where to.
+	subscribedAt int64
+}
+
+type graphQLContextKey struct{}
+
+func graphQLContextFrom(ctx context.Context) *graphQLContext {
+	return ctx.Value(graphQLContextKey{}).(*graphQLContext)
+}
+
+// require checks a permission for a field that needs more than the route does, like require does for HTTP requests.
+func (g *graphQLContext) require(ctx context.Context, field, permission string) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return &graphQLError{code: "UNAUTHENTICATED", message: "unauthenticated"}
+	}
+	if !g.server.policy().Grants(principal, permission) {
+		reason := fmt.Sprintf("missing permission %q", permission)
+		g.server.recordDenial(ctx, principal, permission, "graphql "+field, reason)
+		return &graphQLError{code: "FORBIDDEN", message: reason, details: map[string]any{"permission": permission}}
+	}
+	return nil
+}
+
+// internal logs an unexpected error and hides it from the client.
+func (g *graphQLContext) internal(msg string, err error) error {
+	g.server.Logger.Error(msg, "error", err)
+	return errGraphQLInternal
+}
+
+// studentLoader batches the students a request looks up by id. Loading one only queues its id, and the first result
+// asked for fetches every student queued by then in a single lookup. The executor asks for results once it's resolved
+// every sibling field, so the students of a whole list are fetched at once.
+type studentLoader struct {
+	server  *Server
+	mu      sync.Mutex
+	pending []int
+	loaded  map[int]studentResult
+}
+
+type studentResult struct {
+	student *Student
+	err     error
+}
+
+func newStudentLoader(server *Server) *studentLoader {
+	return &studentLoader{server: server, loaded: make(map[int]studentResult)}
+}
+
+// load returns a thunk for the student with id, which resolves to nil if there's no such student.
+func (l *studentLoader) load(ctx context.Context, id int) func() (any, error) {
+	l.mu.Lock()
+	if _, ok := l.loaded[id]; !ok && !slices.Contains(l.pending, id) {
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, ok := l.loaded[id]; !ok {
+			l.fetch(ctx)
+		}
+		result := l.loaded[id]
+		if result.err != nil {
+			return nil, result.err
+		}
+		if result.student == nil {
+			return nil, nil
+		}
+		return *result.student, nil
+	}
+}
+
+func (l *studentLoader) fetch(ctx context.Context) {
+	ids := l.pending
+	l.pending = nil
+
+	students, err := l.server.getStudents(ctx, ids)
+	for _, id := range ids {
+		l.loaded[id] = studentResult{err: err}
+	}
+	for _, s := range students {
+		l.loaded[s.Id] = studentResult{student: &s}
+	}
+}
+
+// getStudents looks the live students with ids up in one go if the store can, and one by one otherwise.
+func (s *Server) getStudents(ctx context.Context, ids []int) ([]Student, error) {
+	if s.Batch != nil {
+		return s.Batch.GetStudents(ctx, ids)
+	}
+
+	var students []Student
+	for _, id := range ids {
+		student, err := s.Store.GetStudent(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, err
+		}
+		students = append(students, *student)
+	}
+	return students, nil
+}
+
+// studentPage is a page of the students query.
+type studentPage struct {
+	Nodes       []Student `json:"nodes"`
+	EndCursor   *int      `json:"endCursor"`
+	HasNextPage bool      `json:"hasNextPage"`
+}
+
+// studentFilter narrows down the students query. Zero values don't filter.
+type studentFilter struct {
+	nameContains   string
+	minAge, maxAge int
+}
+
+func (f studentFilter) empty() bool {
+	return f == studentFilter{}
+}
+
+func (f studentFilter) matches(s Student) bool {
+	if f.nameContains != "" && !strings.Contains(strings.ToLower(s.Name), strings.ToLower(f.nameContains)) {
+		return false
+	}
+	if f.minAge > 0 && s.Age < f.minAge {
+		return false
+	}
+	if f.maxAge > 0 && s.Age > f.maxAge {
+		return false
+	}
+	return true
+}
+
+// listStudentPage lists up to first students after the cursor that match the filter. The store can't filter, so
+// filtered pages are read in batches until they're full.
+func (s *Server) listStudentPage(ctx context.Context, opts ListOptions, filter studentFilter, first int) (studentPage, error) {
+	opts.Limit = first + 1
+	if !filter.empty() {
+		opts.Limit = maxListLimit
+	}
+
+	page := studentPage{Nodes: []Student{}}
+	for {
+		students, err := s.Store.ListStudents(ctx, opts)
+		if err != nil {
+			return studentPage{}, err
+		}
+
+		for _, student := range students {
+			if !filter.matches(student) {
+				continue
+			}
+			if len(page.Nodes) == first {
+				page.HasNextPage = true
+				return page, nil
+			}
+			page.Nodes = append(page.Nodes, student)
+			page.EndCursor = &student.Id
+		}
+		if len(students) < opts.Limit {
+			return page, nil
+		}
+		opts.AfterId = students[len(students)-1].Id
+	}
+}
+
+func studentInput(args map[string]any) Student {
+	input := args["input"].(map[string]any)
+	return Student{Name: input["name"].(string), Age: input["age"].(int)}
+}
+
+func invalidGraphQLStudent(details map[string]any) error {
+	return &graphQLError{code: "BAD_USER_INPUT", message: "invalid student", details: map[string]any{"fields": details}}
+}
+
+// auditSnapshot is the student recorded before or after an audited change, if there is one.
+func auditSnapshot(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var s Student
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+var graphQLSchema = sync.OnceValue(func() graphql.Schema {
+	schema, err := graphql.NewSchema(newGraphQLSchemaConfig())
+	if err != nil {
+		panic(fmt.Sprintf("graphql schema is invalid: %v", err))
+	}
+	return schema
+})
+
+func newGraphQLSchemaConfig() graphql.SchemaConfig {
+	studentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Student",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"age":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"deletedAt": &graphql.Field{Type: graphql.DateTime, Description: "When the student was moved to the trash."},
+		},
+	})
+
+	revisionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Revision",
+		Fields: graphql.Fields{
+			"revision":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"action":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"actor":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"student":   &graphql.Field{Type: graphql.NewNonNull(studentType), Description: "The student as the change left it."},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	auditEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AuditEvent",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"actor":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"action":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"studentId": &graphql.Field{Type: graphql.Int},
+			"student": &graphql.Field{
+				Type:        studentType,
+				Description: "The student as it is now, unless it's been deleted since.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					e := p.Source.(AuditEvent)
+					if e.StudentId == nil {
+						return nil, nil
+					}
+					return graphQLContextFrom(p.Context).students.load(p.Context, *e.StudentId), nil
+				},
+			},
+			"before": &graphql.Field{
+				Type: studentType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return auditSnapshot(p.Source.(AuditEvent).Before)
+				},
+			},
+			"after": &graphql.Field{
+				Type: studentType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return auditSnapshot(p.Source.(AuditEvent).After)
+				},
+			},
+			"requestId": &graphql.Field{Type: graphql.String},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	studentType.AddFieldConfig("revisions", &graphql.Field{
+		Type:        graphql.NewList(graphql.NewNonNull(revisionType)),
+		Description: "Every version of the student, oldest first.",
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			g := graphQLContextFrom(p.Context)
+			if g.server.Revisions == nil {
+				return nil, &graphQLError{code: "NOT_IMPLEMENTED", message: "revisions are not supported"}
+			}
+
+			revisions, err := g.server.Revisions.ListRevisions(p.Context, p.Source.(Student).Id)
+			if err != nil {
+				return nil, g.internal("error listing revisions", err)
+			}
+			return revisions, nil
+		},
+	})
+	studentType.AddFieldConfig("auditEvents", &graphql.Field{
+		Type:        graphql.NewList(graphql.NewNonNull(auditEventType)),
+		Description: "The changes made to the student, newest first. Needs the audit:read permission.",
+		Args: graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: graphQLDefaultPageSize},
+		},
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			g := graphQLContextFrom(p.Context)
+			if err := g.require(p.Context, "Student.auditEvents", ScopeAuditRead); err != nil {
+				return nil, err
+			}
+			if g.server.Audit == nil {
+				return nil, &graphQLError{code: "NOT_IMPLEMENTED", message: "the audit log is not supported"}
+			}
+
+			id := p.Source.(Student).Id
+			events, err := g.server.Audit.ListAuditEvents(p.Context, AuditFilter{StudentId: &id, Limit: pageSize(p.Args)})
+			if err != nil {
+				return nil, g.internal("error listing audit events", err)
+			}
+			return events, nil
+		},
+	})
+
+	studentPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StudentPage",
+		Fields: graphql.Fields{
+			"nodes":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(studentType)))},
+			"endCursor":   &graphql.Field{Type: graphql.Int, Description: "Pass as after to get the next page."},
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+
+	studentEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "StudentEvent",
+		Description: "A change to a student, from the change feed.",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"type":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"studentId":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"student":    &graphql.Field{Type: studentType, Description: "The student as the change left it, empty for purges."},
+			"actor":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"requestId":  &graphql.Field{Type: graphql.String},
+			"occurredAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	studentFilterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "StudentFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"nameContains": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Matched case insensitively."},
+			"minAge":       &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"maxAge":       &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+
+	studentInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "StudentInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"age":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"student": &graphql.Field{
+				Type:        studentType,
+				Description: "A live student, or null if there's none with the id.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return graphQLContextFrom(p.Context).students.load(p.Context, p.Args["id"].(int)), nil
+				},
+			},
+			"students": &graphql.Field{
+				Type:        graphql.NewNonNull(studentPageType),
+				Description: "Pages through the students in id order. Listing deleted ones needs the students:delete permission.",
+				Args: graphql.FieldConfigArgument{
+					"filter":  &graphql.ArgumentConfig{Type: studentFilterType},
+					"deleted": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+					"after":   &graphql.ArgumentConfig{Type: graphql.Int},
+					"first":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: graphQLDefaultPageSize},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					g := graphQLContextFrom(p.Context)
+					var opts ListOptions
+					if p.Args["deleted"].(bool) {
+						if err := g.require(p.Context, "Query.students", ScopeStudentsDelete); err != nil {
+							return nil, err
+						}
+						opts.OnlyDeleted = true
+					}
+					if after, ok := p.Args["after"].(int); ok {
+						opts.AfterId = after
+					}
+
+					var filter studentFilter
+					if f, ok := p.Args["filter"].(map[string]any); ok {
+						filter.nameContains, _ = f["nameContains"].(string)
+						filter.minAge, _ = f["minAge"].(int)
+						filter.maxAge, _ = f["maxAge"].(int)
+					}
+
+					page, err := g.server.listStudentPage(p.Context, opts, filter, pageSize(p.Args))
+					if err != nil {
+						return nil, g.internal("error listing students", err)
+					}
+					return page, nil
+				},
+			},
+			"auditEvents": &graphql.Field{
+				Type:        graphql.NewList(graphql.NewNonNull(auditEventType)),
+				Description: "The audit log, newest first. Needs the audit:read permission.",
+				Args: graphql.FieldConfigArgument{
+					"studentId": &graphql.ArgumentConfig{Type: graphql.Int},
+					"actor":     &graphql.ArgumentConfig{Type: graphql.String},
+					"action":    &graphql.ArgumentConfig{Type: graphql.String},
+					"before":    &graphql.ArgumentConfig{Type: graphql.Int, Description: "The id of the last event seen."},
+					"first":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: graphQLDefaultPageSize},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					g := graphQLContextFrom(p.Context)
+					if err := g.require(p.Context, "Query.auditEvents", ScopeAuditRead); err != nil {
+						return nil, err
+					}
+					if g.server.Audit == nil {
+						return nil, &graphQLError{code: "NOT_IMPLEMENTED", message: "the audit log is not supported"}
+					}
+
+					f := AuditFilter{Limit: pageSize(p.Args)}
+					if id, ok := p.Args["studentId"].(int); ok {
+						f.StudentId = &id
+					}
+					f.Actor, _ = p.Args["actor"].(string)
+					f.Action, _ = p.Args["action"].(string)
+					if before, ok := p.Args["before"].(int); ok {
+						f.BeforeId = int64(before)
+					}
+
+					events, err := g.server.Audit.ListAuditEvents(p.Context, f)
+					if err != nil {
+						return nil, g.internal("error listing audit events", err)
+					}
+					return events, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createStudent": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Needs the students:write permission.",
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(studentInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					g := graphQLContextFrom(p.Context)
+					if err := g.require(p.Context, "Mutation.createStudent", ScopeStudentsWrite); err != nil {
+						return nil, err
+					}
+
+					student := studentInput(p.Args)
+					if details := validateStudent(student); details != nil {
+						return nil, invalidGraphQLStudent(details)
+					}
+					if err := g.server.Store.CreateStudent(p.Context, student); err != nil {
+						return nil, g.internal("error creating student", err)
+					}
+
+					g.server.notifyEvents()
+					return true, nil
+				},
+			},
+			"updateStudent": &graphql.Field{
+				Type:        graphql.NewNonNull(studentType),
+				Description: "Needs the students:write permission. Returns the student as updated.",
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(studentInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					g := graphQLContextFrom(p.Context)
+					if err := g.require(p.Context, "Mutation.updateStudent", ScopeStudentsWrite); err != nil {
+						return nil, err
+					}
+
+					id := p.Args["id"].(int)
+					student := studentInput(p.Args)
+					if details := validateStudent(student); details != nil {
+						return nil, invalidGraphQLStudent(details)
+					}
+					if err := g.server.Store.UpdateStudent(p.Context, id, student); err != nil {
+						return nil, g.studentError("error updating student", err)
+					}
+
+					g.server.notifyEvents()
+					updated, err := g.server.Store.GetStudent(p.Context, id)
+					if err != nil {
+						return nil, g.studentError("error getting student", err)
+					}
+					return *updated, nil
+				},
+			},
+			"deleteStudent": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Moves the student to the trash. Needs the students:delete permission.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					g := graphQLContextFrom(p.Context)
+					if err := g.require(p.Context, "Mutation.deleteStudent", ScopeStudentsDelete); err != nil {
+						return nil, err
+					}
+
+					if err := g.server.Store.DeleteStudent(p.Context, p.Args["id"].(int)); err != nil {
+						return nil, g.studentError("error deleting student", err)
+					}
+
+					g.server.notifyEvents()
+					return true, nil
+				},
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"studentChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(studentEventType),
+				Description: "Every change to a student, from the change event after the one given on, or the next one.",
+				Args: graphql.FieldConfigArgument{
+					"after": &graphql.ArgumentConfig{Type: graphql.Int, Description: "The id of the last change event seen."},
+				},
+				Subscribe: func(p graphql.ResolveParams) (any, error) {
+					g := graphQLContextFrom(p.Context)
+					s := g.server
+
+					after := g.subscribedAt
+					if v, ok := p.Args["after"].(int); ok {
+						after = int64(v)
+					}
+
+					events := make(chan any)
+					go func() {
+						defer close(events)
+						s.streamEvents(p.Context, &eventStream{
+							lastId: after,
+							deliver: func(e Event) error {
+								select {
+								case events <- e:
+									return nil
+								case <-p.Context.Done():
+									return p.Context.Err()
+								}
+							},
+							// heartbeats are sent by the handler, between results.
+							heartbeat: func(int64) error { return nil },
+						})
+					}()
+					return events, nil
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.SchemaConfig{Query: query, Mutation: mutation, Subscription: subscription}
+}
+
+// studentError maps an error from the store about a single student to a GraphQL error.
+func (g *graphQLContext) studentError(msg string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) || err.Error() == errStudentNotFound {
+		return &graphQLError{code: "NOT_FOUND", message: errStudentNotFound}
+	}
+	if err.Error() == errStudentEnrolled {
+		return &graphQLError{code: "CONFLICT", message: "the student is enrolled, or waitlisted, in courses"}
+	}
+	return g.internal(msg, err)
+}
+
+// pageSize is the page size a field was asked for, capped like list limits are.
+func pageSize(args map[string]any) int {
+	first, _ := args["first"].(int)
+	return max(1, min(first, maxListLimit))
+}
+
+// GraphQL executes queries and mutations, and streams subscriptions as server-sent events to clients that accept
+// them. Mutations can only be sent with a POST. Every field is covered by the permission of the route, students:read,
+// and fields that need more check for it themselves. Those are nullable, so a denial only takes out the one field.
+func (s *Server) GraphQL(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				respondWithGraphQLErrors(w, http.StatusBadRequest, errors.New("variables should be a JSON object"))
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithGraphQLErrors(w, http.StatusBadRequest, errors.New("the body should be a JSON GraphQL request"))
+			return
+		}
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		respondWithGraphQLErrors(w, http.StatusBadRequest, errors.New("a query is required"))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		respondWithGraphQLErrors(w, http.StatusOK, err)
+		return
+	}
+	op := findOperation(doc, req.OperationName)
+	if op != nil {
+		if err := checkGraphQLLimits(doc, op, req.Variables); err != nil {
+			respondWithGraphQLErrors(w, http.StatusOK, err)
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), graphQLContextKey{}, &graphQLContext{server: s, students: newStudentLoader(s)})
+	params := graphql.Params{
+		Schema:         graphQLSchema(),
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        ctx,
+	}
+
+	if op != nil && op.Operation == ast.OperationTypeSubscription {
+		s.graphQLSubscription(w, r, params)
+		return
+	}
+	if op != nil && op.Operation == ast.OperationTypeMutation && r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		respondWithGraphQLErrors(w, http.StatusMethodNotAllowed, errors.New("mutations can only be sent with a POST"))
+		return
+	}
+
+	respondWithGraphQL(w, http.StatusOK, graphql.Do(params))
+}
+
+// graphQLSubscription streams the results of a subscription following the distinct connections mode of the GraphQL
+// over SSE protocol: a next event for every result, and a complete event when it ends.
+func (s *Server) graphQLSubscription(w http.ResponseWriter, r *http.Request, params graphql.Params) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		respondWithGraphQLErrors(w, http.StatusNotAcceptable, errors.New("subscriptions are streamed as text/event-stream"))
+		return
+	}
+	if s.Events == nil {
+		respondWithGraphQLErrors(w, http.StatusNotImplemented, errors.New("subscriptions are not supported"))
+		return
+	}
+
+	// subscriptions are resolved in the background, so where they start is settled now, before the client is told it's
+	// subscribed, rather than whenever that happens.
+	latest, err := s.Events.Outbox.LatestEventId(r.Context())
+	if err != nil {
+		s.Logger.Error("error reading latest event id", "error", err)
+		respondWithGraphQLErrors(w, http.StatusInternalServerError, errGraphQLInternal)
+		return
+	}
+	graphQLContextFrom(params.Context).subscribedAt = latest
+
+	ctx, cancel := context.WithCancel(params.Context)
+	params.Context = ctx
+	results := graphql.Subscribe(params)
+	defer func() {
+		cancel()
+		// the executor blocks on sending results until they're read.
+		for range results {
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	sw := newStreamWriter(w)
+	if err := sw.write(": subscribed\n\n"); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(s.Events.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				_ = sw.write("event: complete\ndata:\n\n")
+				return
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				s.Logger.Error("error encoding subscription result", "error", err)
+				return
+			}
+			if err := sw.write(fmt.Sprintf("event: next\ndata: %s\n\n", data)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := sw.write(sseHeartbeat(0)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func respondWithGraphQL(w http.ResponseWriter, status int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// respondWithGraphQLErrors responds with a request that failed before it could be executed.
+func respondWithGraphQLErrors(w http.ResponseWriter, status int, err error) {
+	formatted := gqlerrors.FormatError(err)
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+	respondWithGraphQL(w, status, &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}})
+}
+
+// findOperation returns the operation a request asks to execute, or nil if it's ambiguous, which execution will report.
+func findOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = op
+		} else if op.Name != nil && op.Name.Value == name {
+			return op
+		}
+	}
+	return found
+}
+
+// checkGraphQLLimits rejects operations that nest deeper than graphQLMaxDepth, or graphQLMaxIntrospectionDepth under an
+// introspection field, or add up to more than graphQLMaxComplexity.
+func checkGraphQLLimits(doc *ast.Document, op *ast.OperationDefinition, variables map[string]any) error {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	m := graphQLMeasure{fragments: fragments, variables: variables, visiting: make(map[string]bool)}
+	depth, introspectionDepth, complexity := m.selectionSet(op.SelectionSet)
+	if depth > graphQLMaxDepth {
+		return &graphQLError{
+			code:    "QUERY_TOO_DEEP",
+			message: fmt.Sprintf("the operation is nested %d levels deep, the most allowed is %d", depth, graphQLMaxDepth),
+		}
+	}
+	if introspectionDepth > graphQLMaxIntrospectionDepth {
+		return &graphQLError{
+			code: "QUERY_TOO_DEEP",
+			message: fmt.Sprintf("the introspection is nested %d levels deep, the most allowed is %d", introspectionDepth,
+				graphQLMaxIntrospectionDepth),
+		}
+	}
+	if complexity > graphQLMaxComplexity {
+		return &graphQLError{
+			code:    "QUERY_TOO_COMPLEX",
+			message: fmt.Sprintf("the operation has a complexity of %d, the most allowed is %d", complexity, graphQLMaxComplexity),
+		}
+	}
+	return nil
+}
+
+type graphQLMeasure struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// visiting guards against fragment cycles, which validation rejects later on.
+	visiting map[string]bool
+}
+
+// selectionSet returns how deep a selection set nests, outside of introspection and under it, and how many fields it
+// selects.
+func (m graphQLMeasure) selectionSet(set *ast.SelectionSet) (depth, introspectionDepth, complexity int) {
+	if set == nil {
+		return 0, 0, 0
+	}
+
+	for _, selection := range set.Selections {
+		var d, id, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, id, c = m.selectionSet(selection.SelectionSet)
+			if strings.HasPrefix(selection.Name.Value, "__") && selection.SelectionSet != nil {
+				// everything under __schema or __type is introspection.
+				d, id = 0, max(d, id)+1
+			} else {
+				d++
+			}
+			c = 1 + c*m.multiplier(selection)
+		case *ast.InlineFragment:
+			d, id, c = m.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visiting[name] {
+				continue
+			}
+			m.visiting[name] = true
+			d, id, c = m.selectionSet(fragment.SelectionSet)
+			delete(m.visiting, name)
+		}
+		depth, introspectionDepth, complexity = max(depth, d), max(introspectionDepth, id), complexity+c
+	}
+	return depth, introspectionDepth, complexity
+}
+
+// multiplier is how many times what's selected under a field is counted: the size of the page it asks for, if it's
+// paginated.
+func (m graphQLMeasure) multiplier(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			var n int
+			if _, err := fmt.Sscan(v.Value, &n); err == nil {
+				return max(1, min(n, maxListLimit))
+			}
+		case *ast.Variable:
+			if n, ok := m.variables[v.Name.Value].(float64); ok {
+				return max(1, min(int(n), maxListLimit))
+			}
+		}
+		return graphQLDefaultPageSize
+	}
+
+	if slices.Contains(graphQLPaginatedFields, field.Name.Value) {
+		return graphQLDefaultPageSize
+	}
+	return 1
+}
@@ -0,0 +1,443 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/studentpb"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcMethodRoutes maps every StudentService method to the HTTP route it mirrors, "METHOD pattern" as in the access
+// policy, which it's authorized against.
+var grpcMethodRoutes = map[string]string{
+	studentpb.StudentService_Create_FullMethodName: "POST /api/v1/students/add",
+	studentpb.StudentService_Get_FullMethodName:    "GET /api/v1/students/{id}",
+	studentpb.StudentService_Update_FullMethodName: "PATCH /api/v1/students/{id}",
+	studentpb.StudentService_Delete_FullMethodName: "DELETE /api/v1/students/{id}",
+	studentpb.StudentService_List_FullMethodName:   "GET /api/v1/students",
+	studentpb.StudentService_Watch_FullMethodName:  "GET /api/v1/students",
+}
+
+// grpcPublicServices are served to anyone, like the public HTTP routes.
+var grpcPublicServices = []string{
+	healthpb.Health_ServiceDesc.ServiceName,
+	"grpc.reflection.v1.ServerReflection",
+	"grpc.reflection.v1alpha.ServerReflection",
+}
+
+// the gRPC metrics, published with expvar: how many calls were handled, and how long they took in all, by method and
+// status code.
+var (
+	grpcHandled         = expvar.NewMap("grpc_server_handled_total")
+	grpcHandlingSeconds = expvar.NewMap("grpc_server_handling_seconds_total")
+)
+
+// NewGRPCServer serves StudentService on top of server, along with the health and reflection services. Calls go
+// through the same steps HTTP requests do: they're tagged with a request id, logged, counted, authenticated and then
+// checked against the access policy.
+func NewGRPCServer(server *Server, opts ...grpc.ServerOption) *grpc.Server {
+	requestIdUnary, requestIdStream := grpcInterceptors(grpcRequestId)
+	logUnary, logStream := grpcObservers(server.logGRPC)
+	metricsUnary, metricsStream := grpcObservers(countGRPC)
+	authUnary, authStream := grpcInterceptors(server.authorizeGRPC)
+
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(requestIdUnary, logUnary, metricsUnary, authUnary),
+		grpc.ChainStreamInterceptor(requestIdStream, logStream, metricsStream, authStream),
+	)
+	gs := grpc.NewServer(opts...)
+	studentpb.RegisterStudentServiceServer(gs, &grpcStudentService{server: server})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(studentpb.StudentService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(gs, healthServer)
+	reflection.Register(gs)
+	return gs
+}
+
+// MultiplexGRPC serves gRPC calls with grpcServer and every other request with next, on the same port. HTTP/2 is
+// accepted without TLS (h2c), as gRPC clients speak it on plaintext connections.
+func MultiplexGRPC(grpcServer *grpc.Server, next http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
+
+// GRPCAddr is the address to serve gRPC on, separately from the HTTP API, as set in GRPC_ADDR. Without one, gRPC is
+// multiplexed with the HTTP API.
+func GRPCAddr() (string, bool) {
+	addr := os.Getenv(grpcAddr)
+	return addr, addr != ""
+}
+
+// grpcInterceptors turns a step that runs before every call, and can fail it, into interceptors for both kinds of
+// calls. The context it returns is the one the call is handled with.
+func grpcInterceptors(before func(ctx context.Context, method string) (context.Context, error)) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := before(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := before(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &grpcServerStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+// grpcObservers turns a step that runs after every call into interceptors for both kinds of calls.
+func grpcObservers(observe func(ctx context.Context, method string, elapsed time.Duration, err error)) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(ctx, info.FullMethod, time.Since(start), err)
+		return resp, err
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observe(ss.Context(), info.FullMethod, time.Since(start), err)
+		return err
+	}
+	return unary, stream
+}
+
+// grpcServerStream is a stream with the context an interceptor handed down.
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *grpcServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+// grpcRequestId is RequestID for gRPC: it tags the call with the id sent in the x-request-id metadata, or a new one,
+// and sends it back in the response headers.
+func grpcRequestId(ctx context.Context, method string) (context.Context, error) {
+	id := firstMetadata(ctx, requestIdHeader)
+	if id == "" || len(id) > maxRequestIdLength {
+		id = newRequestId()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(requestIdHeader), id))
+	return context.WithValue(ctx, RequestIdKey, id), nil
+}
+
+func (s *Server) logGRPC(ctx context.Context, method string, elapsed time.Duration, err error) {
+	s.Logger.Info("grpc call",
+		"method", method,
+		"code", status.Code(err).String(),
+		"duration", elapsed,
+		"requestId", RequestIdFromContext(ctx),
+	)
+}
+
+func countGRPC(ctx context.Context, method string, elapsed time.Duration, err error) {
+	key := method + " " + status.Code(err).String()
+	grpcHandled.Add(key, 1)
+	grpcHandlingSeconds.AddFloat(key, elapsed.Seconds())
+}
+
+// authorizeGRPC is Authenticate and Authorize for gRPC. Credentials are read from the authorization and x-api-key
+// metadata, and calls are authorized with the permission of the HTTP route they mirror.
+func (s *Server) authorizeGRPC(ctx context.Context, method string) (context.Context, error) {
+	service, _, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if slices.Contains(grpcPublicServices, service) {
+		return ctx, nil
+	}
+
+	principal, key, err := s.authenticate(ctx, firstMetadata(ctx, "Authorization"), firstMetadata(ctx, apiKeyHeader))
+	if err != nil {
+		if errors.Is(err, errUnauthenticated) {
+			return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+		}
+
+		s.Logger.Error("error authenticating call", "method", method, "error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	ctx = context.WithValue(ctx, PrincipalKey, principal)
+	if key != nil {
+		s.touchAPIKey(ctx, key)
+		ctx = context.WithValue(ctx, APIKeyKey, key)
+	}
+
+	route, ok := grpcMethodRoutes[method]
+	if !ok {
+		return nil, s.denyGRPC(ctx, principal, "", method, "method is not covered by the access policy")
+	}
+	httpMethod, pattern, _ := strings.Cut(route, " ")
+	permission, ok := s.policy().RequiredPermission(httpMethod, pattern)
+	if !ok {
+		return nil, s.denyGRPC(ctx, principal, "", method, "route is not covered by the access policy")
+	}
+	if err := s.requireGRPC(ctx, method, permission); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// requireGRPC checks a permission from inside a call, like require does for HTTP requests.
+func (s *Server) requireGRPC(ctx context.Context, method, permission string) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	if !s.policy().Grants(principal, permission) {
+		return s.denyGRPC(ctx, principal, permission, method, fmt.Sprintf("missing permission %q", permission))
+	}
+	return nil
+}
+
+// denyGRPC records the denial like deny does, and returns a PERMISSION_DENIED status naming the missing permission.
+func (s *Server) denyGRPC(ctx context.Context, principal *Principal, permission, method, reason string) error {
+	s.recordDenial(ctx, principal, permission, method, reason)
+
+	st := status.New(codes.PermissionDenied, reason)
+	if permission != "" {
+		st, _ = st.WithDetails(&errdetails.ErrorInfo{
+			Reason:   "MISSING_PERMISSION",
+			Domain:   studentpb.StudentService_ServiceDesc.ServiceName,
+			Metadata: map[string]string{"permission": permission},
+		})
+	}
+	return st.Err()
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// grpcStudentService implements StudentService with the server's store.
+type grpcStudentService struct {
+	studentpb.UnimplementedStudentServiceServer
+	server *Server
+}
+
+func (g *grpcStudentService) Create(ctx context.Context, req *studentpb.CreateStudentRequest) (*emptypb.Empty, error) {
+	s := g.server
+	student := Student{Name: req.GetName(), Age: int(req.GetAge())}
+	if details := validateStudent(student); details != nil {
+		return nil, invalidStudent(details)
+	}
+
+	if err := s.Store.CreateStudent(ctx, student); err != nil {
+		s.Logger.Error("error creating student", "error", err)
+		return nil, status.Error(codes.Internal, "error creating student")
+	}
+
+	s.notifyEvents()
+	return &emptypb.Empty{}, nil
+}
+
+func (g *grpcStudentService) Get(ctx context.Context, req *studentpb.GetStudentRequest) (*studentpb.Student, error) {
+	s := g.server
+	student, err := s.Store.GetStudent(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, g.studentError(err, "error getting student", req.GetId())
+	}
+	return toProtoStudent(*student), nil
+}
+
+func (g *grpcStudentService) Update(ctx context.Context, req *studentpb.UpdateStudentRequest) (*emptypb.Empty, error) {
+	s := g.server
+	student := Student{Name: req.GetName(), Age: int(req.GetAge())}
+	if details := validateStudent(student); details != nil {
+		return nil, invalidStudent(details)
+	}
+
+	if err := s.Store.UpdateStudent(ctx, int(req.GetId()), student); err != nil {
+		return nil, g.studentError(err, "error updating student", req.GetId())
+	}
+
+	s.notifyEvents()
+	return &emptypb.Empty{}, nil
+}
+
+func (g *grpcStudentService) Delete(ctx context.Context, req *studentpb.DeleteStudentRequest) (*emptypb.Empty, error) {
+	s := g.server
+	if err := s.Store.DeleteStudent(ctx, int(req.GetId())); err != nil {
+		return nil, g.studentError(err, "error deleting student", req.GetId())
+	}
+
+	s.notifyEvents()
+	return &emptypb.Empty{}, nil
+}
+
+func (g *grpcStudentService) List(ctx context.Context, req *studentpb.ListStudentsRequest) (*studentpb.ListStudentsResponse, error) {
+	s := g.server
+	if req.GetAfterId() < 0 || req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "after_id and limit can't be negative")
+	}
+
+	opts := ListOptions{AfterId: int(req.GetAfterId()), Limit: min(int(req.GetLimit()), maxListLimit)}
+	if req.GetOnlyDeleted() {
+		// the trash is for admins only, which the method alone can't tell.
+		if err := s.requireGRPC(ctx, studentpb.StudentService_List_FullMethodName, ScopeStudentsDelete); err != nil {
+			return nil, err
+		}
+		opts.OnlyDeleted = true
+	}
+
+	var students []Student
+	var resourceVersion int64
+	var err error
+	if s.Watch != nil {
+		students, resourceVersion, err = s.Watch.ListStudentsVersioned(ctx, opts)
+	} else {
+		students, err = s.Store.ListStudents(ctx, opts)
+	}
+	if err != nil {
+		s.Logger.Error("error listing students", "error", err)
+		return nil, status.Error(codes.Internal, "failed to list students")
+	}
+
+	resp := &studentpb.ListStudentsResponse{ResourceVersion: resourceVersion}
+	for _, student := range students {
+		resp.Students = append(resp.Students, toProtoStudent(student))
+	}
+	return resp, nil
+}
+
+// Watch streams changes to the live students like watchStudents does, as WatchEvent messages.
+func (g *grpcStudentService) Watch(req *studentpb.WatchStudentsRequest, stream grpc.ServerStreamingServer[studentpb.WatchEvent]) error {
+	s := g.server
+	if s.Events == nil || s.Watch == nil {
+		return status.Error(codes.Unimplemented, "watching students is not supported")
+	}
+	if req.ResourceVersion != nil && req.GetResourceVersion() < 0 {
+		return status.Error(codes.InvalidArgument, "resource_version can't be negative")
+	}
+
+	ctx := stream.Context()
+	initial, resourceVersion, err := s.beginWatch(ctx, req.ResourceVersion)
+	if err != nil {
+		var tooOld errResourceVersionTooOld
+		if errors.As(err, &tooOld) {
+			st, _ := status.New(codes.FailedPrecondition, "the history since this resource version has been compacted, list again").
+				WithDetails(&errdetails.ErrorInfo{
+					Reason: "RESOURCE_VERSION_TOO_OLD",
+					Domain: studentpb.StudentService_ServiceDesc.ServiceName,
+					Metadata: map[string]string{
+						"resource_version":        fmt.Sprint(tooOld.resourceVersion),
+						"oldest_resource_version": fmt.Sprint(tooOld.oldest),
+					},
+				})
+			return st.Err()
+		}
+
+		s.Logger.Error("error starting watch", "error", err)
+		return status.Error(codes.Internal, "failed to watch students")
+	}
+
+	for _, student := range initial {
+		if err := stream.Send(watchEventMessage(WatchAdded, resourceVersion, &student)); err != nil {
+			return err
+		}
+	}
+
+	s.streamEvents(ctx, &eventStream{
+		lastId: resourceVersion,
+		deliver: func(e Event) error {
+			typ := watchEventType(e)
+			if typ == "" {
+				return nil
+			}
+			return stream.Send(watchEventMessage(typ, e.Id, e.Student))
+		},
+		heartbeat: func(lastId int64) error {
+			return stream.Send(watchEventMessage(WatchBookmark, lastId, nil))
+		},
+	})
+
+	// the stream only ends when the client goes away, or the change history can't be read.
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Unavailable, "watch interrupted, watch again from the last resource version")
+}
+
+// studentError maps an error from the store to a status, logging the unexpected ones.
+func (g *grpcStudentService) studentError(err error, msg string, studentId int64) error {
+	if errors.Is(err, sql.ErrNoRows) || err.Error() == errStudentNotFound {
+		return status.Error(codes.NotFound, errStudentNotFound)
+	}
+	if err.Error() == errStudentEnrolled {
+		return status.Error(codes.FailedPrecondition, "the student is enrolled, or waitlisted, in courses")
+	}
+
+	g.server.Logger.Error(msg, "studentId", studentId, "error", err)
+	return status.Error(codes.Internal, msg)
+}
+
+// invalidStudent is an INVALID_ARGUMENT status, with a violation for every field validateStudent found fault with.
+func invalidStudent(details map[string]any) error {
+	fields := make([]string, 0, len(details))
+	for field := range details {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: fmt.Sprint(details[field]),
+		})
+	}
+
+	st, _ := status.New(codes.InvalidArgument, "invalid student").WithDetails(badRequest)
+	return st.Err()
+}
+
+func toProtoStudent(s Student) *studentpb.Student {
+	ps := &studentpb.Student{Id: int64(s.Id), Name: s.Name, Age: int32(s.Age)}
+	if s.DeletedAt != nil {
+		ps.DeletedAt = timestamppb.New(*s.DeletedAt)
+	}
+	return ps
+}
+
+func watchEventMessage(typ string, resourceVersion int64, s *Student) *studentpb.WatchEvent {
+	we := &studentpb.WatchEvent{
+		Type:            studentpb.WatchEvent_Type(studentpb.WatchEvent_Type_value[typ]),
+		ResourceVersion: resourceVersion,
+	}
+	if s != nil {
+		we.Object = toProtoStudent(*s)
+	}
+	return we
+}
@@ -0,0 +1,214 @@
+package student
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+
+	defaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKeyLen  = 255
+	// requests have to be buffered to be fingerprinted, so idempotent ones are capped in size.
+	maxIdempotentBodySize = 1 << 20
+)
+
+// replayedHeaders are the response headers stored with an idempotent response and sent again on replay.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// IdempotencyRecord is what's stored for an Idempotency-Key: a fingerprint of the request it was first used with and,
+// once that request completes, its response. A zero Status means the request is still in flight.
+type IdempotencyRecord struct {
+	Principal   string
+	Key         string
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// NewIdempotencyTTL reads how long idempotency keys are honoured for from IDEMPOTENCY_TTL.
+func NewIdempotencyTTL() time.Duration {
+	v := os.Getenv(idempotencyTTL)
+	if v == "" {
+		return defaultIdempotencyTTL
+	}
+
+	ttl, err := time.ParseDuration(v)
+	if err != nil || ttl <= 0 {
+		log.Fatalf("invalid %q: %q", idempotencyTTL, v)
+	}
+	return ttl
+}
+
+// Idempotent makes mutations safe to retry. The first response to a request carrying an Idempotency-Key header is
+// stored, and replayed unchanged when the same request is sent again with the same key within the TTL. Reusing a key
+// for a different request is rejected with a 422. Keys are scoped to the caller, so this has to sit behind
+// Authenticate.
+func (s *Server) Idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || s.Idempotency == nil || isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLen {
+			RespondWithError(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+		if err != nil {
+			RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxIdempotentBodySize {
+			RespondWithError(w, "Request body is too large to be sent with an Idempotency-Key", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ttl := s.IdempotencyTTL
+		if ttl == 0 {
+			ttl = defaultIdempotencyTTL
+		}
+
+		reservation := IdempotencyRecord{
+			Principal:   actorFromContext(r.Context()),
+			Key:         key,
+			Fingerprint: requestFingerprint(r, body),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+
+		existing, err := s.Idempotency.ReserveIdempotencyKey(r.Context(), reservation)
+		if err != nil {
+			s.Logger.Error("error reserving idempotency key", "error", err)
+			RespondWithError(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if existing != nil {
+			s.replay(w, reservation, existing)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// server errors aren't worth replaying — let the client retry them for real.
+		if rec.status >= http.StatusInternalServerError {
+			if err := s.Idempotency.ReleaseIdempotencyKey(context.WithoutCancel(r.Context()), reservation.Principal, key); err != nil {
+				s.Logger.Error("error releasing idempotency key", "error", err)
+			}
+			return
+		}
+
+		reservation.Status = rec.status
+		reservation.Header = http.Header{}
+		for _, h := range replayedHeaders {
+			if v := rec.Header().Get(h); v != "" {
+				reservation.Header.Set(h, v)
+			}
+		}
+		reservation.Body = rec.body.Bytes()
+
+		if err := s.Idempotency.CompleteIdempotencyKey(context.WithoutCancel(r.Context()), reservation); err != nil {
+			s.Logger.Error("error storing idempotent response", "error", err)
+		}
+	})
+}
+
+func (s *Server) replay(w http.ResponseWriter, reservation IdempotencyRecord, existing *IdempotencyRecord) {
+	if existing.Fingerprint != reservation.Fingerprint {
+		RespondWithJSONError(w, http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   "idempotency_key_reused",
+			Message: "Idempotency-Key was already used for a different request",
+		})
+		return
+	}
+
+	if existing.Status == 0 {
+		RespondWithJSONError(w, http.StatusConflict, ErrorResponse{
+			Error:   "idempotency_key_in_use",
+			Message: "a request with this Idempotency-Key is still being processed",
+		})
+		return
+	}
+
+	for h, values := range existing.Header {
+		for _, v := range values {
+			w.Header().Add(h, v)
+		}
+	}
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	w.WriteHeader(existing.Status)
+	_, _ = w.Write(existing.Body)
+}
+
+// requestFingerprint identifies a request by what it does: its method, target and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// ExpireIdempotencyKeys deletes expired idempotency keys every interval, until ctx is done.
+func ExpireIdempotencyKeys(ctx context.Context, store IdempotencyStore, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := store.DeleteExpiredIdempotencyKeys(ctx, time.Now()); err != nil {
+			logger.Error("error deleting expired idempotency keys", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// responseRecorder passes a response through to the client while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,346 @@
+package student
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	importBatchSize = 500
+	// maxImportLine is the longest NDJSON line accepted, no student needs anywhere near as much.
+	maxImportLine = 64 << 10
+	maxNameLength = 255
+	maxAge        = 150
+)
+
+// import row statuses. Rows are "valid" when they passed validation but weren't written, in a dry run or because an
+// all-or-nothing import was rolled back. The row the input stopped being readable at is "failed".
+const (
+	ImportRowCreated = "created"
+	ImportRowValid   = "valid"
+	ImportRowFailed  = "failed"
+)
+
+// ImportReport is the outcome of an import, row by row. Error is set when the input stopped being readable part way
+// through, in which case the rows after the one it stopped at were never read.
+type ImportReport struct {
+	DryRun       bool        `json:"dry_run"`
+	AllOrNothing bool        `json:"all_or_nothing"`
+	Committed    bool        `json:"committed"`
+	Total        int         `json:"total"`
+	Created      int         `json:"created"`
+	Failed       int         `json:"failed"`
+	Rows         []ImportRow `json:"rows"`
+	Error        string      `json:"error,omitempty"`
+}
+
+type ImportRow struct {
+	// Row is the 1-based position of the row in the input, not counting a CSV header.
+	Row    int            `json:"row"`
+	Status string         `json:"status"`
+	Id     int            `json:"id,omitempty"`
+	Errors map[string]any `json:"errors,omitempty"`
+}
+
+// validateStudent checks a student that's about to be created.
+func validateStudent(s Student) map[string]any {
+	details := make(map[string]any)
+	if strings.TrimSpace(s.Name) == "" {
+		details["name"] = "is required"
+	} else if len(s.Name) > maxNameLength {
+		details["name"] = fmt.Sprintf("must be at most %d characters", maxNameLength)
+	}
+	if s.Age < 1 || s.Age > maxAge {
+		details["age"] = fmt.Sprintf("must be between 1 and %d", maxAge)
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// studentReader reads students off an import, one row at a time. Errors in a single row are returned as rowErrors,
+// anything else means the input can't be read any further.
+type studentReader interface {
+	next() (Student, error)
+}
+
+type rowErrors map[string]any
+
+func (e rowErrors) Error() string {
+	return fmt.Sprintf("invalid row: %v", map[string]any(e))
+}
+
+type csvStudentReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVStudentReader(body io.Reader) (*csvStudentReader, error) {
+	r := csv.NewReader(body)
+	r.ReuseRecord = true
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case "name", "age":
+			columns[column] = i
+		default:
+			return nil, fmt.Errorf("unknown column %q, expected name and age", column)
+		}
+	}
+	if len(columns) != 2 {
+		return nil, errors.New("the header should have a name and an age column")
+	}
+	return &csvStudentReader{r: r, columns: columns}, nil
+}
+
+func (c *csvStudentReader) next() (Student, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			return Student{}, rowErrors{"row": fmt.Sprintf("expected %d fields", len(c.columns))}
+		}
+		return Student{}, err
+	}
+
+	s := Student{Name: record[c.columns["name"]]}
+	age := strings.TrimSpace(record[c.columns["age"]])
+	if s.Age, err = strconv.Atoi(age); err != nil {
+		return Student{}, rowErrors{"age": fmt.Sprintf("%q is not a number", age)}
+	}
+	return s, nil
+}
+
+type ndjsonStudentReader struct {
+	scanner *bufio.Scanner
+}
+
+func newNDJSONStudentReader(body io.Reader) *ndjsonStudentReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxImportLine)
+	return &ndjsonStudentReader{scanner: scanner}
+}
+
+func (n *ndjsonStudentReader) next() (Student, error) {
+	if !n.scanner.Scan() {
+		if err := n.scanner.Err(); err != nil {
+			return Student{}, err
+		}
+		return Student{}, io.EOF
+	}
+
+	var s Student
+	dec := json.NewDecoder(strings.NewReader(n.scanner.Text()))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return Student{}, rowErrors{"row": fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	if s.Id != 0 || s.DeletedAt != nil {
+		return Student{}, rowErrors{"row": "id and deleted_at can't be imported"}
+	}
+	return s, nil
+}
+
+// ImportStudents creates students from a CSV or NDJSON upload of any size, and reports on every row. Rows are
+// validated one by one and written in batches. By default every valid row is created; with ?all_or_nothing=true
+// nothing is unless every row is valid, and with ?dry_run=true the rows are only validated.
+func (s *Server) ImportStudents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	if s.Import == nil {
+		RespondWithError(w, "Importing students is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	report := ImportReport{Rows: []ImportRow{}}
+	for name, dest := range map[string]*bool{"dry_run": &report.DryRun, "all_or_nothing": &report.AllOrNothing} {
+		if v := r.URL.Query().Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				RespondWithError(w, errInvalidParam(name).Error(), http.StatusBadRequest)
+				return
+			}
+			*dest = b
+		}
+	}
+
+	var reader studentReader
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		csvReader, err := newCSVStudentReader(r.Body)
+		if err != nil {
+			RespondWithError(w, "Invalid CSV: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		reader = csvReader
+	case "application/x-ndjson":
+		reader = newNDJSONStudentReader(r.Body)
+	default:
+		RespondWithJSONError(w, http.StatusUnsupportedMediaType, ErrorResponse{
+			Error:   "unsupported_media_type",
+			Message: "imports should be text/csv or application/x-ndjson",
+		})
+		return
+	}
+
+	if err := s.importStudents(r.Context(), reader, &report); err != nil {
+		s.Logger.Error("error importing students", "error", err)
+		RespondWithError(w, "Error importing students", http.StatusInternalServerError)
+		return
+	}
+
+	if report.Created > 0 {
+		s.notifyEvents()
+	}
+
+	status := http.StatusOK
+	if report.Error != "" {
+		// the input is malformed. When rows before it were committed, the report is the only way the client can tell
+		// which, and so where to pick up from, otherwise the import as a whole is rejected.
+		if !report.Committed {
+			RespondWithError(w, report.Error, http.StatusBadRequest)
+			return
+		}
+		status = http.StatusMultiStatus
+	}
+	respondWithJSON(w, s.Logger, status, report)
+}
+
+// importStudents reads, validates and writes every row of reader into report. The input becoming unreadable part way
+// through isn't an error: reading stops there, the rows read until then are written as they would have been, and the
+// report says where it stopped.
+func (s *Server) importStudents(ctx context.Context, reader studentReader, report *ImportReport) error {
+	var imp StudentImport
+	// an all-or-nothing import writes every batch in the same transaction, the others commit each one on its own.
+	if report.AllOrNothing && !report.DryRun {
+		var err error
+		if imp, err = s.Import.BeginImport(ctx); err != nil {
+			return err
+		}
+		defer imp.Rollback(ctx)
+	}
+
+	var batch []Student
+	var batchRows []int
+	flush := func() error {
+		if len(batch) == 0 || report.DryRun || (report.AllOrNothing && report.Failed > 0) {
+			batch, batchRows = batch[:0], batchRows[:0]
+			return nil
+		}
+
+		created, err := s.insertImportBatch(ctx, imp, batch)
+		if err != nil {
+			return err
+		}
+		for i, student := range created {
+			row := &report.Rows[batchRows[i]]
+			row.Id = student.Id
+			row.Status = ImportRowCreated
+		}
+		report.Created += len(created)
+		batch, batchRows = batch[:0], batchRows[:0]
+		return nil
+	}
+
+	for {
+		student, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		report.Total++
+		row := ImportRow{Row: report.Total, Status: ImportRowValid}
+		var invalid rowErrors
+		switch {
+		case errors.As(err, &invalid):
+			row.Errors = invalid
+		case err != nil:
+			report.Error = fmt.Sprintf("Invalid input at row %d: %v", report.Total, err)
+			row.Errors = rowErrors{"row": err.Error()}
+		default:
+			row.Errors = validateStudent(student)
+		}
+
+		if row.Errors != nil {
+			row.Status = ImportRowFailed
+			report.Failed++
+			report.Rows = append(report.Rows, row)
+			if report.Error != "" {
+				break
+			}
+			continue
+		}
+
+		report.Rows = append(report.Rows, row)
+		batch = append(batch, student)
+		batchRows = append(batchRows, len(report.Rows)-1)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if imp != nil {
+		if report.Failed > 0 {
+			// nothing was written after all.
+			for i := range report.Rows {
+				if report.Rows[i].Status == ImportRowCreated {
+					report.Rows[i].Status, report.Rows[i].Id = ImportRowValid, 0
+				}
+			}
+			report.Created = 0
+			return nil
+		}
+		if err := imp.Commit(ctx); err != nil {
+			return err
+		}
+	}
+	report.Committed = !report.DryRun && report.Created > 0
+	return nil
+}
+
+// insertImportBatch writes a batch in imp, or in a transaction of its own when imp is nil.
+func (s *Server) insertImportBatch(ctx context.Context, imp StudentImport, batch []Student) ([]Student, error) {
+	if imp != nil {
+		return imp.Insert(ctx, batch)
+	}
+
+	imp, err := s.Import.BeginImport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer imp.Rollback(ctx)
+
+	created, err := imp.Insert(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	return created, imp.Commit(ctx)
+}
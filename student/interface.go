@@ -1,9 +1,222 @@
 package student
 
+import (
+	"context"
+	"iter"
+	"time"
+)
+
 type Store interface {
-	CreateStudent(s Student) error
-	GetStudent(studentId int) (*Student, error)
-	UpdateStudent(id int, s Student) error
-	DeleteStudent(id int) error
-	ListStudents() ([]Student, error)
+	CreateStudent(ctx context.Context, s Student) error
+	GetStudent(ctx context.Context, studentId int) (*Student, error)
+	UpdateStudent(ctx context.Context, id int, s Student) error
+	DeleteStudent(ctx context.Context, id int) error
+	ListStudents(ctx context.Context, opts ListOptions) ([]Student, error)
+}
+
+// CourseStore keeps the courses students can take. Courses are kept apart from students, so they can be stored on their
+// own.
+type CourseStore interface {
+	// CreateCourse creates a course and returns its id. Codes are unique, creating a course with a code that's taken
+	// fails with errCourseCodeTaken.
+	CreateCourse(ctx context.Context, c Course) (int, error)
+	GetCourse(ctx context.Context, id int) (*Course, error)
+	// UpdateCourse replaces everything but the id of a course.
+	UpdateCourse(ctx context.Context, id int, c Course) error
+	DeleteCourse(ctx context.Context, id int) error
+	// ListCourses lists courses in id order. Only opts.AfterId and opts.Limit are used.
+	ListCourses(ctx context.Context, opts ListOptions) ([]Course, error)
+}
+
+// EnrollmentStore enrolls students in courses. A course seats students up to its capacity, first come first served,
+// and waitlists the others. Seats that free up go to the waitlist in the same order.
+type EnrollmentStore interface {
+	// Enroll gives a live student a seat in a course, or a place on its waitlist if it's full. It fails with
+	// errAlreadyEnrolled if the student is enrolled, or waitlisted, already.
+	Enroll(ctx context.Context, courseId, studentId int) (*Enrollment, error)
+	GetEnrollment(ctx context.Context, courseId, studentId int) (*Enrollment, error)
+	// Withdraw takes a student out of a course, or off its waitlist.
+	Withdraw(ctx context.Context, courseId, studentId int) error
+	// ListEnrollments lists the students of a course: those enrolled, then the waitlist, each in the order they came.
+	ListEnrollments(ctx context.Context, courseId int) ([]Enrollment, error)
+	// ListStudentCourses lists the courses a live student is enrolled, or waitlisted, in, in course id order.
+	ListStudentCourses(ctx context.Context, studentId int) ([]StudentCourse, error)
+}
+
+// GradeStore keeps the assessments of courses, and the scores students get in them.
+type GradeStore interface {
+	// CreateAssessment creates an assessment of a.CourseId and returns its id.
+	CreateAssessment(ctx context.Context, a Assessment) (int, error)
+	GetAssessment(ctx context.Context, courseId, id int) (*Assessment, error)
+	// UpdateAssessment replaces the title, weight and maximum score of an assessment.
+	UpdateAssessment(ctx context.Context, courseId, id int, a Assessment) error
+	// DeleteAssessment deletes an assessment along with its scores.
+	DeleteAssessment(ctx context.Context, courseId, id int) error
+	// ListAssessments lists the assessments of a course in id order.
+	ListAssessments(ctx context.Context, courseId int) ([]Assessment, error)
+	// RecordScores records scores in an assessment, replacing those the students had. Either all of them are recorded
+	// or none is: it fails with an errNotEnrolled if one of the students isn't enrolled in the course.
+	RecordScores(ctx context.Context, courseId, assessmentId int, scores []Score) error
+	// ListScores lists the scores of an assessment in student id order.
+	ListScores(ctx context.Context, courseId, assessmentId int) ([]Score, error)
+	// StudentGrades returns the courses a live student is enrolled in, in id order, each with all its assessments and
+	// the student's scores in them.
+	StudentGrades(ctx context.Context, studentId int) ([]CourseGrades, error)
+}
+
+// AttendanceStore keeps the sessions of courses, and who attended them.
+type AttendanceStore interface {
+	// CreateSession schedules a session of s.CourseId and returns its id.
+	CreateSession(ctx context.Context, s ClassSession) (int, error)
+	GetSession(ctx context.Context, courseId, id int) (*ClassSession, error)
+	// UpdateSession replaces the topic and start of a session.
+	UpdateSession(ctx context.Context, courseId, id int, s ClassSession) error
+	// DeleteSession deletes a session along with its attendance.
+	DeleteSession(ctx context.Context, courseId, id int) error
+	// ListSessions lists the sessions of a course by when they start.
+	ListSessions(ctx context.Context, courseId int) ([]ClassSession, error)
+	// MarkAttendance marks the attendance of a session, replacing the marks the students had, and every other student
+	// enrolled in the course with others, unless it's empty. Either all of them are marked or none is: it fails with
+	// an errNotEnrolled if one of the students isn't enrolled. Students it takes below the AttendanceThreshold are
+	// alerted on with an EventAttendanceLow, in the same transaction.
+	MarkAttendance(ctx context.Context, courseId, sessionId int, marks []AttendanceMark, others string) error
+	// ListAttendance lists the marks of a session in student id order.
+	ListAttendance(ctx context.Context, courseId, sessionId int) ([]AttendanceMark, error)
+	// CourseAttendance sums up the attendance of the students enrolled in a course, in student id order.
+	CourseAttendance(ctx context.Context, courseId int) ([]AttendanceSummary, error)
+	// StudentAttendance sums up the attendance of a live student in the courses it's enrolled in, in course id order.
+	StudentAttendance(ctx context.Context, studentId int) ([]AttendanceSummary, error)
+}
+
+// BatchStore looks students up in bulk, so resolving many of them doesn't take a query each.
+type BatchStore interface {
+	// GetStudents returns the live students with the given ids, in id order. Ids that don't match one are left out.
+	GetStudents(ctx context.Context, ids []int) ([]Student, error)
+}
+
+// SearchStore finds students by what they're called, rather than by id.
+type SearchStore interface {
+	// SearchStudents returns the live students matching query, most relevant first, and those equally relevant in id
+	// order. Only opts.AfterId and opts.Limit are used: AfterId is the last student of the previous page, and the page
+	// is empty if that student doesn't match anymore.
+	SearchStudents(ctx context.Context, query string, opts ListOptions) ([]SearchResult, error)
+}
+
+// StatsStore sums students up in the database, rather than reading them all out to do it.
+type StatsStore interface {
+	// StudentStats sums up the live students, with their ages split into buckets at ageBuckets, which are ascending.
+	StudentStats(ctx context.Context, ageBuckets []int) (*StudentStats, error)
+}
+
+// TrashStore manages soft-deleted students. DeleteStudent only moves a student to the trash.
+type TrashStore interface {
+	RestoreStudent(ctx context.Context, id int) error
+	// PurgeStudents permanently deletes students that were soft deleted before deletedBefore, and returns how many
+	// there were.
+	PurgeStudents(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+// RevisionStore keeps the version history of students. Stores record a revision with every mutation, in the same
+// transaction.
+type RevisionStore interface {
+	ListRevisions(ctx context.Context, studentId int) ([]Revision, error)
+	// ListRevisionsOf returns the revisions of many students in a single query, by student id. Students without any
+	// are left out.
+	ListRevisionsOf(ctx context.Context, studentIds []int) (map[int][]Revision, error)
+	// GetStudentAsOf returns the student as it was at asOf, from the latest revision recorded by then.
+	GetStudentAsOf(ctx context.Context, studentId int, asOf time.Time) (*Student, error)
+	// RevertStudent restores the name and age of the student to those at revision and returns it. A student deleted
+	// at revision is moved to the trash, and a live one out of it, only if trash is set; otherwise the revert fails
+	// without changing anything.
+	RevertStudent(ctx context.Context, studentId, revision int, trash bool) (*Student, error)
+}
+
+// OutboxStore reads the transactional outbox. Stores write an event to it with every mutation, in the same
+// transaction.
+type OutboxStore interface {
+	// PendingEvents returns up to limit events that haven't been dispatched yet, oldest first, leaving out those of the
+	// students in skip.
+	PendingEvents(ctx context.Context, limit int, skip []int) ([]Event, error)
+	MarkEventsDispatched(ctx context.Context, ids []int64) error
+	// EventsAfter returns up to limit events with an id greater than afterId, dispatched or not, oldest first.
+	EventsAfter(ctx context.Context, afterId int64, limit int) ([]Event, error)
+	// LatestEventId is the id of the newest event, or 0 if there are none.
+	LatestEventId(ctx context.Context) (int64, error)
+	DeleteDispatchedEvents(ctx context.Context, before time.Time) (int, error)
+}
+
+// StudentStreamer reads students one at a time, rather than all at once into a slice.
+type StudentStreamer interface {
+	// StreamStudents yields the students ListStudents would list, in the same order, holding a cursor open for as
+	// long as they're iterated over. It yields at most one error, after which it stops.
+	StreamStudents(ctx context.Context, opts ListOptions) iter.Seq2[Student, error]
+}
+
+// ImportStore creates students in bulk.
+type ImportStore interface {
+	BeginImport(ctx context.Context) (StudentImport, error)
+}
+
+// StudentImport is the transaction an import writes students in. Rolling back after a commit does nothing.
+type StudentImport interface {
+	// Insert creates the students, and returns them with their ids, in the same order.
+	Insert(ctx context.Context, students []Student) ([]Student, error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// WatchStore is what watching students takes on top of an OutboxStore.
+type WatchStore interface {
+	// ListStudentsVersioned lists students along with the id of the last change event the list reflects.
+	ListStudentsVersioned(ctx context.Context, opts ListOptions) ([]Student, int64, error)
+	// CompactedEventId is the id of the newest event deleted from the outbox, or 0 if none were.
+	CompactedEventId(ctx context.Context) (int64, error)
+}
+
+// WebhookStore keeps webhook subscriptions and the queue of deliveries to them.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, wh Webhook) (int, error)
+	GetWebhook(ctx context.Context, id int) (*Webhook, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	// DeleteWebhook deletes the webhook along with its deliveries.
+	DeleteWebhook(ctx context.Context, id int) error
+
+	// EnqueueWebhookDeliveries queues a delivery of the event to every webhook subscribed to it. Enqueueing the same
+	// event twice doesn't deliver it twice.
+	EnqueueWebhookDeliveries(ctx context.Context, e Event, payload []byte) error
+	// DueWebhookDeliveries returns up to limit pending deliveries that are due at now, oldest first.
+	DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error)
+	// RecordWebhookAttempt saves the outcome of an attempt at a delivery.
+	RecordWebhookAttempt(ctx context.Context, d WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, webhookId int) ([]WebhookDelivery, error)
+	ListDeadWebhookDeliveries(ctx context.Context) ([]WebhookDelivery, error)
+	// RetryWebhookDelivery puts a delivery back in the queue, with its attempts reset.
+	RetryWebhookDelivery(ctx context.Context, webhookId int, deliveryId int64) error
+}
+
+// IdempotencyStore keeps the responses to requests sent with an Idempotency-Key.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey claims the record's key for a request about to be processed. If the key is already taken
+	// (and hasn't expired), nothing is reserved and the existing record is returned instead.
+	ReserveIdempotencyKey(ctx context.Context, record IdempotencyRecord) (*IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the response to a reserved key.
+	CompleteIdempotencyKey(ctx context.Context, record IdempotencyRecord) error
+	// ReleaseIdempotencyKey gives up a reservation, so the request can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, principal, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error)
+}
+
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, k APIKey) (int, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+	TouchAPIKey(ctx context.Context, id int) error
+}
+
+// AuditStore reads the audit trail. Student mutations are audited by the Store itself, in the same transaction as the
+// change, so the only event written through here is one that has no change to go with it — like an access denial.
+type AuditStore interface {
+	RecordAuditEvent(ctx context.Context, e AuditEvent) error
+	ListAuditEvents(ctx context.Context, f AuditFilter) ([]AuditEvent, error)
 }
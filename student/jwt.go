@@ -0,0 +1,343 @@
+package student
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultJWKSRefresh = 15 * time.Minute
+	// an unknown kid usually means the issuer rotated its keys, so it triggers a refetch — but no more often than this,
+	// or tokens with made up kids would have us hammering the JWKS endpoint.
+	minJWKSRefetch   = time.Minute
+	defaultRoleClaim = "roles"
+)
+
+var validJWTMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512"}
+
+// JWKS is a JSON Web Key Set loaded from a local file or a URL. Keys are cached and reloaded once they are older than
+// the refresh interval.
+type JWKS struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKS loads the key set at source, which is either an http(s) URL or a path to a local file (optionally prefixed
+// with file://).
+func NewJWKS(source string, refresh time.Duration) (*JWKS, error) {
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+
+	j := &JWKS{
+		source:  source,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Key returns the public key for kid. A token without a kid is accepted when the set holds a single key.
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	j.mu.RLock()
+	key, ok := j.lookup(kid)
+	age := time.Since(j.fetchedAt)
+	j.mu.RUnlock()
+
+	if ok && age < j.refresh {
+		return key, nil
+	}
+
+	if age >= j.refresh || age >= minJWKSRefetch {
+		if err := j.load(); err != nil {
+			// keep serving the keys we have if the source is temporarily unavailable.
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if key, ok := j.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key with kid %q in jwks", kid)
+}
+
+func (j *JWKS) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+func (j *JWKS) load() error {
+	data, err := j.read()
+	if err != nil {
+		return fmt.Errorf("error reading jwks from %q: %w", j.source, err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("error parsing jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// issuers publish keys of types we can't verify with alongside the ones we can, which shouldn't take the rest
+		// of the set down with them.
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("skipping key in jwks", "kid", k.Kid, "kty", k.Kty, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no usable signing keys in jwks from %q", j.source)
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	j.mu.Unlock()
+	return nil
+}
+
+func (j *JWKS) read() ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(strings.TrimPrefix(j.source, "file://"))
+	}
+
+	resp, err := j.client.Get(j.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// JWTVerifier validates bearer tokens issued by our other internal services — signature against the JWKS, issuer,
+// audience and expiry — and maps their claims to a Principal.
+type JWTVerifier struct {
+	Issuer   string
+	Audience string
+	// RolesClaim names the claim roles are read from. Nested claims can be addressed with dots, e.g.
+	// realm_access.roles.
+	RolesClaim string
+	Keys       *JWKS
+}
+
+// NewJWTVerifier builds a verifier from the environment. Bearer tokens are only accepted when JWKS_URL is set, so
+// this returns nil when it isn't.
+func NewJWTVerifier() *JWTVerifier {
+	source := os.Getenv(jwksUrl)
+	if source == "" {
+		return nil
+	}
+
+	if os.Getenv(jwtIssuer) == "" || os.Getenv(jwtAudience) == "" {
+		log.Fatalf("%q and %q are required when %q is set", jwtIssuer, jwtAudience, jwksUrl)
+	}
+
+	refresh := defaultJWKSRefresh
+	if v := os.Getenv(jwksRefreshInterval); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid %q: %v", jwksRefreshInterval, err)
+		}
+		refresh = d
+	}
+
+	keys, err := NewJWKS(source, refresh)
+	if err != nil {
+		log.Fatalf("unable to load jwks: %v", err)
+	}
+
+	rolesClaim := os.Getenv(jwtRolesClaim)
+	if rolesClaim == "" {
+		rolesClaim = defaultRoleClaim
+	}
+
+	return &JWTVerifier{
+		Issuer:     os.Getenv(jwtIssuer),
+		Audience:   os.Getenv(jwtAudience),
+		RolesClaim: rolesClaim,
+		Keys:       keys,
+	}
+}
+
+// Verify validates token and returns the principal it was issued to. Any validation failure is reported as
+// errUnauthenticated.
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(validJWTMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(v.Issuer),
+		jwt.WithAudience(v.Audience),
+		jwt.WithLeeway(30 * time.Second),
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.Keys.Key(kid)
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnauthenticated, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", errUnauthenticated)
+	}
+
+	rolesClaim := v.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = defaultRoleClaim
+	}
+
+	// scopes come from the standard OAuth claims — "scope" is a space separated string, some issuers use "scp".
+	scopes := claimStrings(claims["scope"])
+	if len(scopes) == 0 {
+		scopes = claimStrings(claims["scp"])
+	}
+
+	principal := &Principal{
+		Subject: subject,
+		Method:  "jwt",
+		Roles:   claimStrings(lookupClaim(claims, rolesClaim)),
+		Scopes:  scopes,
+		Claims:  claims,
+	}
+	return principal, nil
+}
+
+func lookupClaim(claims map[string]any, path string) any {
+	var cur any = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+func claimStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []any:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// MemoryCourseStore keeps courses in memory, for tests and for servers whose Store doesn't keep courses. They're lost
+// when the process exits.
+type MemoryCourseStore struct {
+	mu      sync.Mutex
+	courses map[int]Course
+	lastId  int
+}
+
+func NewMemoryCourseStore() *MemoryCourseStore {
+	return &MemoryCourseStore{courses: make(map[int]Course)}
+}
+
+func (m *MemoryCourseStore) CreateCourse(ctx context.Context, c Course) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.codeTaken(c.Code, 0) {
+		return 0, errors.New(errCourseCodeTaken)
+	}
+	m.lastId++
+	c.Id = m.lastId
+	m.courses[c.Id] = c
+	return c.Id, nil
+}
+
+func (m *MemoryCourseStore) GetCourse(ctx context.Context, id int) (*Course, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.courses[id]
+	if !ok {
+		return nil, errors.New(errCourseNotFound)
+	}
+	return &c, nil
+}
+
+func (m *MemoryCourseStore) UpdateCourse(ctx context.Context, id int, c Course) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.courses[id]; !ok {
+		return errors.New(errCourseNotFound)
+	}
+	if m.codeTaken(c.Code, id) {
+		return errors.New(errCourseCodeTaken)
+	}
+	c.Id = id
+	m.courses[id] = c
+	return nil
+}
+
+func (m *MemoryCourseStore) DeleteCourse(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.courses[id]; !ok {
+		return errors.New(errCourseNotFound)
+	}
+	delete(m.courses, id)
+	return nil
+}
+
+func (m *MemoryCourseStore) ListCourses(ctx context.Context, opts ListOptions) ([]Course, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var courses []Course
+	for _, id := range slices.Sorted(maps.Keys(m.courses)) {
+		if id <= opts.AfterId {
+			continue
+		}
+		if opts.Limit > 0 && len(courses) == opts.Limit {
+			break
+		}
+		courses = append(courses, m.courses[id])
+	}
+	return courses, nil
+}
+
+// codeTaken reports whether a course other than the one with id has the code.
+func (m *MemoryCourseStore) codeTaken(code string, id int) bool {
+	for _, c := range m.courses {
+		if c.Code == code && c.Id != id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,302 @@
+package student
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// representation is a media type students can be rendered in and, unless decode is nil, read from.
+type representation struct {
+	mediaType string
+	// aliases are other media types clients use for the same format.
+	aliases []string
+	// listOnly representations can only render lists of students, not a single one.
+	listOnly bool
+	encode   func(w io.Writer, v any) error
+	decode   func(r io.Reader, v any) error
+}
+
+// representations are in order of preference, for when the client doesn't mind which it gets. JSON comes first, it's
+// what clients that don't send an Accept header get.
+var representations = []representation{
+	{
+		mediaType: "application/json",
+		encode:    func(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) },
+		decode:    func(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) },
+	},
+	{
+		mediaType: "application/xml",
+		aliases:   []string{"text/xml"},
+		encode:    encodeXML,
+		decode:    func(r io.Reader, v any) error { return xml.NewDecoder(r).Decode(v) },
+	},
+	{
+		mediaType: "application/yaml",
+		aliases:   []string{"application/x-yaml", "text/yaml"},
+		encode:    func(w io.Writer, v any) error { return yaml.NewEncoder(w).Encode(v) },
+		decode:    func(r io.Reader, v any) error { return yaml.NewDecoder(r).Decode(v) },
+	},
+	{
+		mediaType: "application/vnd.msgpack",
+		aliases:   []string{"application/msgpack", "application/x-msgpack"},
+		encode:    func(w io.Writer, v any) error { return msgpack.NewEncoder(w).Encode(v) },
+		decode:    func(r io.Reader, v any) error { return msgpack.NewDecoder(r).Decode(v) },
+	},
+	{
+		mediaType: "text/csv",
+		listOnly:  true,
+		encode:    encodeCSV,
+	},
+}
+
+// studentList is how a list of students is rendered in XML, which needs a root element.
+type studentList struct {
+	Students []Student `xml:"student"`
+}
+
+type studentViewList struct {
+	Students []studentView `xml:"student"`
+}
+
+func encodeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	switch v := v.(type) {
+	case Student, *Student, studentView:
+		err := enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "student"}})
+		if err != nil {
+			return err
+		}
+	case []Student:
+		err := enc.EncodeElement(studentList{v}, xml.StartElement{Name: xml.Name{Local: "students"}})
+		if err != nil {
+			return err
+		}
+	case studentViews:
+		err := enc.EncodeElement(studentViewList{v.views}, xml.StartElement{Name: xml.Name{Local: "students"}})
+		if err != nil {
+			return err
+		}
+	default:
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// encodeCSV renders a list of students with the same columns as a csv export, or only those of the fields asked for.
+// Lists with related resources embedded can't be rendered.
+func encodeCSV(w io.Writer, v any) error {
+	enc := &csvStudentEncoder{w: csv.NewWriter(w)}
+	var students []Student
+	switch v := v.(type) {
+	case []Student:
+		students = v
+	case studentViews:
+		enc.fields = v.fields
+		for _, view := range v.views {
+			students = append(students, view.student())
+		}
+	default:
+		return errors.New("only lists of students can be rendered as csv")
+	}
+
+	// an empty list still gets its header.
+	if err := enc.writeHeader(); err != nil {
+		return err
+	}
+	for _, s := range students {
+		if err := enc.encode(s); err != nil {
+			return err
+		}
+	}
+	return enc.close()
+}
+
+// mediaRange is one of the media ranges of an Accept header, e.g. "application/*;q=0.5".
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType, q})
+	}
+	return ranges
+}
+
+// quality is how much the client wants rep, going by the most specific of ranges that matches it, and the position of
+// that range in the header. The quality is -1 when no range matches.
+func (rep representation) quality(ranges []mediaRange) (q float64, position int) {
+	q, position = -1, -1
+	specificity := 0
+	for i, mr := range ranges {
+		var s int
+		switch {
+		case mr.mediaType == "*/*":
+			s = 1
+		case strings.HasSuffix(mr.mediaType, "/*"):
+			if !strings.HasPrefix(rep.mediaType, strings.TrimSuffix(mr.mediaType, "*")) {
+				continue
+			}
+			s = 2
+		case rep.matches(mr.mediaType):
+			s = 3
+		default:
+			continue
+		}
+
+		if s > specificity {
+			q, position, specificity = mr.q, i, s
+		}
+	}
+	return q, position
+}
+
+func (rep representation) matches(mediaType string) bool {
+	if mediaType == rep.mediaType {
+		return true
+	}
+	for _, alias := range rep.aliases {
+		if mediaType == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiate picks the representation to render a response in from the Accept header, list saying whether the response
+// is a list of students. It responds itself with a 406 when there's nothing the client accepts. Handlers should
+// negotiate before they change anything, so that the client isn't told its request failed after it went through.
+func (s *Server) negotiate(w http.ResponseWriter, r *http.Request, list bool) (representation, bool) {
+	w.Header().Add("Vary", "Accept")
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return representations[0], true
+	}
+
+	var candidates []representation
+	for _, rep := range representations {
+		if !rep.listOnly || list {
+			candidates = append(candidates, rep)
+		}
+	}
+
+	rep, ok := preferred(parseAccept(accept), candidates)
+	if !ok {
+		RespondWithJSONError(w, http.StatusNotAcceptable, ErrorResponse{
+			Error:   "not_acceptable",
+			Message: "none of the media types in the Accept header can be rendered",
+			Details: map[string]any{"supported": supportedMediaTypes(list, false)},
+		})
+		return representation{}, false
+	}
+	return rep, true
+}
+
+// preferred returns the representation of candidates the client wants most going by ranges, the first one on a tie. It
+// returns false when the client wants none of them.
+func preferred(ranges []mediaRange, candidates []representation) (representation, bool) {
+	best, bestQ, bestPosition := -1, 0.0, 0
+	for i, rep := range candidates {
+		q, position := rep.quality(ranges)
+		if q <= 0 {
+			continue
+		}
+		if best == -1 || q > bestQ || (q == bestQ && position < bestPosition) {
+			best, bestQ, bestPosition = i, q, position
+		}
+	}
+
+	if best == -1 {
+		return representation{}, false
+	}
+	return candidates[best], true
+}
+
+// render writes v out in rep.
+func (s *Server) render(w http.ResponseWriter, rep representation, status int, v any) {
+	contentType := rep.mediaType
+	if strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	if err := rep.encode(w, v); err != nil {
+		s.Logger.Error("error encoding response", "mediaType", rep.mediaType, "error", err)
+	}
+}
+
+// decode reads the request body into v, in the format given by its Content-Type. Bodies without a Content-Type are
+// read as JSON, as they always have been. It responds itself with a 415 or a 400 when the body can't be read.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	rep := representations[0]
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		found := false
+		if err == nil {
+			for _, candidate := range representations {
+				if candidate.decode != nil && candidate.matches(mediaType) {
+					rep, found = candidate, true
+					break
+				}
+			}
+		}
+
+		if !found {
+			RespondWithJSONError(w, http.StatusUnsupportedMediaType, ErrorResponse{
+				Error:   "unsupported_media_type",
+				Message: "request bodies can't be read from " + contentType,
+				Details: map[string]any{"supported": supportedMediaTypes(false, true)},
+			})
+			return false
+		}
+	}
+
+	if err := rep.decode(r.Body, v); err != nil {
+		s.Logger.Error("error unmarshalling request body", "mediaType", rep.mediaType, "error", err)
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func supportedMediaTypes(list, decoding bool) []string {
+	var mediaTypes []string
+	for _, rep := range representations {
+		if (decoding && rep.decode == nil) || (rep.listOnly && !list) {
+			continue
+		}
+		mediaTypes = append(mediaTypes, rep.mediaType)
+	}
+	return mediaTypes
+}
@@ -0,0 +1,566 @@
+package student
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/swaggest/swgui/v5emb"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// OpenAPI 3.1 description of every route in NewRequestMultiplexer.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// openAPIURL is where the document is registered with the schema compiler, schema locations in errors are relative to
+// it.
+const openAPIURL = "mem:///openapi.json"
+
+var apiDocs = sync.OnceValue(func() http.Handler {
+	return v5emb.New("Students API", "/openapi.json", "/docs/")
+})
+
+// OpenAPIDocument serves the OpenAPI document.
+func (s *Server) OpenAPIDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIDocument)
+}
+
+// APIDocs serves a Swagger UI, embedded in the binary, to browse the OpenAPI document with.
+func (s *Server) APIDocs(w http.ResponseWriter, r *http.Request) {
+	apiDocs().ServeHTTP(w, r)
+}
+
+// OpenAPIValidator checks requests, and optionally responses, against the OpenAPI document.
+type OpenAPIValidator struct {
+	// ValidateResponses checks responses too. Mismatches are reported to OnResponseError, the response itself is sent
+	// unchanged. Meant for tests.
+	ValidateResponses bool
+	// OnResponseError hears about responses that don't match the document. They're logged when it's nil.
+	OnResponseError func(r *http.Request, err error)
+
+	operations map[string]*openAPIOperation
+}
+
+// NewOpenAPIValidator validates requests when OPENAPI_VALIDATION is true, and returns nil otherwise.
+func NewOpenAPIValidator() *OpenAPIValidator {
+	v := os.Getenv(openAPIValidation)
+	if v == "" {
+		return nil
+	}
+
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("invalid %q: %q", openAPIValidation, v)
+	}
+	if !enabled {
+		return nil
+	}
+
+	validator, err := LoadOpenAPIValidator()
+	if err != nil {
+		log.Fatalf("invalid openapi document: %v", err)
+	}
+	return validator
+}
+
+// openAPIOperation is an operation of the document, with its schemas compiled.
+type openAPIOperation struct {
+	parameters   []openAPIParameter
+	bodyRequired bool
+	// bodies maps the media types a request body can be sent in to its schema. Only JSON schemas are compiled, the
+	// others are nil.
+	bodies map[string]*jsonschema.Schema
+	// responses maps a status ("200", "4XX" or "default") to the media types of the response and their schemas.
+	responses map[string]map[string]*jsonschema.Schema
+}
+
+type openAPIParameter struct {
+	name     string
+	in       string
+	required bool
+	// kind is the type in the parameter's schema, which the raw value is converted to before it's validated.
+	kind   string
+	schema *jsonschema.Schema
+}
+
+// openAPIDoc is the part of the document the validator reads, the schemas are left to the schema compiler.
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIRawParameter `json:"parameters"`
+		Responses  map[string]openAPIRawResponse  `json:"responses"`
+	} `json:"components"`
+}
+
+type openAPIRawParameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   struct {
+		Type string `json:"type"`
+	} `json:"schema"`
+}
+
+type openAPIRawResponse struct {
+	Ref     string                     `json:"$ref"`
+	Content map[string]json.RawMessage `json:"content"`
+}
+
+type openAPIRawOperation struct {
+	Parameters  []openAPIRawParameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool                       `json:"required"`
+		Content  map[string]json.RawMessage `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]openAPIRawResponse `json:"responses"`
+}
+
+// OpenAPIOperations lists every operation in the OpenAPI document as "METHOD path", the same way routes are keyed in
+// the access policy.
+func OpenAPIOperations() ([]string, error) {
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		return nil, err
+	}
+
+	var operations []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method != "parameters" {
+				operations = append(operations, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	return operations, nil
+}
+
+// LoadOpenAPIValidator compiles the schemas of every operation in the OpenAPI document.
+func LoadOpenAPIValidator() (*OpenAPIValidator, error) {
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		return nil, err
+	}
+
+	schemaDoc, err := jsonschema.UnmarshalJSON(bytes.NewReader(openAPIDocument))
+	if err != nil {
+		return nil, err
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(openAPIURL, schemaDoc); err != nil {
+		return nil, err
+	}
+
+	v := &OpenAPIValidator{operations: make(map[string]*openAPIOperation)}
+	for path, item := range doc.Paths {
+		pathPtr := "/paths/" + escapePointer(path)
+
+		var shared []openAPIRawParameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+
+			var rawOp openAPIRawOperation
+			if err := json.Unmarshal(raw, &rawOp); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+
+			opPtr := pathPtr + "/" + method
+			op := &openAPIOperation{
+				bodies:    make(map[string]*jsonschema.Schema),
+				responses: make(map[string]map[string]*jsonschema.Schema),
+			}
+
+			params := make([]openAPIParameter, 0, len(shared)+len(rawOp.Parameters))
+			for i, p := range shared {
+				param, err := compileParameter(c, &doc, p, fmt.Sprintf("%s/parameters/%d", pathPtr, i))
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+				params = append(params, param)
+			}
+			for i, p := range rawOp.Parameters {
+				param, err := compileParameter(c, &doc, p, fmt.Sprintf("%s/parameters/%d", opPtr, i))
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+				params = append(params, param)
+			}
+			op.parameters = params
+
+			if body := rawOp.RequestBody; body != nil {
+				op.bodyRequired = body.Required
+				for mediaType := range body.Content {
+					var schema *jsonschema.Schema
+					if mediaType == "application/json" {
+						ptr := opPtr + "/requestBody/content/" + escapePointer(mediaType) + "/schema"
+						if schema, err = c.Compile(openAPIURL + "#" + ptr); err != nil {
+							return nil, err
+						}
+					}
+					op.bodies[mediaType] = schema
+				}
+			}
+
+			for status, response := range rawOp.Responses {
+				ptr := opPtr + "/responses/" + status
+				if response.Ref != "" {
+					name := strings.TrimPrefix(response.Ref, "#/components/responses/")
+					response = doc.Components.Responses[name]
+					ptr = "/components/responses/" + name
+				}
+
+				contents := make(map[string]*jsonschema.Schema)
+				for mediaType := range response.Content {
+					var schema *jsonschema.Schema
+					if mediaType == "application/json" {
+						schemaPtr := ptr + "/content/" + escapePointer(mediaType) + "/schema"
+						if schema, err = c.Compile(openAPIURL + "#" + schemaPtr); err != nil {
+							return nil, err
+						}
+					}
+					contents[mediaType] = schema
+				}
+				op.responses[status] = contents
+			}
+
+			v.operations[strings.ToUpper(method)+" "+path] = op
+		}
+	}
+	return v, nil
+}
+
+func compileParameter(c *jsonschema.Compiler, doc *openAPIDoc, p openAPIRawParameter, ptr string) (openAPIParameter, error) {
+	if p.Ref != "" {
+		name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+		resolved, ok := doc.Components.Parameters[name]
+		if !ok {
+			return openAPIParameter{}, fmt.Errorf("unknown parameter %q", p.Ref)
+		}
+		p, ptr = resolved, "/components/parameters/"+name
+	}
+
+	schema, err := c.Compile(openAPIURL + "#" + ptr + "/schema")
+	if err != nil {
+		return openAPIParameter{}, err
+	}
+	return openAPIParameter{name: p.Name, in: p.In, required: p.Required, kind: p.Schema.Type, schema: schema}, nil
+}
+
+// escapePointer escapes a key of the document to be used in a JSON pointer.
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// ValidateOpenAPI rejects requests that don't match the OpenAPI document with a 400, or a 415 for bodies of a media
+// type the operation doesn't take. Like Authorize, it has to sit behind the mux, operations are looked up by the
+// pattern the request was routed by. It lets everything through when there's no validator.
+func (s *Server) ValidateOpenAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := s.Validator
+		if v == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		method := r.Method
+		if method == http.MethodHead {
+			method = http.MethodGet
+		}
+		op, ok := v.operations[method+" "+r.Pattern]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if problems := op.validateParameters(r); len(problems) > 0 {
+			respondInvalidRequest(w, problems)
+			return
+		}
+		if !op.validateBody(w, r) {
+			return
+		}
+
+		if !v.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &specRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if err := op.validateResponse(rec); err != nil {
+			if v.OnResponseError != nil {
+				v.OnResponseError(r, err)
+			} else {
+				s.Logger.Error("response doesn't match the openapi document",
+					"request", r.Method+" "+r.URL.Path, "error", err)
+			}
+		}
+	})
+}
+
+func (op *openAPIOperation) validateParameters(r *http.Request) []string {
+	var problems []string
+	for _, p := range op.parameters {
+		var raw string
+		var present bool
+		switch p.in {
+		case "path":
+			raw = r.PathValue(p.name)
+			present = raw != ""
+		case "query":
+			present = r.URL.Query().Has(p.name)
+			raw = r.URL.Query().Get(p.name)
+		case "header":
+			raw = r.Header.Get(p.name)
+			present = raw != ""
+		}
+
+		if !present {
+			if p.required {
+				problems = append(problems, fmt.Sprintf("%s parameter %s is required", p.in, p.name))
+			}
+			continue
+		}
+
+		value, err := parameterValue(p.kind, raw)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s parameter %s: %v", p.in, p.name, err))
+			continue
+		}
+		for _, problem := range schemaProblems(p.schema.Validate(value)) {
+			problems = append(problems, fmt.Sprintf("%s parameter %s: %s", p.in, p.name, problem))
+		}
+	}
+	return problems
+}
+
+// parameterValue converts a raw parameter to the type its schema expects.
+func parameterValue(kind, raw string) (any, error) {
+	switch kind {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a %s", raw, kind)
+		}
+		return json.Number(raw), nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", raw)
+		}
+		return b, nil
+	case "array":
+		// arrays are only taken comma separated, the form style without explode, and only of strings.
+		var items []any
+		for _, item := range strings.Split(raw, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+		return items, nil
+	default:
+		return raw, nil
+	}
+}
+
+// validateBody checks the request body, which is read and put back for JSON bodies, and only looked at for the others.
+// It responds itself when the body is invalid.
+func (op *openAPIOperation) validateBody(w http.ResponseWriter, r *http.Request) bool {
+	if len(op.bodies) == 0 {
+		return true
+	}
+
+	// bodies without a Content-Type are read as JSON, see decode.
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			parsed = contentType
+		}
+		mediaType = parsed
+	}
+
+	schema, ok := op.bodies[mediaType]
+	if !ok {
+		var supported []string
+		for mediaType := range op.bodies {
+			supported = append(supported, mediaType)
+		}
+		RespondWithJSONError(w, http.StatusUnsupportedMediaType, ErrorResponse{
+			Error:   "unsupported_media_type",
+			Message: "request bodies can't be sent as " + mediaType,
+			Details: map[string]any{"supported": supported},
+		})
+		return false
+	}
+	if schema == nil {
+		return true
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.bodyRequired {
+			respondInvalidRequest(w, []string{"request body is required"})
+			return false
+		}
+		return true
+	}
+
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		respondInvalidRequest(w, []string{"request body is not valid JSON"})
+		return false
+	}
+	if problems := schemaProblems(schema.Validate(value)); len(problems) > 0 {
+		for i := range problems {
+			problems[i] = "request body " + problems[i]
+		}
+		respondInvalidRequest(w, problems)
+		return false
+	}
+	return true
+}
+
+func (op *openAPIOperation) validateResponse(rec *specRecorder) error {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	contents, ok := op.responses[strconv.Itoa(status)]
+	if !ok {
+		contents, ok = op.responses[fmt.Sprintf("%dXX", status/100)]
+	}
+	if !ok {
+		contents, ok = op.responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("status %d isn't documented", status)
+	}
+
+	// bodies of responses that can't have one are dropped by net/http.
+	if !rec.wroteBody || status == http.StatusNoContent || status == http.StatusNotModified {
+		return nil
+	}
+
+	schema, ok := contents[rec.mediaType]
+	if !ok {
+		return fmt.Errorf("status %d isn't documented with a %q body", status, rec.mediaType)
+	}
+	if schema == nil || !rec.keep {
+		return nil
+	}
+
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(rec.body.Bytes()))
+	if err != nil {
+		return fmt.Errorf("status %d body is not valid JSON: %w", status, err)
+	}
+	if problems := schemaProblems(schema.Validate(value)); len(problems) > 0 {
+		return fmt.Errorf("status %d body: %s", status, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+var schemaErrorPrinter = message.NewPrinter(language.English)
+
+// schemaProblems flattens a validation error into the problems at its leaves, e.g. "/age: minimum: got 0, want 1".
+func schemaProblems(err error) []string {
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		if err != nil {
+			return []string{err.Error()}
+		}
+		return nil
+	}
+
+	var problems []string
+	var walk func(ve *jsonschema.ValidationError)
+	walk = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			location := "/" + strings.Join(ve.InstanceLocation, "/")
+			problems = append(problems, location+": "+ve.ErrorKind.LocalizedString(schemaErrorPrinter))
+			return
+		}
+		for _, cause := range ve.Causes {
+			walk(cause)
+		}
+	}
+	walk(ve)
+	return problems
+}
+
+func respondInvalidRequest(w http.ResponseWriter, problems []string) {
+	RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+		Error:   "invalid_request",
+		Message: problems[0],
+		Details: map[string]any{"problems": problems},
+	})
+}
+
+// specRecorder passes a response through to the client, keeping a copy of JSON bodies to validate. Streams, other
+// formats and compressed bodies aren't kept, they're only checked against the media types the document lists.
+type specRecorder struct {
+	http.ResponseWriter
+	status    int
+	wroteBody bool
+	mediaType string
+	keep      bool
+	body      bytes.Buffer
+}
+
+func (rec *specRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *specRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteBody && len(b) > 0 {
+		// responses written without a Content-Type get a sniffed one, as net/http would.
+		if rec.Header().Get("Content-Type") == "" {
+			rec.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		rec.mediaType, _, _ = mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		rec.keep = rec.mediaType == "application/json" && rec.Header().Get("Content-Encoding") == ""
+		rec.wroteBody = true
+	}
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.keep {
+		rec.body.Write(b)
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *specRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,216 @@
+package student
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDispatchInterval = time.Second
+	defaultDispatchBatch    = 100
+	defaultOutboxRetention  = 7 * 24 * time.Hour
+)
+
+// Event is a change to a student, or an alert about one, as published to downstream systems. Events are written to the
+// outbox in the same transaction as the change. Ids increase with every event, in commit order, so they can be used to
+// resume from.
+type Event struct {
+	Id        int64  `json:"id"`
+	Type      string `json:"type"`
+	StudentId int    `json:"student_id"`
+	// Student is the state the change left the student in. It's empty for purges, after which nothing is left, and
+	// for attendance alerts, which don't change the student.
+	Student *Student `json:"student,omitempty"`
+	// Previous is the state the student was in before the change, for changes to a student that already existed.
+	Previous *Student `json:"previous,omitempty"`
+	// Attendance is the attendance that fell below the threshold, on EventAttendanceLow events.
+	Attendance *AttendanceSummary `json:"attendance,omitempty"`
+	Actor      string             `json:"actor"`
+	RequestId  string             `json:"request_id,omitempty"`
+	OccurredAt time.Time          `json:"occurred_at"`
+}
+
+// event is the outbox entry for the mutation. Event types are the same as the audit actions.
+func (m mutation) event() (Event, []byte, error) {
+	e := Event{
+		Type:       m.action,
+		StudentId:  m.studentId,
+		Student:    m.revisionSnapshot(),
+		Previous:   m.before,
+		Actor:      m.actor,
+		RequestId:  m.requestId,
+		OccurredAt: time.Now().UTC(),
+	}
+
+	payload, err := json.Marshal(e)
+	return e, payload, err
+}
+
+func decodeEvent(id int64, payload []byte) (Event, error) {
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Event{}, err
+	}
+	e.Id = id
+	return e, nil
+}
+
+// EventSink is somewhere events are delivered to. Delivery is at-least-once, so sinks may see an event more than once.
+type EventSink interface {
+	Deliver(ctx context.Context, e Event) error
+}
+
+// Dispatcher delivers the events in the outbox to the sinks. Events of a student are delivered in order: once one of
+// them fails, the rest of that student's events wait for the next attempt. Only one dispatcher should run per outbox.
+type Dispatcher struct {
+	Outbox OutboxStore
+	Sinks  []EventSink
+	Logger *slog.Logger
+
+	Interval  time.Duration
+	BatchSize int
+	// Retention is how long delivered events are kept in the outbox for.
+	Retention time.Duration
+}
+
+// Run dispatches pending events every interval, until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = defaultDispatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		if _, err := d.DispatchPending(ctx); err != nil {
+			d.Logger.Error("error dispatching events", "error", err)
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			d.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending makes a single pass over the pending events, a batch at a time, and returns how many it delivered.
+// Students whose events fail are left out of the batches after, so that their backlog can't hold up the others.
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	blocked := make(map[int]bool)
+	var skip []int
+	total := 0
+	for {
+		events, err := d.Outbox.PendingEvents(ctx, d.batchSize(), skip)
+		if err != nil {
+			return total, err
+		}
+
+		var delivered []int64
+		for _, e := range events {
+			if blocked[e.StudentId] {
+				continue
+			}
+
+			if err := d.deliver(ctx, e); err != nil {
+				d.Logger.Error("error delivering event", "eventId", e.Id, "studentId", e.StudentId, "error", err)
+				blocked[e.StudentId] = true
+				skip = append(skip, e.StudentId)
+				continue
+			}
+			delivered = append(delivered, e.Id)
+		}
+
+		if len(delivered) > 0 {
+			if err := d.Outbox.MarkEventsDispatched(ctx, delivered); err != nil {
+				return total, err
+			}
+			total += len(delivered)
+		}
+
+		// every full batch either delivers an event or blocks a student, so the pass ends.
+		if len(events) < d.batchSize() {
+			return total, nil
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, e Event) error {
+	for _, sink := range d.Sinks {
+		if err := sink.Deliver(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) cleanup(ctx context.Context) {
+	retention := d.Retention
+	if retention <= 0 {
+		retention = defaultOutboxRetention
+	}
+
+	if _, err := d.Outbox.DeleteDispatchedEvents(ctx, time.Now().Add(-retention)); err != nil {
+		d.Logger.Error("error deleting dispatched events", "error", err)
+	}
+}
+
+func (d *Dispatcher) batchSize() int {
+	if d.BatchSize <= 0 {
+		return defaultDispatchBatch
+	}
+	return d.BatchSize
+}
+
+// NewEventSinks builds the sinks listed in OUTBOX_SINKS: "stdout" and/or "ndjson", which appends to the file at
+// OUTBOX_NDJSON_PATH.
+func NewEventSinks() []EventSink {
+	var sinks []EventSink
+	for _, name := range strings.Split(os.Getenv(outboxSinks), ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "stdout":
+			sinks = append(sinks, NewWriterSink(os.Stdout))
+		case "ndjson":
+			path := os.Getenv(outboxNDJSONPath)
+			if path == "" {
+				log.Fatalf("%q is required for the ndjson sink", outboxNDJSONPath)
+			}
+			sink, err := NewFileSink(path)
+			if err != nil {
+				log.Fatalf("unable to open event file: %v", err)
+			}
+			sinks = append(sinks, sink)
+		default:
+			log.Fatalf("unknown event sink %q in %q", name, outboxSinks)
+		}
+	}
+	return sinks
+}
+
+// NewOutboxRetention reads how long delivered events are kept for from OUTBOX_RETENTION.
+func NewOutboxRetention() time.Duration {
+	v := os.Getenv(outboxRetention)
+	if v == "" {
+		return defaultOutboxRetention
+	}
+
+	retention, err := time.ParseDuration(v)
+	if err != nil || retention <= 0 {
+		log.Fatalf("invalid %q: %q", outboxRetention, v)
+	}
+	return retention
+}
@@ -0,0 +1,113 @@
+package student
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// A4, in points.
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+	pdfMargin     = 56.0
+)
+
+// pdfDocument lays lines of text out on A4 pages, and writes them out as a PDF. It only uses the standard Helvetica
+// fonts, which every PDF viewer has, so that nothing has to be embedded.
+type pdfDocument struct {
+	// pages are the content streams of the pages.
+	pages []*bytes.Buffer
+	// y is how far from the bottom of the last page its last line is.
+	y float64
+}
+
+// pdfText is a run of text on a line, x points from the left margin.
+type pdfText struct {
+	x    float64
+	text string
+}
+
+// line writes texts on a new line, size points high, starting a new page when there's no room left on the last one.
+func (d *pdfDocument) line(size float64, bold bool, texts ...pdfText) {
+	height := size * 1.4
+	if len(d.pages) == 0 || d.y-height < pdfMargin {
+		d.pages = append(d.pages, new(bytes.Buffer))
+		d.y = pdfPageHeight - pdfMargin
+	}
+	d.y -= height
+
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	page := d.pages[len(d.pages)-1]
+	for _, t := range texts {
+		fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, pdfMargin+t.x, d.y, pdfString(t.text))
+	}
+}
+
+// space leaves points of blank space before the next line.
+func (d *pdfDocument) space(points float64) {
+	d.y -= points
+}
+
+// WriteTo writes the document out. Objects 1 to 4 are the catalog, the page tree and the two fonts, then every page is
+// a page object followed by its content stream.
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.pages = append(d.pages, new(bytes.Buffer))
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// the binary comment tells tools the file isn't plain text.
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", page.Len(), page.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.WriteTo(w)
+}
+
+// pdfString encodes text for a string literal, in the Windows-1252 the fonts are encoded with. Characters it doesn't
+// have are rendered as question marks.
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok || c < ' ' {
+			c = '?'
+		}
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
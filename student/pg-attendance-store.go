@@ -0,0 +1,248 @@
+package student
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// sessionColumns is the column list both stores select sessions with, in the order they're scanned in.
+const sessionColumns = `id, course_id, topic, starts_at`
+
+func (p *PostgresDataStore) CreateSession(ctx context.Context, cs ClassSession) (int, error) {
+	query := `INSERT INTO class_sessions (course_id, topic, starts_at) VALUES ($1, $2, $3) RETURNING id`
+	var id int
+	if err := p.Pool.QueryRow(ctx, query, cs.CourseId, cs.Topic, cs.StartsAt).Scan(&id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return 0, errors.New(errCourseNotFound)
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+func (p *PostgresDataStore) GetSession(ctx context.Context, courseId, id int) (*ClassSession, error) {
+	return getPgSession(ctx, p.Pool, courseId, id)
+}
+
+func (p *PostgresDataStore) UpdateSession(ctx context.Context, courseId, id int, cs ClassSession) error {
+	query := `UPDATE class_sessions SET topic = $1, starts_at = $2 WHERE id = $3 AND course_id = $4`
+	cTag, err := p.Pool.Exec(ctx, query, cs.Topic, cs.StartsAt, id, courseId)
+	if err != nil {
+		return err
+	}
+	if cTag.RowsAffected() == 0 {
+		return errors.New(errSessionNotFound)
+	}
+	return nil
+}
+
+// DeleteSession deletes a session. Its attendance goes with it, by way of its foreign key.
+func (p *PostgresDataStore) DeleteSession(ctx context.Context, courseId, id int) error {
+	cTag, err := p.Pool.Exec(ctx, `DELETE FROM class_sessions WHERE id = $1 AND course_id = $2`, id, courseId)
+	if err != nil {
+		return err
+	}
+	if cTag.RowsAffected() == 0 {
+		return errors.New(errSessionNotFound)
+	}
+	return nil
+}
+
+func (p *PostgresDataStore) ListSessions(ctx context.Context, courseId int) ([]ClassSession, error) {
+	if _, err := p.GetCourse(ctx, courseId); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + sessionColumns + ` FROM class_sessions WHERE course_id = $1 ORDER BY starts_at, id`
+	rows, err := p.Pool.Query(ctx, query, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []ClassSession
+	for rows.Next() {
+		cs, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *cs)
+	}
+	return sessions, rows.Err()
+}
+
+func (p *PostgresDataStore) MarkAttendance(ctx context.Context, courseId, sessionId int, marks []AttendanceMark,
+	others string) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// markings of a course are serialized on the course, so that each one compares its marks with the rates the one
+	// before it left, and a student falling below the threshold is alerted on once. The session and the enrollments
+	// of the students are locked, so that they can't be deleted, or withdrawn, before the marks are committed.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM courses WHERE id = $1 FOR UPDATE`, courseId); err != nil {
+		return err
+	}
+	query := `SELECT ` + sessionColumns + ` FROM class_sessions WHERE id = $1 AND course_id = $2 FOR SHARE`
+	if _, err := scanSession(tx.QueryRow(ctx, query, sessionId, courseId)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New(errSessionNotFound)
+		}
+		return err
+	}
+
+	studentIds := make([]int, len(marks))
+	statuses := make([]string, len(marks))
+	for i, mark := range marks {
+		studentIds[i], statuses[i] = mark.StudentId, mark.Status
+	}
+
+	query = `SELECT student_id FROM enrollments WHERE course_id = $1 AND status = 'enrolled' AND student_id = ANY($2)
+		FOR SHARE`
+	rows, err := tx.Query(ctx, query, courseId, studentIds)
+	if err != nil {
+		return err
+	}
+	enrolled, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+	if err := checkEnrolled(studentIds, enrolled); err != nil {
+		return err
+	}
+
+	before, err := p.courseAttendance(ctx, tx, courseId)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO attendance (session_id, student_id, status)
+		SELECT $1::int, m.student_id, m.status FROM unnest($2::int[], $3::text[]) AS m (student_id, status)
+		ON CONFLICT (session_id, student_id) DO UPDATE SET status = excluded.status, marked_at = now()`
+	if _, err := tx.Exec(ctx, query, sessionId, studentIds, statuses); err != nil {
+		return err
+	}
+
+	if others != "" {
+		// every enrolled student that doesn't have a mark of this session by now is one of the others.
+		query = `INSERT INTO attendance (session_id, student_id, status)
+			SELECT $1, e.student_id, $2 FROM enrollments e WHERE e.course_id = $3 AND e.status = 'enrolled'
+			ON CONFLICT (session_id, student_id) DO NOTHING`
+		if _, err := tx.Exec(ctx, query, sessionId, others, courseId); err != nil {
+			return err
+		}
+	}
+
+	after, err := p.courseAttendance(ctx, tx, courseId)
+	if err != nil {
+		return err
+	}
+	for _, alert := range attendanceAlerts(before, after) {
+		e, payload, err := attendanceEvent(ctx, alert)
+		if err != nil {
+			return err
+		}
+		if err := p.insertEvent(ctx, tx, e, payload); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) ListAttendance(ctx context.Context, courseId, sessionId int) ([]AttendanceMark, error) {
+	if _, err := p.GetSession(ctx, courseId, sessionId); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT session_id, student_id, status, marked_at FROM attendance WHERE session_id = $1 ORDER BY student_id`
+	rows, err := p.Pool.Query(ctx, query, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var marks []AttendanceMark
+	for rows.Next() {
+		var m AttendanceMark
+		if err := rows.Scan(&m.SessionId, &m.StudentId, &m.Status, &m.MarkedAt); err != nil {
+			return nil, err
+		}
+		marks = append(marks, m)
+	}
+	return marks, rows.Err()
+}
+
+func (p *PostgresDataStore) CourseAttendance(ctx context.Context, courseId int) ([]AttendanceSummary, error) {
+	if _, err := p.GetCourse(ctx, courseId); err != nil {
+		return nil, err
+	}
+	return p.courseAttendance(ctx, p.Pool, courseId)
+}
+
+func (p *PostgresDataStore) StudentAttendance(ctx context.Context, studentId int) ([]AttendanceSummary, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM students WHERE id = $1 AND deleted_at IS NULL)`
+	if err := p.Pool.QueryRow(ctx, query, studentId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(errStudentNotFound)
+	}
+
+	rows, err := p.Pool.Query(ctx, pgAttendanceQuery+` WHERE e.student_id = $1 AND e.status = 'enrolled'
+		GROUP BY e.course_id, e.student_id ORDER BY e.course_id`, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttendanceSummaries(rows, p.AttendanceThreshold)
+}
+
+// pgAttendanceQuery counts the marks of enrolled students, to be narrowed down and grouped by course and student.
+const pgAttendanceQuery = `SELECT e.course_id, e.student_id, count(a.status),
+		count(*) FILTER (WHERE a.status = 'present'), count(*) FILTER (WHERE a.status = 'late'),
+		count(*) FILTER (WHERE a.status = 'absent'), count(*) FILTER (WHERE a.status = 'excused')
+	FROM enrollments e
+	LEFT JOIN class_sessions s ON s.course_id = e.course_id
+	LEFT JOIN attendance a ON a.session_id = s.id AND a.student_id = e.student_id`
+
+func (p *PostgresDataStore) courseAttendance(ctx context.Context, q pgQuerier, courseId int) (
+	[]AttendanceSummary, error) {
+	rows, err := q.Query(ctx, pgAttendanceQuery+` WHERE e.course_id = $1 AND e.status = 'enrolled'
+		GROUP BY e.course_id, e.student_id ORDER BY e.student_id`, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttendanceSummaries(rows, p.AttendanceThreshold)
+}
+
+func getPgSession(ctx context.Context, q pgQuerier, courseId, id int) (*ClassSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM class_sessions WHERE id = $1 AND course_id = $2`
+	cs, err := scanSession(q.QueryRow(ctx, query, id, courseId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errSessionNotFound)
+		}
+		return nil, err
+	}
+	return cs, nil
+}
+
+// scanSession scans a session selected with sessionColumns, in either store.
+func scanSession(row interface{ Scan(...any) error }) (*ClassSession, error) {
+	var cs ClassSession
+	if err := row.Scan(&cs.Id, &cs.CourseId, &cs.Topic, &cs.StartsAt); err != nil {
+		return nil, err
+	}
+	cs.StartsAt = cs.StartsAt.UTC()
+	return &cs, nil
+}
@@ -0,0 +1,118 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgUniqueViolation is the SQLSTATE Postgres fails an insert or update that breaks a unique constraint with.
+const pgUniqueViolation = "23505"
+
+// courseColumns is the column list both stores select courses with, in the order they're scanned in.
+const courseColumns = `id, code, title, description, capacity, start_date, end_date`
+
+func (p *PostgresDataStore) CreateCourse(ctx context.Context, c Course) (int, error) {
+	query := `INSERT INTO courses (code, title, description, capacity, start_date, end_date)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	var id int
+	err := p.Pool.QueryRow(ctx, query, c.Code, c.Title, c.Description, c.Capacity, c.StartDate.Time, c.EndDate.Time).
+		Scan(&id)
+	if err != nil {
+		return 0, pgCourseError(err)
+	}
+	return id, nil
+}
+
+func (p *PostgresDataStore) GetCourse(ctx context.Context, id int) (*Course, error) {
+	query := `SELECT ` + courseColumns + ` FROM courses WHERE id = $1`
+	c, err := scanPgCourse(p.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errCourseNotFound)
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *PostgresDataStore) UpdateCourse(ctx context.Context, id int, c Course) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE courses SET code = $1, title = $2, description = $3, capacity = $4, start_date = $5,
+		end_date = $6 WHERE id = $7`
+	cTag, err := tx.Exec(ctx, query, c.Code, c.Title, c.Description, c.Capacity, c.StartDate.Time, c.EndDate.Time, id)
+	if err != nil {
+		return pgCourseError(err)
+	}
+	if cTag.RowsAffected() == 0 {
+		return errors.New(errCourseNotFound)
+	}
+
+	// seats added to the course go to its waitlist. Students keep theirs when seats are taken away.
+	if err := fillPgSeats(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) DeleteCourse(ctx context.Context, id int) error {
+	cTag, err := p.Pool.Exec(ctx, `DELETE FROM courses WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if cTag.RowsAffected() == 0 {
+		return errors.New(errCourseNotFound)
+	}
+	return nil
+}
+
+func (p *PostgresDataStore) ListCourses(ctx context.Context, opts ListOptions) ([]Course, error) {
+	query := `SELECT ` + courseColumns + ` FROM courses WHERE id > $1 ORDER BY id`
+	args := []any{opts.AfterId}
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := p.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []Course
+	for rows.Next() {
+		c, err := scanPgCourse(rows)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, *c)
+	}
+	return courses, rows.Err()
+}
+
+func scanPgCourse(row pgx.Row) (*Course, error) {
+	var c Course
+	err := row.Scan(&c.Id, &c.Code, &c.Title, &c.Description, &c.Capacity, &c.StartDate.Time, &c.EndDate.Time)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// pgCourseError turns the violation of the unique code into errCourseCodeTaken.
+func pgCourseError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return errors.New(errCourseCodeTaken)
+	}
+	return err
+}
@@ -0,0 +1,228 @@
+package student
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// enrollmentColumns is the column list both stores select enrollments e with, in the order they're scanned in. The
+// position of a waitlisted student is one more than how many were waitlisted before it.
+const enrollmentColumns = `e.course_id, e.student_id, e.status,
+	CASE WHEN e.status = 'waitlisted' THEN (SELECT count(*) FROM enrollments w
+		WHERE w.course_id = e.course_id AND w.status = 'waitlisted' AND w.id <= e.id) ELSE 0 END,
+	e.created_at`
+
+func (p *PostgresDataStore) Enroll(ctx context.Context, courseId, studentId int) (*Enrollment, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// the student can't be deleted until the enrollment is committed, and the course takes one enrollment at a time,
+	// so that two students can't both take its last seat. Students are locked before courses, like DeleteStudent does.
+	query := `SELECT 1 FROM students WHERE id = $1 AND deleted_at IS NULL FOR SHARE`
+	if err := tx.QueryRow(ctx, query, studentId).Scan(new(int)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errStudentNotFound)
+		}
+		return nil, err
+	}
+	seats, err := freePgSeats(ctx, tx, courseId)
+	if err != nil {
+		return nil, err
+	}
+
+	status := EnrollmentEnrolled
+	if seats == 0 {
+		status = EnrollmentWaitlisted
+	}
+	query = `INSERT INTO enrollments (course_id, student_id, status) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, courseId, studentId, status); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return nil, errors.New(errAlreadyEnrolled)
+		}
+		return nil, err
+	}
+
+	e, err := getPgEnrollment(ctx, tx, courseId, studentId)
+	if err != nil {
+		return nil, err
+	}
+	return e, tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) GetEnrollment(ctx context.Context, courseId, studentId int) (*Enrollment, error) {
+	return getPgEnrollment(ctx, p.Pool, courseId, studentId)
+}
+
+func (p *PostgresDataStore) Withdraw(ctx context.Context, courseId, studentId int) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the course is locked first, so that the seat that's freed goes to the waitlist rather than to a new enrollment.
+	if _, err := freePgSeats(ctx, tx, courseId); err != nil {
+		return err
+	}
+
+	cTag, err := tx.Exec(ctx, `DELETE FROM enrollments WHERE course_id = $1 AND student_id = $2`, courseId, studentId)
+	if err != nil {
+		return err
+	}
+	if cTag.RowsAffected() == 0 {
+		return errors.New(errEnrollmentNotFound)
+	}
+
+	if err := fillPgSeats(ctx, tx, courseId); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) ListEnrollments(ctx context.Context, courseId int) ([]Enrollment, error) {
+	if _, err := p.GetCourse(ctx, courseId); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + enrollmentColumns + ` FROM enrollments e WHERE e.course_id = $1
+		ORDER BY e.status = 'waitlisted', e.id`
+	rows, err := p.Pool.Query(ctx, query, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var enrollments []Enrollment
+	for rows.Next() {
+		e, err := scanPgEnrollment(rows)
+		if err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, *e)
+	}
+	return enrollments, rows.Err()
+}
+
+func (p *PostgresDataStore) ListStudentCourses(ctx context.Context, studentId int) ([]StudentCourse, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM students WHERE id = $1 AND deleted_at IS NULL)`
+	if err := p.Pool.QueryRow(ctx, query, studentId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(errStudentNotFound)
+	}
+
+	query = `SELECT c.id, c.code, c.title, c.description, c.capacity, c.start_date, c.end_date, ` + enrollmentColumns + `
+		FROM enrollments e JOIN courses c ON c.id = e.course_id WHERE e.student_id = $1 ORDER BY c.id`
+	rows, err := p.Pool.Query(ctx, query, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []StudentCourse
+	for rows.Next() {
+		var sc StudentCourse
+		c, e := &sc.Course, &sc.Enrollment
+		err := rows.Scan(&c.Id, &c.Code, &c.Title, &c.Description, &c.Capacity, &c.StartDate.Time, &c.EndDate.Time,
+			&e.CourseId, &e.StudentId, &e.Status, &e.Position, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, sc)
+	}
+	return courses, rows.Err()
+}
+
+// releasePgEnrollments applies the EnrolledStudents policy to a student that's being deleted, and is locked in tx.
+func (p *PostgresDataStore) releasePgEnrollments(ctx context.Context, tx pgx.Tx, studentId int) error {
+	if p.EnrolledStudents != WithdrawEnrolledStudents {
+		var enrolled bool
+		query := `SELECT EXISTS (SELECT 1 FROM enrollments WHERE student_id = $1)`
+		if err := tx.QueryRow(ctx, query, studentId).Scan(&enrolled); err != nil {
+			return err
+		}
+		if enrolled {
+			return errors.New(errStudentEnrolled)
+		}
+		return nil
+	}
+
+	// courses are locked in id order, so that two deletions can't each hold a course the other is waiting for.
+	query := `SELECT id FROM courses WHERE id IN (SELECT course_id FROM enrollments WHERE student_id = $1)
+		ORDER BY id FOR UPDATE`
+	rows, err := tx.Query(ctx, query, studentId)
+	if err != nil {
+		return err
+	}
+	courseIds, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM enrollments WHERE student_id = $1`, studentId); err != nil {
+		return err
+	}
+	for _, courseId := range courseIds {
+		if err := fillPgSeats(ctx, tx, courseId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// freePgSeats locks a course for the rest of tx, and returns how many of its seats are free.
+func freePgSeats(ctx context.Context, tx pgx.Tx, courseId int) (int, error) {
+	query := `SELECT capacity - (SELECT count(*) FROM enrollments WHERE course_id = $1 AND status = 'enrolled')
+		FROM courses WHERE id = $1 FOR UPDATE`
+	var seats int
+	if err := tx.QueryRow(ctx, query, courseId).Scan(&seats); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, errors.New(errCourseNotFound)
+		}
+		return 0, err
+	}
+	// a course can have more students than seats, when its capacity was lowered after they enrolled.
+	return max(seats, 0), nil
+}
+
+// fillPgSeats gives the free seats of a course to the students at the front of its waitlist.
+func fillPgSeats(ctx context.Context, tx pgx.Tx, courseId int) error {
+	seats, err := freePgSeats(ctx, tx, courseId)
+	if err != nil || seats == 0 {
+		return err
+	}
+
+	query := `UPDATE enrollments SET status = 'enrolled' WHERE id IN (
+		SELECT id FROM enrollments WHERE course_id = $1 AND status = 'waitlisted' ORDER BY id LIMIT $2)`
+	_, err = tx.Exec(ctx, query, courseId, seats)
+	return err
+}
+
+func getPgEnrollment(ctx context.Context, q pgQuerier, courseId, studentId int) (*Enrollment, error) {
+	query := `SELECT ` + enrollmentColumns + ` FROM enrollments e WHERE e.course_id = $1 AND e.student_id = $2`
+	e, err := scanPgEnrollment(q.QueryRow(ctx, query, courseId, studentId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errEnrollmentNotFound)
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+func scanPgEnrollment(row pgx.Row) (*Enrollment, error) {
+	var e Enrollment
+	if err := row.Scan(&e.CourseId, &e.StudentId, &e.Status, &e.Position, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,241 @@
+package student
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgForeignKeyViolation is the SQLSTATE Postgres fails a write that references a row that doesn't exist with.
+const pgForeignKeyViolation = "23503"
+
+// assessmentColumns is the column list both stores select assessments with, in the order they're scanned in.
+const assessmentColumns = `id, course_id, title, weight, max_score`
+
+func (p *PostgresDataStore) CreateAssessment(ctx context.Context, a Assessment) (int, error) {
+	query := `INSERT INTO assessments (course_id, title, weight, max_score) VALUES ($1, $2, $3, $4) RETURNING id`
+	var id int
+	if err := p.Pool.QueryRow(ctx, query, a.CourseId, a.Title, a.Weight, a.MaxScore).Scan(&id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+			return 0, errors.New(errCourseNotFound)
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+func (p *PostgresDataStore) GetAssessment(ctx context.Context, courseId, id int) (*Assessment, error) {
+	return getPgAssessment(ctx, p.Pool, courseId, id, "")
+}
+
+func (p *PostgresDataStore) UpdateAssessment(ctx context.Context, courseId, id int, a Assessment) error {
+	query := `UPDATE assessments SET title = $1, weight = $2, max_score = $3 WHERE id = $4 AND course_id = $5`
+	cTag, err := p.Pool.Exec(ctx, query, a.Title, a.Weight, a.MaxScore, id, courseId)
+	if err != nil {
+		return err
+	}
+	if cTag.RowsAffected() == 0 {
+		return errors.New(errAssessmentNotFound)
+	}
+	return nil
+}
+
+// DeleteAssessment deletes an assessment. Its scores go with it, by way of their foreign key.
+func (p *PostgresDataStore) DeleteAssessment(ctx context.Context, courseId, id int) error {
+	cTag, err := p.Pool.Exec(ctx, `DELETE FROM assessments WHERE id = $1 AND course_id = $2`, id, courseId)
+	if err != nil {
+		return err
+	}
+	if cTag.RowsAffected() == 0 {
+		return errors.New(errAssessmentNotFound)
+	}
+	return nil
+}
+
+func (p *PostgresDataStore) ListAssessments(ctx context.Context, courseId int) ([]Assessment, error) {
+	if _, err := p.GetCourse(ctx, courseId); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + assessmentColumns + ` FROM assessments WHERE course_id = $1 ORDER BY id`
+	rows, err := p.Pool.Query(ctx, query, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assessments []Assessment
+	for rows.Next() {
+		a, err := scanAssessment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assessments = append(assessments, *a)
+	}
+	return assessments, rows.Err()
+}
+
+func (p *PostgresDataStore) RecordScores(ctx context.Context, courseId, assessmentId int, scores []Score) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the assessment and the enrollments of the students are locked, so that they can't be deleted, or withdrawn,
+	// before the scores are committed.
+	if _, err := getPgAssessment(ctx, tx, courseId, assessmentId, " FOR SHARE"); err != nil {
+		return err
+	}
+
+	studentIds := make([]int, len(scores))
+	values := make([]float64, len(scores))
+	for i, score := range scores {
+		studentIds[i], values[i] = score.StudentId, score.Score
+	}
+
+	query := `SELECT student_id FROM enrollments WHERE course_id = $1 AND status = 'enrolled' AND student_id = ANY($2)
+		FOR SHARE`
+	rows, err := tx.Query(ctx, query, courseId, studentIds)
+	if err != nil {
+		return err
+	}
+	enrolled, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+	if err := checkEnrolled(studentIds, enrolled); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO scores (assessment_id, student_id, score)
+		SELECT $1::int, s.student_id, s.score FROM unnest($2::int[], $3::float8[]) AS s (student_id, score)
+		ON CONFLICT (assessment_id, student_id) DO UPDATE SET score = excluded.score, graded_at = now()`
+	if _, err := tx.Exec(ctx, query, assessmentId, studentIds, values); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) ListScores(ctx context.Context, courseId, assessmentId int) ([]Score, error) {
+	if _, err := p.GetAssessment(ctx, courseId, assessmentId); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT assessment_id, student_id, score, graded_at FROM scores WHERE assessment_id = $1 ORDER BY student_id`
+	rows, err := p.Pool.Query(ctx, query, assessmentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []Score
+	for rows.Next() {
+		var s Score
+		if err := rows.Scan(&s.AssessmentId, &s.StudentId, &s.Score, &s.GradedAt); err != nil {
+			return nil, err
+		}
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
+
+func (p *PostgresDataStore) StudentGrades(ctx context.Context, studentId int) ([]CourseGrades, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM students WHERE id = $1 AND deleted_at IS NULL)`
+	if err := p.Pool.QueryRow(ctx, query, studentId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(errStudentNotFound)
+	}
+
+	query = `SELECT c.id, c.code, c.title, c.description, c.capacity, c.start_date, c.end_date,
+			a.id, a.title, a.weight, a.max_score, s.score
+		FROM enrollments e
+		JOIN courses c ON c.id = e.course_id
+		LEFT JOIN assessments a ON a.course_id = c.id
+		LEFT JOIN scores s ON s.assessment_id = a.id AND s.student_id = e.student_id
+		WHERE e.student_id = $1 AND e.status = 'enrolled'
+		ORDER BY c.id, a.id`
+	rows, err := p.Pool.Query(ctx, query, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCourseGrades(rows)
+}
+
+// getPgAssessment reads an assessment of a course, with lock appended to the query.
+func getPgAssessment(ctx context.Context, q pgQuerier, courseId, id int, lock string) (*Assessment, error) {
+	query := `SELECT ` + assessmentColumns + ` FROM assessments WHERE id = $1 AND course_id = $2` + lock
+	a, err := scanAssessment(q.QueryRow(ctx, query, id, courseId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errAssessmentNotFound)
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// scanAssessment scans an assessment selected with assessmentColumns, in either store.
+func scanAssessment(row interface{ Scan(...any) error }) (*Assessment, error) {
+	var a Assessment
+	if err := row.Scan(&a.Id, &a.CourseId, &a.Title, &a.Weight, &a.MaxScore); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// checkEnrolled fails with an errNotEnrolled for the first of studentIds that isn't one of enrolled.
+func checkEnrolled(studentIds, enrolled []int) error {
+	seats := make(map[int]bool, len(enrolled))
+	for _, id := range enrolled {
+		seats[id] = true
+	}
+	for _, id := range studentIds {
+		if !seats[id] {
+			return errNotEnrolled{studentId: id}
+		}
+	}
+	return nil
+}
+
+// scanCourseGrades groups the rows of the grades query of either store by course. A course without assessments is a
+// single row, with the columns of the assessment null.
+func scanCourseGrades(rows interface {
+	Next() bool
+	Scan(...any) error
+	Err() error
+}) ([]CourseGrades, error) {
+	var grades []CourseGrades
+	for rows.Next() {
+		var c Course
+		var assessmentId *int
+		var title *string
+		var weight, maxScore, score *float64
+		err := rows.Scan(&c.Id, &c.Code, &c.Title, &c.Description, &c.Capacity, &c.StartDate.Time, &c.EndDate.Time,
+			&assessmentId, &title, &weight, &maxScore, &score)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(grades) == 0 || grades[len(grades)-1].Course.Id != c.Id {
+			grades = append(grades, CourseGrades{Course: c, Assessments: []GradedAssessment{}})
+		}
+		if assessmentId == nil {
+			continue
+		}
+		last := &grades[len(grades)-1]
+		last.Assessments = append(last.Assessments, GradedAssessment{
+			Assessment: Assessment{Id: *assessmentId, CourseId: c.Id, Title: *title, Weight: *weight, MaxScore: *maxScore},
+			Score:      score,
+		})
+	}
+	return grades, rows.Err()
+}
@@ -2,15 +2,34 @@ package student
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"iter"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostgresDataStore struct {
-	Pool *pgxpool.Pool
+	Pool                *pgxpool.Pool
+	Revisions           RevisionPolicy
+	EnrolledStudents    EnrolledStudentPolicy
+	AttendanceThreshold AttendanceThreshold
+}
+
+// pgQuerier is what the pool and a transaction have in common, for queries that run either on their own or as part of
+// a larger transaction.
+type pgQuerier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
 func NewPostgresDataStore() *PostgresDataStore {
@@ -23,75 +42,1071 @@ func NewPostgresDataStore() *PostgresDataStore {
 		log.Fatalf("unable to connect to database: %v", err)
 	}
 
-	return &PostgresDataStore{Pool: pool}
+	return &PostgresDataStore{
+		Pool:                pool,
+		Revisions:           NewRevisionPolicy(),
+		EnrolledStudents:    NewEnrolledStudentPolicy(),
+		AttendanceThreshold: NewAttendanceThreshold(),
+	}
 }
 
-func (p *PostgresDataStore) CreateStudent(s Student) error {
-	query := `INSERT INTO students (name, age) values ($1, $2)`
-	_, err := p.Pool.Exec(context.Background(), query, s.Name, s.Age)
+func (p *PostgresDataStore) CreateStudent(ctx context.Context, s Student) error {
+	tx, err := p.Pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO students (name, age) values ($1, $2) RETURNING id`
+	if err := tx.QueryRow(ctx, query, s.Name, s.Age).Scan(&s.Id); err != nil {
+		return err
+	}
+
+	if err := p.recordMutation(ctx, tx, newMutation(ctx, AuditStudentCreated, s.Id, nil, &s)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (p *PostgresDataStore) GetStudent(studentId int) (*Student, error) {
-	query := `SELECT * FROM students where id = $1`
-	row := p.Pool.QueryRow(context.Background(), query, studentId)
+func (p *PostgresDataStore) GetStudent(ctx context.Context, studentId int) (*Student, error) {
+	query := `SELECT ` + studentColumns + ` FROM students where id = $1 AND deleted_at IS NULL`
+	row := p.Pool.QueryRow(ctx, query, studentId)
+
+	return scanPgStudent(row)
+}
 
-	var id, age int
-	var name string
-	if err := row.Scan(&id, &name, &age); err != nil {
+// GetStudents reads the students in a single query.
+func (p *PostgresDataStore) GetStudents(ctx context.Context, ids []int) ([]Student, error) {
+	query := `SELECT ` + studentColumns + ` FROM students WHERE id = ANY($1) AND deleted_at IS NULL ORDER BY id`
+	rows, err := p.Pool.Query(ctx, query, ids)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	student := &Student{id, name, age}
-	return student, nil
+	var students []Student
+	for rows.Next() {
+		student, err := scanPgStudent(rows)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, *student)
+	}
+	return students, rows.Err()
 }
 
-func (p *PostgresDataStore) UpdateStudent(id int, s Student) error {
+func (p *PostgresDataStore) UpdateStudent(ctx context.Context, id int, s Student) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := p.lockStudent(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE students set name = $1, age = $2 WHERE id = $3`
-	cTag, err := p.Pool.Exec(context.Background(), query, s.Name, s.Age, id)
+	if _, err := tx.Exec(ctx, query, s.Name, s.Age, id); err != nil {
+		return err
+	}
+
+	after := &Student{Id: id, Name: s.Name, Age: s.Age}
+	if err := p.recordMutation(ctx, tx, newMutation(ctx, AuditStudentUpdated, id, before, after)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) DeleteStudent(ctx context.Context, id int) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := p.lockStudent(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := p.releasePgEnrollments(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// students are only soft deleted, PurgeStudents gets rid of them for good once they've been in the trash for long
+	// enough.
+	query := `UPDATE students SET deleted_at = now() WHERE id = $1 RETURNING ` + studentColumns
+	deleted, err := scanPgStudent(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		return err
+	}
+
+	m := newMutation(ctx, AuditStudentDeleted, id, before, nil)
+	m.state = deleted
+	if err := p.recordMutation(ctx, tx, m); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) ListStudents(ctx context.Context, opts ListOptions) ([]Student, error) {
+	return listPgStudents(ctx, p.Pool, opts)
+}
+
+// StreamStudents reads the students off the cursor as they're iterated over.
+func (p *PostgresDataStore) StreamStudents(ctx context.Context, opts ListOptions) iter.Seq2[Student, error] {
+	return func(yield func(Student, error) bool) {
+		query, args := pgListQuery(opts)
+		rows, err := p.Pool.Query(ctx, query, args...)
+		if err != nil {
+			yield(Student{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			student, err := scanStudentFields(rows, opts.Fields)
+			if err != nil {
+				yield(Student{}, err)
+				return
+			}
+			if !yield(*student, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(Student{}, err)
+		}
+	}
+}
+
+// ListStudentsVersioned reads the students and the latest event id from the same snapshot. Outbox events commit in id
+// order, so the snapshot reflects exactly the events up to that id.
+func (p *PostgresDataStore) ListStudentsVersioned(ctx context.Context, opts ListOptions) ([]Student, int64, error) {
+	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var resourceVersion int64
+	if err := tx.QueryRow(ctx, latestEventIdQuery).Scan(&resourceVersion); err != nil {
+		return nil, 0, err
+	}
+
+	students, err := listPgStudents(ctx, tx, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return students, resourceVersion, tx.Commit(ctx)
+}
+
+func pgListQuery(opts ListOptions) (string, []any) {
+	columns := studentColumnsFor(opts.Fields)
+	query := `SELECT ` + columns + ` FROM students WHERE deleted_at IS NULL`
+	if opts.OnlyDeleted {
+		query = `SELECT ` + columns + ` FROM students WHERE deleted_at IS NOT NULL`
+	}
+
+	var args []any
+	if opts.AfterId > 0 {
+		args = append(args, opts.AfterId)
+		query += fmt.Sprintf(` AND id > $%d`, len(args))
+	}
+	query += ` ORDER BY id`
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	return query, args
+}
+
+func listPgStudents(ctx context.Context, q pgQuerier, opts ListOptions) ([]Student, error) {
+	query, args := pgListQuery(opts)
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	// 	TODO: find out why do I need to close rows?
+
+	var students []Student
+	for rows.Next() {
+		student, err := scanStudentFields(rows, opts.Fields)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, *student)
+	}
+	return students, rows.Err()
+}
+
+// pgSearchQuery matches students on the words of their names, and on trigrams of those words so that typos still
+// match. students_search_idx and students_name_trgm_idx serve both.
+const pgSearchQuery = `WITH matches AS (
+	SELECT ` + studentColumns + `,
+		(ts_rank(search, websearch_to_tsquery('simple', $1)) + word_similarity($1, name))::float8 AS rank,
+		ts_headline('simple', name, websearch_to_tsquery('simple', $1), E'StartSel=\x02, StopSel=\x03') AS snippet
+	FROM students
+	WHERE deleted_at IS NULL AND (search @@ websearch_to_tsquery('simple', $1) OR $1 <% name)
+)
+SELECT ` + studentColumns + `, rank, snippet FROM matches`
+
+// SearchStudents ranks full-text matches by ts_rank, and adds how similar the name is to the query, so that closer
+// spellings come first.
+func (p *PostgresDataStore) SearchStudents(ctx context.Context, q string, opts ListOptions) ([]SearchResult, error) {
+	query := pgSearchQuery
+	args := []any{q}
+	if opts.AfterId > 0 {
+		args = append(args, opts.AfterId)
+		query += fmt.Sprintf(` WHERE (-rank, id) > (SELECT -rank, id FROM matches WHERE id = $%d)`, len(args))
+	}
+	query += ` ORDER BY rank DESC, id`
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := p.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		err := rows.Scan(&r.Student.Id, &r.Student.Name, &r.Student.Age, &r.Student.DeletedAt, &r.Rank, &r.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		r.Snippet = markSnippet(r.Snippet, r.Student.Name)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// StudentStats reads the summary and the histogram in one snapshot, so that they agree. width_bucket numbers the
+// buckets as newAgeHistogram does.
+func (p *PostgresDataStore) StudentStats(ctx context.Context, ageBuckets []int) (*StudentStats, error) {
+	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	stats := StudentStats{AgeHistogram: newAgeHistogram(ageBuckets)}
+	query := `SELECT count(*), min(age), max(age), avg(age)::float8, percentile_cont(0.5) WITHIN GROUP (ORDER BY age)
+		FROM students WHERE deleted_at IS NULL`
+	err = tx.QueryRow(ctx, query).Scan(&stats.Count, &stats.MinAge, &stats.MaxAge, &stats.MeanAge, &stats.MedianAge)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `SELECT width_bucket(age, $1::int[]), count(*) FROM students
+		WHERE deleted_at IS NULL AND age IS NOT NULL GROUP BY 1`
+	rows, err := tx.Query(ctx, query, ageBuckets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		stats.AgeHistogram[bucket].Count = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &stats, tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) RestoreStudent(ctx context.Context, id int) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE students SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + studentColumns
+	after, err := scanPgStudent(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New(errStudentNotFound)
+		}
+		return err
+	}
+
+	if err := p.recordMutation(ctx, tx, newMutation(ctx, AuditStudentRestored, id, nil, after)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) PurgeStudents(ctx context.Context, deletedBefore time.Time) (int, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `DELETE FROM students WHERE deleted_at < $1 RETURNING ` + studentColumns
+	rows, err := tx.Query(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged []*Student
+	for rows.Next() {
+		student, err := scanPgStudent(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		purged = append(purged, student)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, student := range purged {
+		if err := p.recordMutation(ctx, tx, newMutation(ctx, AuditStudentPurged, student.Id, student, nil)); err != nil {
+			return 0, err
+		}
+	}
+	return len(purged), tx.Commit(ctx)
+}
+
+func scanPgStudent(row pgx.Row) (*Student, error) {
+	var s Student
+	if err := row.Scan(&s.Id, &s.Name, &s.Age, &s.DeletedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// lockStudent reads a student inside tx and locks its row until the transaction ends, so the snapshot recorded for a
+// mutation is the one it was actually applied to.
+func (p *PostgresDataStore) lockStudent(ctx context.Context, tx pgx.Tx, id int) (*Student, error) {
+	query := `SELECT ` + studentColumns + ` FROM students WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+
+	s, err := scanPgStudent(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errStudentNotFound)
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// recordMutation writes everything that has to be committed together with a change to a student.
+func (p *PostgresDataStore) recordMutation(ctx context.Context, tx pgx.Tx, m mutation) error {
+	e, err := m.auditEvent()
+	if err != nil {
+		return err
+	}
+	if err := p.insertAuditEvent(ctx, tx, e); err != nil {
+		return err
+	}
+	if err := p.recordRevision(ctx, tx, m); err != nil {
+		return err
+	}
+	return p.insertOutboxEvent(ctx, tx, m)
+}
+
+// outboxLockKey is the advisory lock that orders outbox inserts.
+const outboxLockKey = 0x6f75_7462_6f78
+
+// insertOutboxEvent writes the event for m, and notifies listeners once the transaction commits. Event ids come from a
+// sequence, which hands them out in insert order rather than commit order; holding a lock until commit makes the two
+// the same, so that readers following the outbox by id never skip an event that commits late.
+func (p *PostgresDataStore) insertOutboxEvent(ctx context.Context, tx pgx.Tx, m mutation) error {
+	e, payload, err := m.event()
+	if err != nil {
+		return err
+	}
+	return p.insertEvent(ctx, tx, e, payload)
+}
+
+// insertEvent writes e to the outbox, for events that come with no mutation, like alerts.
+func (p *PostgresDataStore) insertEvent(ctx context.Context, tx pgx.Tx, e Event, payload []byte) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, outboxLockKey); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO outbox_events (student_id, type, payload) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, e.StudentId, e.Type, payload); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `SELECT pg_notify($1, '')`, outboxChannel)
+	return err
+}
+
+// outboxChannel is the channel new outbox events are announced on.
+const outboxChannel = "outbox_events"
+
+// ListenForEvents calls notify whenever any replica commits a change event, until ctx is done.
+func (p *PostgresDataStore) ListenForEvents(ctx context.Context, notify func(), logger *slog.Logger) {
+	for ctx.Err() == nil {
+		if err := p.listen(ctx, notify); err != nil && ctx.Err() == nil {
+			logger.Error("error listening for events, reconnecting", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}
+}
+
+func (p *PostgresDataStore) listen(ctx context.Context, notify func()) error {
+	conn, err := p.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+outboxChannel); err != nil {
+		return err
+	}
+	// events may have been missed while not listening.
+	notify()
+
+	for {
+		if _, err := conn.Conn().WaitForNotification(ctx); err != nil {
+			return err
+		}
+		notify()
+	}
+}
+
+func (p *PostgresDataStore) EventsAfter(ctx context.Context, afterId int64, limit int) ([]Event, error) {
+	query := `SELECT id, payload FROM outbox_events WHERE id > $1 ORDER BY id LIMIT $2`
+	return p.queryEvents(ctx, query, afterId, limit)
+}
+
+// latestEventIdQuery reads the newest event id. Once the outbox has been compacted, that may only be remembered as how
+// far it was compacted.
+const latestEventIdQuery = `SELECT GREATEST(
+	(SELECT COALESCE(MAX(id), 0) FROM outbox_events),
+	(SELECT COALESCE(MAX(compacted_through), 0) FROM outbox_state))`
+
+func (p *PostgresDataStore) LatestEventId(ctx context.Context) (int64, error) {
+	var id int64
+	err := p.Pool.QueryRow(ctx, latestEventIdQuery).Scan(&id)
+	return id, err
+}
+
+func (p *PostgresDataStore) PendingEvents(ctx context.Context, limit int, skip []int) ([]Event, error) {
+	// a nil slice would be a NULL array, which no student id is distinct from.
+	if skip == nil {
+		skip = []int{}
+	}
+
+	query := `SELECT id, payload FROM outbox_events WHERE dispatched_at IS NULL AND NOT (student_id = ANY($2))
+		ORDER BY id LIMIT $1`
+	return p.queryEvents(ctx, query, limit, skip)
+}
+
+func (p *PostgresDataStore) queryEvents(ctx context.Context, query string, args ...any) ([]Event, error) {
+	rows, err := p.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var id int64
+		var payload []byte
+		if err := rows.Scan(&id, &payload); err != nil {
+			return nil, err
+		}
+
+		e, err := decodeEvent(id, payload)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (p *PostgresDataStore) MarkEventsDispatched(ctx context.Context, ids []int64) error {
+	query := `UPDATE outbox_events SET dispatched_at = now() WHERE id = ANY($1)`
+	_, err := p.Pool.Exec(ctx, query, ids)
+	return err
+}
+
+// DeleteDispatchedEvents compacts the outbox, and remembers how far it got so that watches can tell when they've
+// missed events.
+func (p *PostgresDataStore) DeleteDispatchedEvents(ctx context.Context, before time.Time) (int, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `WITH deleted AS (DELETE FROM outbox_events WHERE dispatched_at < $1 RETURNING id)
+		SELECT COUNT(*), COALESCE(MAX(id), 0) FROM deleted`
+	var count int
+	var compacted int64
+	if err := tx.QueryRow(ctx, query, before).Scan(&count, &compacted); err != nil {
+		return 0, err
+	}
+
+	query = `UPDATE outbox_state SET compacted_through = GREATEST(compacted_through, $1)`
+	if _, err := tx.Exec(ctx, query, compacted); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) CompactedEventId(ctx context.Context) (int64, error) {
+	var id int64
+	err := p.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(compacted_through), 0) FROM outbox_state`).Scan(&id)
+	return id, err
+}
+
+// recordRevision appends the state m left the student in to its history, and prunes the history according to the
+// retention policy. A student that's gone for good takes its history with it.
+func (p *PostgresDataStore) recordRevision(ctx context.Context, tx pgx.Tx, m mutation) error {
+	snapshot := m.revisionSnapshot()
+	if snapshot == nil {
+		_, err := tx.Exec(ctx, `DELETE FROM student_revisions WHERE student_id = $1`, m.studentId)
+		return err
+	}
+
+	query := `INSERT INTO student_revisions (student_id, revision, action, actor, name, age, deleted_at)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6 FROM student_revisions WHERE student_id = $1
+		RETURNING revision`
+	var revision int
+	err := tx.QueryRow(ctx, query, m.studentId, m.action, m.actor, snapshot.Name, snapshot.Age, snapshot.DeletedAt).
+		Scan(&revision)
+	if err != nil {
+		return err
+	}
+
+	if p.Revisions.MaxRevisions > 0 {
+		query := `DELETE FROM student_revisions WHERE student_id = $1 AND revision <= $2`
+		if _, err := tx.Exec(ctx, query, m.studentId, revision-p.Revisions.MaxRevisions); err != nil {
+			return err
+		}
+	}
+	if p.Revisions.MaxAge > 0 {
+		query := `DELETE FROM student_revisions WHERE student_id = $1 AND revision < $2 AND created_at < $3`
+		if _, err := tx.Exec(ctx, query, m.studentId, revision, time.Now().Add(-p.Revisions.MaxAge)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PostgresDataStore) ListRevisions(ctx context.Context, studentId int) ([]Revision, error) {
+	query := `SELECT student_id, revision, action, actor, name, age, deleted_at, created_at FROM student_revisions
+		WHERE student_id = $1 ORDER BY revision`
+	rows, err := p.Pool.Query(ctx, query, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		rev, err := scanPgRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (p *PostgresDataStore) ListRevisionsOf(ctx context.Context, studentIds []int) (map[int][]Revision, error) {
+	query := `SELECT student_id, revision, action, actor, name, age, deleted_at, created_at FROM student_revisions
+		WHERE student_id = ANY($1) ORDER BY student_id, revision`
+	rows, err := p.Pool.Query(ctx, query, studentIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make(map[int][]Revision)
+	for rows.Next() {
+		rev, err := scanPgRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions[rev.StudentId] = append(revisions[rev.StudentId], *rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (p *PostgresDataStore) GetStudentAsOf(ctx context.Context, studentId int, asOf time.Time) (*Student, error) {
+	query := `SELECT student_id, revision, action, actor, name, age, deleted_at, created_at FROM student_revisions
+		WHERE student_id = $1 AND created_at <= $2 ORDER BY revision DESC LIMIT 1`
+	rev, err := scanPgRevision(p.Pool.QueryRow(ctx, query, studentId, asOf))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errStudentNotFound)
+		}
+		return nil, err
+	}
+
+	// the student existed, but it had already been deleted by then.
+	if rev.Student.DeletedAt != nil {
+		return nil, errors.New(errStudentNotFound)
+	}
+	return &rev.Student, nil
+}
+
+func (p *PostgresDataStore) RevertStudent(ctx context.Context, studentId, revision int, trash bool) (*Student, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// unlike other mutations, reverts apply to deleted students too — reverting to a revision from before the delete
+	// brings the student back.
+	query := `SELECT ` + studentColumns + ` FROM students WHERE id = $1 FOR UPDATE`
+	before, err := scanPgStudent(tx.QueryRow(ctx, query, studentId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errStudentNotFound)
+		}
+		return nil, err
+	}
+
+	query = `SELECT student_id, revision, action, actor, name, age, deleted_at, created_at FROM student_revisions
+		WHERE student_id = $1 AND revision = $2`
+	rev, err := scanPgRevision(tx.QueryRow(ctx, query, studentId, revision))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errRevisionNotFound)
+		}
+		return nil, err
+	}
+
+	deleted := rev.Student.DeletedAt != nil
+	if deleted != (before.DeletedAt != nil) && !trash {
+		return nil, errors.New(errRevertMovesTrash)
+	}
+	// reverting into the trash deletes the student, so its enrollments go the way DeleteStudent sends them.
+	if deleted && before.DeletedAt == nil {
+		if err := p.releasePgEnrollments(ctx, tx, studentId); err != nil {
+			return nil, err
+		}
+	}
+
+	// a student reverted into the trash is deleted now, not when the revision was, so PurgeStudents gives it the full
+	// retention period.
+	query = `UPDATE students SET name = $1, age = $2,
+		deleted_at = CASE WHEN $3 THEN COALESCE(deleted_at, now()) END
+		WHERE id = $4 RETURNING ` + studentColumns
+	after, err := scanPgStudent(tx.QueryRow(ctx, query, rev.Student.Name, rev.Student.Age, deleted, studentId))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.recordMutation(ctx, tx, newMutation(ctx, AuditStudentReverted, studentId, before, after)); err != nil {
+		return nil, err
+	}
+	return after, tx.Commit(ctx)
+}
+
+func scanPgRevision(row pgx.Row) (*Revision, error) {
+	var r Revision
+	err := row.Scan(&r.StudentId, &r.Revision, &r.Action, &r.Actor, &r.Student.Name, &r.Student.Age,
+		&r.Student.DeletedAt, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.Student.Id = r.StudentId
+	return &r, nil
+}
+
+func (p *PostgresDataStore) insertAuditEvent(ctx context.Context, q pgQuerier, e AuditEvent) error {
+	query := `INSERT INTO audit_events (actor, action, student_id, before, after, metadata, request_id)
+		values ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := q.Exec(ctx, query, e.Actor, e.Action, e.StudentId, nullableJSON(e.Before), nullableJSON(e.After),
+		nullableJSON(e.Metadata), e.RequestId)
+	return err
+}
+
+func (p *PostgresDataStore) RecordAuditEvent(ctx context.Context, e AuditEvent) error {
+	return p.insertAuditEvent(ctx, p.Pool, e)
+}
+
+func (p *PostgresDataStore) ListAuditEvents(ctx context.Context, f AuditFilter) ([]AuditEvent, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.StudentId != nil {
+		where("student_id = $%d", *f.StudentId)
+	}
+	if f.Actor != "" {
+		where("actor = $%d", f.Actor)
+	}
+	if f.Action != "" {
+		where("action = $%d", f.Action)
+	}
+	if f.Since != nil {
+		where("created_at >= $%d", *f.Since)
+	}
+	if f.Until != nil {
+		where("created_at < $%d", *f.Until)
+	}
+	if f.BeforeId > 0 {
+		where("id < $%d", f.BeforeId)
+	}
+
+	query := `SELECT id, actor, action, student_id, before, after, metadata, request_id, created_at FROM audit_events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, auditLimit(f.Limit))
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := p.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		var before, after, metadata []byte
+		err := rows.Scan(&e.Id, &e.Actor, &e.Action, &e.StudentId, &before, &after, &metadata, &e.RequestId, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Before, e.After, e.Metadata = before, after, metadata
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (p *PostgresDataStore) ReserveIdempotencyKey(ctx context.Context, r IdempotencyRecord) (*IdempotencyRecord, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `DELETE FROM idempotency_keys WHERE principal = $1 AND key = $2 AND expires_at < now()`
+	if _, err := tx.Exec(ctx, query, r.Principal, r.Key); err != nil {
+		return nil, err
+	}
+
+	query = `INSERT INTO idempotency_keys (principal, key, fingerprint, expires_at) values ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`
+	cTag, err := tx.Exec(ctx, query, r.Principal, r.Key, r.Fingerprint, r.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if cTag.RowsAffected() == 1 {
+		return nil, tx.Commit(ctx)
+	}
+
+	query = `SELECT principal, key, fingerprint, status, headers, body, expires_at FROM idempotency_keys
+		WHERE principal = $1 AND key = $2`
+	var existing IdempotencyRecord
+	var headers []byte
+	err = tx.QueryRow(ctx, query, r.Principal, r.Key).Scan(&existing.Principal, &existing.Key, &existing.Fingerprint,
+		&existing.Status, &headers, &existing.Body, &existing.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &existing.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &existing, tx.Commit(ctx)
+}
+
+func (p *PostgresDataStore) CompleteIdempotencyKey(ctx context.Context, r IdempotencyRecord) error {
+	headers, err := json.Marshal(r.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE idempotency_keys SET status = $1, headers = $2, body = $3 WHERE principal = $4 AND key = $5`
+	_, err = p.Pool.Exec(ctx, query, r.Status, string(headers), r.Body, r.Principal, r.Key)
+	return err
+}
+
+func (p *PostgresDataStore) ReleaseIdempotencyKey(ctx context.Context, principal, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE principal = $1 AND key = $2`
+	_, err := p.Pool.Exec(ctx, query, principal, key)
+	return err
+}
+
+func (p *PostgresDataStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at < $1`
+	cTag, err := p.Pool.Exec(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+	return int(cTag.RowsAffected()), nil
+}
+
+func (p *PostgresDataStore) CreateAPIKey(ctx context.Context, k APIKey) (int, error) {
+	query := `INSERT INTO api_keys (name, prefix, key_hash, scopes) values ($1, $2, $3, $4) RETURNING id`
+	var id int
+	err := p.Pool.QueryRow(ctx, query, k.Name, k.Prefix, k.Hash, k.Scopes).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (p *PostgresDataStore) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	query := `SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE key_hash = $1`
+	row := p.Pool.QueryRow(ctx, query, hash)
+
+	var k APIKey
+	if err := row.Scan(&k.Id, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errAPIKeyNotFound)
+		}
+		return nil, err
+	}
+	k.Hash = hash
+	return &k, nil
+}
+
+func (p *PostgresDataStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	query := `SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys ORDER BY id`
+	rows, err := p.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.Id, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (p *PostgresDataStore) RevokeAPIKey(ctx context.Context, id int) error {
+	query := `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+	cTag, err := p.Pool.Exec(ctx, query, id)
 	if err != nil {
 		return err
 	}
 
 	if cTag.RowsAffected() == 0 {
-		return errors.New(errStudentNotFound)
+		return errors.New(errAPIKeyNotFound)
 	}
 	return nil
 }
 
-func (p *PostgresDataStore) DeleteStudent(id int) error {
-	query := `DELETE from students where id = $1`
-	cTag, err := p.Pool.Exec(context.Background(), query, id)
+func (p *PostgresDataStore) TouchAPIKey(ctx context.Context, id int) error {
+	query := `UPDATE api_keys SET last_used_at = now() WHERE id = $1`
+	_, err := p.Pool.Exec(ctx, query, id)
+	return err
+}
+
+func (p *PostgresDataStore) CreateWebhook(ctx context.Context, wh Webhook) (int, error) {
+	query := `INSERT INTO webhooks (url, events, secret) VALUES ($1, $2, $3) RETURNING id`
+	var id int
+	err := p.Pool.QueryRow(ctx, query, wh.URL, wh.Events, wh.Secret).Scan(&id)
+	return id, err
+}
+
+func (p *PostgresDataStore) GetWebhook(ctx context.Context, id int) (*Webhook, error) {
+	query := `SELECT id, url, events, secret, created_at FROM webhooks WHERE id = $1`
+	var wh Webhook
+	err := p.Pool.QueryRow(ctx, query, id).Scan(&wh.Id, &wh.URL, &wh.Events, &wh.Secret, &wh.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(errWebhookNotFound)
+		}
+		return nil, err
+	}
+	return &wh, nil
+}
+
+func (p *PostgresDataStore) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	query := `SELECT id, url, events, secret, created_at FROM webhooks ORDER BY id`
+	rows, err := p.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []Webhook
+	for rows.Next() {
+		var wh Webhook
+		if err := rows.Scan(&wh.Id, &wh.URL, &wh.Events, &wh.Secret, &wh.CreatedAt); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, wh)
+	}
+	return webhooks, rows.Err()
+}
+
+func (p *PostgresDataStore) DeleteWebhook(ctx context.Context, id int) error {
+	cTag, err := p.Pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
 	if err != nil {
 		return err
 	}
+	if cTag.RowsAffected() == 0 {
+		return errors.New(errWebhookNotFound)
+	}
+	return nil
+}
+
+func (p *PostgresDataStore) EnqueueWebhookDeliveries(ctx context.Context, e Event, payload []byte) error {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM webhooks WHERE $2 = ANY(events)
+		ON CONFLICT DO NOTHING`
+	_, err := p.Pool.Exec(ctx, query, e.Id, e.Type, payload)
+	return err
+}
+
+// webhookDeliveryColumns is the column list both stores select deliveries with, in the order they're scanned in.
+const webhookDeliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	response_status, last_error, created_at, delivered_at`
+
+func (p *PostgresDataStore) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY id LIMIT $2`
+	return p.queryWebhookDeliveries(ctx, query, now, limit)
+}
 
+func (p *PostgresDataStore) RecordWebhookAttempt(ctx context.Context, d WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, response_status = $4,
+		last_error = $5, delivered_at = $6 WHERE id = $7`
+	_, err := p.Pool.Exec(ctx, query, d.Status, d.Attempts, d.NextAttemptAt, d.ResponseStatus, d.LastError,
+		d.DeliveredAt, d.Id)
+	return err
+}
+
+func (p *PostgresDataStore) ListWebhookDeliveries(ctx context.Context, webhookId int) ([]WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC`
+	return p.queryWebhookDeliveries(ctx, query, webhookId)
+}
+
+func (p *PostgresDataStore) ListDeadWebhookDeliveries(ctx context.Context) ([]WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE status = 'dead' ORDER BY id DESC`
+	return p.queryWebhookDeliveries(ctx, query)
+}
+
+func (p *PostgresDataStore) RetryWebhookDelivery(ctx context.Context, webhookId int, deliveryId int64) error {
+	query := `UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND webhook_id = $2`
+	cTag, err := p.Pool.Exec(ctx, query, deliveryId, webhookId)
+	if err != nil {
+		return err
+	}
 	if cTag.RowsAffected() == 0 {
-		return errors.New(errStudentNotFound)
+		return errors.New(errWebhookDeliveryNotFound)
 	}
 	return nil
 }
 
-func (p *PostgresDataStore) ListStudents() ([]Student, error) {
-	query := `SELECT * FROM students`
-	rows, err := p.Pool.Query(context.Background(), query)
+func (p *PostgresDataStore) queryWebhookDeliveries(ctx context.Context, query string, args ...any) ([]WebhookDelivery, error) {
+	rows, err := p.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	// 	TODO: find out why do I need to close rows?
 
-	var students []Student
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(&d.Id, &d.WebhookId, &d.EventId, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+type pgStudentImport struct {
+	p  *PostgresDataStore
+	tx pgx.Tx
+}
+
+func (p *PostgresDataStore) BeginImport(ctx context.Context) (StudentImport, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pgStudentImport{p: p, tx: tx}, nil
+}
+
+// Insert copies the students in. Their ids are taken from the sequence up front, since COPY can't return them.
+func (i *pgStudentImport) Insert(ctx context.Context, students []Student) ([]Student, error) {
+	query := `SELECT nextval(pg_get_serial_sequence('students', 'id')) FROM generate_series(1, $1)`
+	rows, err := i.tx.Query(ctx, query, len(students))
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]Student, 0, len(students))
 	for rows.Next() {
-		var id, age int
-		var name string
-		if err := rows.Scan(&id, &name, &age); err != nil {
+		s := students[len(created)]
+		if err := rows.Scan(&s.Id); err != nil {
+			rows.Close()
 			return nil, err
 		}
-		students = append(students, Student{id, name, age})
+		created = append(created, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = i.tx.CopyFrom(ctx, pgx.Identifier{"students"}, []string{"id", "name", "age"},
+		pgx.CopyFromSlice(len(created), func(n int) ([]any, error) {
+			return []any{created[n].Id, created[n].Name, created[n].Age}, nil
+		}))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range created {
+		if err := i.p.recordMutation(ctx, i.tx, newMutation(ctx, AuditStudentCreated, s.Id, nil, &s)); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+func (i *pgStudentImport) Commit(ctx context.Context) error {
+	return i.tx.Commit(ctx)
+}
+
+func (i *pgStudentImport) Rollback(ctx context.Context) error {
+	err := i.tx.Rollback(ctx)
+	if errors.Is(err, pgx.ErrTxClosed) {
+		return nil
 	}
-	return students, nil
+	return err
 }
@@ -0,0 +1,203 @@
+package student
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// permissions, which API keys carry directly as scopes and roles grant through the policy.
+const (
+	ScopeStudentsRead    = "students:read"
+	ScopeStudentsWrite   = "students:write"
+	ScopeStudentsDelete  = "students:delete"
+	ScopeStudentsBulk    = "students:bulk"
+	ScopeAuditRead       = "audit:read"
+	ScopeWebhooksManage  = "webhooks:manage"
+	ScopeCoursesRead     = "courses:read"
+	ScopeCoursesWrite    = "courses:write"
+	ScopeGradesRead      = "grades:read"
+	ScopeGradesWrite     = "grades:write"
+	ScopeAttendanceRead  = "attendance:read"
+	ScopeAttendanceWrite = "attendance:write"
+)
+
+//go:embed rbac-policy.json
+var defaultPolicy []byte
+
+// Policy is the declarative access control configuration. Roles grant permissions, and every route registered on the
+// mux maps to the single permission needed to call it. Routes the policy doesn't mention are denied.
+type Policy struct {
+	// Roles maps a role (as carried by a bearer token) to the permissions it grants.
+	Roles map[string][]string `json:"roles"`
+	// Permissions maps "METHOD pattern", with the pattern exactly as registered on the mux, to a permission.
+	Permissions map[string]string `json:"permissions"`
+}
+
+// NewPolicy loads the policy from the file named by RBAC_POLICY_FILE, falling back to DefaultPolicy.
+func NewPolicy() *Policy {
+	path := os.Getenv(rbacPolicyFile)
+	if path == "" {
+		return DefaultPolicy()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("unable to read rbac policy: %v", err)
+	}
+
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		log.Fatalf("invalid rbac policy: %v", err)
+	}
+	return policy
+}
+
+// DefaultPolicy is the built-in policy: viewers can read, editors can also create and update, admins can also delete,
+// bulk-operate, read the audit log and manage webhooks. Editors manage courses and record grades and attendance, which
+// viewers can read.
+var DefaultPolicy = sync.OnceValue(func() *Policy {
+	policy, err := ParsePolicy(defaultPolicy)
+	if err != nil {
+		panic(fmt.Sprintf("built-in rbac policy is invalid: %v", err))
+	}
+	return policy
+})
+
+func ParsePolicy(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+
+	for route := range p.Permissions {
+		method, pattern, ok := strings.Cut(route, " ")
+		if !ok || method == "" || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("route %q should look like \"METHOD /path\"", route)
+		}
+	}
+	return &p, nil
+}
+
+// RequiredPermission returns the permission needed for method on the mux pattern, if the policy covers the route.
+func (p *Policy) RequiredPermission(method, pattern string) (string, bool) {
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	permission, ok := p.Permissions[method+" "+pattern]
+	return permission, ok
+}
+
+// Grants reports whether principal holds permission, either directly as a scope or through one of its roles.
+func (p *Policy) Grants(principal *Principal, permission string) bool {
+	if principal.HasScope(permission) {
+		return true
+	}
+	for _, role := range principal.Roles {
+		if slices.Contains(p.Roles[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// policy is the access policy in force, the built-in one unless another was configured.
+func (s *Server) policy() *Policy {
+	if s.Policy == nil {
+		return DefaultPolicy()
+	}
+	return s.Policy
+}
+
+// Authorize enforces the policy on requests that made it through Authenticate. It has to sit behind the mux, since
+// routes are matched on the pattern the request was routed by.
+func (s *Server) Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := s.policy()
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok {
+			RespondWithError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		permission, ok := policy.RequiredPermission(r.Method, r.Pattern)
+		if !ok {
+			s.deny(w, r, principal, "", "route is not covered by the access policy")
+			return
+		}
+
+		if !policy.Grants(principal, permission) {
+			s.deny(w, r, principal, permission, fmt.Sprintf("missing permission %q", permission))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// require checks a permission that depends on more than the route — a query parameter, say — from inside a handler,
+// denying the request if the caller doesn't hold it.
+func (s *Server) require(w http.ResponseWriter, r *http.Request, permission string) bool {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	if !s.policy().Grants(principal, permission) {
+		s.deny(w, r, principal, permission, fmt.Sprintf("missing permission %q", permission))
+		return false
+	}
+	return true
+}
+
+// deny responds with a structured 403 and leaves an audit trail of the attempt.
+func (s *Server) deny(w http.ResponseWriter, r *http.Request, principal *Principal, permission, reason string) {
+	s.recordDenial(r.Context(), principal, permission, r.Method+" "+r.URL.Path, reason)
+
+	body := ErrorResponse{Error: "forbidden", Message: reason}
+	if permission != "" {
+		body.Details = map[string]any{"permission": permission}
+	}
+	RespondWithJSONError(w, http.StatusForbidden, body)
+}
+
+// recordDenial logs and audits a request that was denied, whether over HTTP or gRPC.
+func (s *Server) recordDenial(ctx context.Context, principal *Principal, permission, request, reason string) {
+	s.Logger.Warn("access denied",
+		slog.Group("audit",
+			"action", "access.denied",
+			"subject", principal.Subject,
+			"method", principal.Method,
+			"roles", principal.Roles,
+			"permission", permission,
+			"request", request,
+			"reason", reason,
+		),
+	)
+
+	if s.Audit != nil {
+		metadata, _ := json.Marshal(map[string]any{
+			"permission": permission,
+			"request":    request,
+			"reason":     reason,
+		})
+		e := AuditEvent{
+			Actor:     principal.Subject,
+			Action:    AuditAccessDenied,
+			Metadata:  metadata,
+			RequestId: RequestIdFromContext(ctx),
+		}
+		if err := s.Audit.RecordAuditEvent(ctx, e); err != nil {
+			s.Logger.Error("error recording access denial", "error", err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package student
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const AuditStudentReverted = "student.reverted"
+
+// Revision is a version of a student, as it was left by a mutation. Revisions are numbered from 1 per student.
+type Revision struct {
+	StudentId int       `json:"student_id" xml:"student_id" yaml:"student_id" msgpack:"student_id"`
+	Revision  int       `json:"revision" xml:"revision" yaml:"revision" msgpack:"revision"`
+	Action    string    `json:"action" xml:"action" yaml:"action" msgpack:"action"`
+	Actor     string    `json:"actor" xml:"actor" yaml:"actor" msgpack:"actor"`
+	Student   Student   `json:"student" xml:"student" yaml:"student" msgpack:"student"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at" yaml:"created_at" msgpack:"created_at"`
+}
+
+// RevisionPolicy decides how much history is kept. A zero value keeps everything. The latest revision of a student is
+// always kept, whatever the policy.
+type RevisionPolicy struct {
+	// MaxRevisions is how many revisions are kept per student.
+	MaxRevisions int
+	// MaxAge is how long revisions are kept for.
+	MaxAge time.Duration
+}
+
+// NewRevisionPolicy reads the policy from REVISION_RETENTION_COUNT and REVISION_RETENTION_AGE.
+func NewRevisionPolicy() RevisionPolicy {
+	var policy RevisionPolicy
+
+	if v := os.Getenv(revisionRetentionCount); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Fatalf("invalid %q: %q", revisionRetentionCount, v)
+		}
+		policy.MaxRevisions = n
+	}
+
+	if v := os.Getenv(revisionRetentionAge); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid %q: %q", revisionRetentionAge, v)
+		}
+		policy.MaxAge = d
+	}
+	return policy
+}
+
+// ListRevisions serves the revision history of a student, oldest first.
+func (s *Server) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid studentId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Revisions == nil {
+		RespondWithError(w, "Revision history is not available", http.StatusNotImplemented)
+		return
+	}
+
+	revisions, err := s.Revisions.ListRevisions(r.Context(), studentId)
+	if err != nil {
+		s.Logger.Error("error listing revisions", "studentId", studentId, "error", err)
+		RespondWithError(w, "Failed to list revisions", http.StatusInternalServerError)
+		return
+	}
+
+	if len(revisions) == 0 {
+		RespondWithError(w, "student not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		s.Logger.Error("error encoding response", "error", err)
+	}
+}
+
+// RevertStudent rolls a student back to the state it was in at the revision given in the query string. The revert is
+// itself recorded as a new revision. Reverting a student into or out of the trash deletes or restores it, which takes
+// students:delete on top of the students:write the route needs.
+func (s *Server) RevertStudent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid studentId", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := strconv.Atoi(r.URL.Query().Get("revision"))
+	if err != nil || revision < 1 {
+		RespondWithError(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	if s.Revisions == nil {
+		RespondWithError(w, "Revision history is not available", http.StatusNotImplemented)
+		return
+	}
+
+	rep, ok := s.negotiate(w, r, false)
+	if !ok {
+		return
+	}
+
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		RespondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	trash := s.policy().Grants(principal, ScopeStudentsDelete)
+	student, err := s.Revisions.RevertStudent(r.Context(), studentId, revision, trash)
+	if err != nil {
+		if err.Error() == errRevertMovesTrash {
+			s.deny(w, r, principal, ScopeStudentsDelete, "reverting would move the student in or out of the trash")
+			return
+		}
+
+		s.Logger.Error("error reverting student", "studentId", studentId, "revision", revision, "error", err)
+
+		errMsg, statusCode := "error reverting student", http.StatusInternalServerError
+		switch err.Error() {
+		case errStudentNotFound:
+			errMsg, statusCode = "student not found", http.StatusNotFound
+		case errRevisionNotFound:
+			errMsg, statusCode = "revision not found", http.StatusNotFound
+		case errStudentEnrolled:
+			RespondWithJSONError(w, http.StatusConflict, ErrorResponse{
+				Error:   "student_enrolled",
+				Message: "the student is enrolled, or waitlisted, in courses, withdraw it from them first",
+			})
+			return
+		}
+
+		RespondWithError(w, errMsg, statusCode)
+		return
+	}
+
+	s.notifyEvents()
+	s.render(w, rep, http.StatusOK, student)
+}
+
+// getStudentAsOf serves a student as it was at the time given in the as_of query parameter.
+func (s *Server) getStudentAsOf(w http.ResponseWriter, r *http.Request, rep representation, p projection, studentId int, asOf string) {
+	t, err := time.Parse(time.RFC3339Nano, asOf)
+	if err != nil {
+		RespondWithError(w, "Invalid as_of, expected an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+
+	if s.Revisions == nil {
+		RespondWithError(w, "Revision history is not available", http.StatusNotImplemented)
+		return
+	}
+
+	student, err := s.Revisions.GetStudentAsOf(r.Context(), studentId, t)
+	if err != nil {
+		if err.Error() == errStudentNotFound {
+			RespondWithError(w, "No student found with that id at that time", http.StatusNotFound)
+			return
+		}
+
+		s.Logger.Error("error getting student revision", "studentId", studentId, "asOf", asOf, "error", err)
+		RespondWithError(w, "Error getting student", http.StatusInternalServerError)
+		return
+	}
+
+	s.renderStudent(w, r, rep, p, *student)
+}
+
+// revisionSnapshot is the state a mutation left the student in, or nil when the student is gone for good.
+func (m mutation) revisionSnapshot() *Student {
+	if m.state != nil {
+		return m.state
+	}
+	return m.after
+}
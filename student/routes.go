@@ -0,0 +1,84 @@
+package student
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Route is an endpoint of the API, as registered on the mux by NewRequestMultiplexer.
+type Route struct {
+	Pattern string
+	// Public routes are served to anyone. The others are authenticated, and checked against the access policy.
+	Public  bool
+	handler func(*Server, http.ResponseWriter, *http.Request)
+}
+
+// routes are matched by pattern in the access policy and the OpenAPI document, so new ones need an entry in both.
+var routes = []Route{
+	{Pattern: "/api/v1/students", handler: (*Server).ListStudents},
+	{Pattern: "/api/v1/students/add", handler: (*Server).CreateStudent},
+	{Pattern: "/api/v1/students/events", handler: (*Server).StudentEvents},
+	{Pattern: "/api/v1/students/import", handler: (*Server).ImportStudents},
+	{Pattern: "/api/v1/students/export", handler: (*Server).ExportStudents},
+	{Pattern: "/api/v1/students/search", handler: (*Server).SearchStudents},
+	{Pattern: "/api/v1/students/stats", handler: (*Server).StudentStats},
+	{Pattern: "/api/v1/students/{id}", handler: (*Server).StudentHandler},
+	{Pattern: "/api/v1/students/{id}/restore", handler: (*Server).RestoreStudent},
+	{Pattern: "/api/v1/students/{id}/revisions", handler: (*Server).ListRevisions},
+	{Pattern: "/api/v1/students/{id}/revert", handler: (*Server).RevertStudent},
+	{Pattern: "/api/v1/students/{id}/audit", handler: (*Server).StudentAudit},
+	{Pattern: "/api/v1/students/{id}/courses", handler: (*Server).StudentCourses},
+	{Pattern: "/api/v1/students/{id}/transcript", handler: (*Server).StudentTranscript},
+	{Pattern: "/api/v1/students/{id}/attendance", handler: (*Server).StudentAttendance},
+	{Pattern: "/api/v1/audit", handler: (*Server).ListAuditEvents},
+	{Pattern: "/api/v1/courses", handler: (*Server).CoursesHandler},
+	{Pattern: "/api/v1/courses/{id}", handler: (*Server).CourseHandler},
+	{Pattern: "/api/v1/courses/{id}/enrollments", handler: (*Server).EnrollmentsHandler},
+	{Pattern: "/api/v1/courses/{id}/enrollments/{studentId}", handler: (*Server).EnrollmentHandler},
+	{Pattern: "/api/v1/courses/{id}/assessments", handler: (*Server).AssessmentsHandler},
+	{Pattern: "/api/v1/courses/{id}/assessments/{assessmentId}", handler: (*Server).AssessmentHandler},
+	{Pattern: "/api/v1/courses/{id}/assessments/{assessmentId}/scores", handler: (*Server).ScoresHandler},
+	{Pattern: "/api/v1/courses/{id}/sessions", handler: (*Server).SessionsHandler},
+	{Pattern: "/api/v1/courses/{id}/sessions/{sessionId}", handler: (*Server).SessionHandler},
+	{Pattern: "/api/v1/courses/{id}/sessions/{sessionId}/attendance", handler: (*Server).AttendanceHandler},
+	{Pattern: "/api/v1/courses/{id}/attendance", handler: (*Server).CourseAttendance},
+	{Pattern: "/api/v1/webhooks", handler: (*Server).WebhooksHandler},
+	{Pattern: "/api/v1/webhooks/dead-letters", handler: (*Server).WebhookDeadLetters},
+	{Pattern: "/api/v1/webhooks/{id}", handler: (*Server).WebhookHandler},
+	{Pattern: "/api/v1/webhooks/{id}/deliveries", handler: (*Server).WebhookDeliveries},
+	{Pattern: "/api/v1/webhooks/{id}/deliveries/{deliveryId}/retry", handler: (*Server).RetryWebhookDelivery},
+	{Pattern: "/graphql", handler: (*Server).GraphQL},
+	{Pattern: "/healthcheck", Public: true, handler: (*Server).Healthcheck},
+	{Pattern: "/openapi.json", Public: true, handler: (*Server).OpenAPIDocument},
+	{Pattern: "/docs/", Public: true, handler: (*Server).APIDocs},
+}
+
+// Routes lists every route NewRequestMultiplexer registers.
+func Routes() []Route {
+	return slices.Clone(routes)
+}
+
+func NewRequestMultiplexer(server *Server) http.Handler {
+	// every api route is authenticated, then checked against the access policy, and against the OpenAPI document when
+	// validation is turned on. Mutations sent with an Idempotency-Key are safe to retry.
+	protect := func(h http.HandlerFunc) http.Handler {
+		return server.Authenticate(server.Authorize(server.ValidateOpenAPI(server.Idempotent(h))))
+	}
+
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		h := func(w http.ResponseWriter, r *http.Request) {
+			route.handler(server, w, r)
+		}
+		if route.Public {
+			mux.HandleFunc(route.Pattern, h)
+		} else {
+			mux.Handle(route.Pattern, protect(h))
+		}
+	}
+	return RequestID(mux)
+}
+
+func (s *Server) Healthcheck(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
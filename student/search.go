@@ -0,0 +1,74 @@
+package student
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// snippetStart and snippetStop are what the stores mark the terms that matched with. They're control characters, so
+// that they can't be told apart from the name, which is escaped, once they've been turned into <mark> tags.
+const (
+	snippetStart = "\x02"
+	snippetStop  = "\x03"
+)
+
+// SearchResult is a student found by a search, along with how well it matched.
+type SearchResult struct {
+	Student Student `json:"student"`
+	// Rank is how relevant the student is to the query, higher is more relevant. Ranks are only comparable within a
+	// search, and they're scored differently by each store.
+	Rank float64 `json:"rank"`
+	// Snippet is the student's name, HTML-escaped, with the terms that matched wrapped in <mark> tags. Names matched
+	// despite a typo may have nothing marked.
+	Snippet string `json:"snippet"`
+}
+
+// markSnippet turns a snippet marked with snippetStart and snippetStop into HTML. A name that has either of them itself
+// is left without marks, rather than let it open or close one.
+func markSnippet(snippet, name string) string {
+	if strings.ContainsAny(name, snippetStart+snippetStop) {
+		return html.EscapeString(strings.NewReplacer(snippetStart, "", snippetStop, "").Replace(snippet))
+	}
+	return strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// SearchStudents serves the live students matching the q query parameter, most relevant first. It's paged like a list
+// is: after_id is the last student of the previous page.
+func (s *Server) SearchStudents(w http.ResponseWriter, r *http.Request) {
+	if s.Search == nil {
+		RespondWithError(w, "Search is not available", http.StatusNotImplemented)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		RespondWithError(w, errInvalidParam("q").Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, ok := s.parseListOptions(w, r)
+	if !ok {
+		return
+	}
+	if opts.OnlyDeleted {
+		RespondWithError(w, "Searching deleted students is not supported", http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.Search.SearchStudents(r.Context(), q, opts)
+	if err != nil {
+		s.Logger.Error("error searching students", "error", err)
+		RespondWithError(w, "Failed to search students", http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		s.Logger.Error("error encoding response", "error", err)
+	}
+}
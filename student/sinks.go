@@ -0,0 +1,75 @@
+package student
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterSink writes events to w as newline delimited JSON.
+type WriterSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{enc: json.NewEncoder(w)}
+}
+
+func (s *WriterSink) Deliver(_ context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(e)
+}
+
+// FileSink appends events to an NDJSON file. Every event is synced to disk before it counts as delivered.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: f}, nil
+}
+
+func (s *FileSink) Deliver(_ context.Context, e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// ChannelSink hands events to an in-process consumer, mostly for tests. Delivery blocks until the event is received.
+type ChannelSink struct {
+	C chan Event
+}
+
+func NewChannelSink(buffer int) *ChannelSink {
+	return &ChannelSink{C: make(chan Event, buffer)}
+}
+
+func (s *ChannelSink) Deliver(ctx context.Context, e Event) error {
+	select {
+	case s.C <- e:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
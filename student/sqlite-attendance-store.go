@@ -0,0 +1,260 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+func (s *SQLiteDataStore) CreateSession(ctx context.Context, cs ClassSession) (int, error) {
+	if err := sqliteCourseExists(ctx, s.db, cs.CourseId); err != nil {
+		return 0, err
+	}
+
+	query := `insert into class_sessions (course_id, topic, starts_at) values (?, ?, ?)`
+	res, err := s.db.ExecContext(ctx, query, cs.CourseId, cs.Topic, sqliteTime(cs.StartsAt))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *SQLiteDataStore) GetSession(ctx context.Context, courseId, id int) (*ClassSession, error) {
+	return getSQLiteSession(ctx, s.db, courseId, id)
+}
+
+func (s *SQLiteDataStore) UpdateSession(ctx context.Context, courseId, id int, cs ClassSession) error {
+	query := `update class_sessions set topic = ?, starts_at = ? where id = ? and course_id = ?`
+	res, err := s.db.ExecContext(ctx, query, cs.Topic, sqliteTime(cs.StartsAt), id, courseId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New(errSessionNotFound)
+	}
+	return nil
+}
+
+func (s *SQLiteDataStore) DeleteSession(ctx context.Context, courseId, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `delete from class_sessions where id = ? and course_id = ?`, id, courseId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New(errSessionNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, `delete from attendance where session_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) ListSessions(ctx context.Context, courseId int) ([]ClassSession, error) {
+	if err := sqliteCourseExists(ctx, s.db, courseId); err != nil {
+		return nil, err
+	}
+
+	query := `select ` + sessionColumns + ` from class_sessions where course_id = ? order by starts_at, id`
+	rows, err := s.db.QueryContext(ctx, query, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []ClassSession
+	for rows.Next() {
+		cs, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *cs)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SQLiteDataStore) MarkAttendance(ctx context.Context, courseId, sessionId int, marks []AttendanceMark,
+	others string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// sqlite has no row locks. The marks are compared with the rates the transaction read before writing them, which
+	// the write lock it takes keeps from changing until it commits.
+	if _, err := getSQLiteSession(ctx, tx, courseId, sessionId); err != nil {
+		return err
+	}
+	before, err := s.courseAttendance(ctx, tx, courseId)
+	if err != nil {
+		return err
+	}
+
+	query := `insert into attendance (session_id, student_id, status) values (?, ?, ?)
+		on conflict (session_id, student_id) do update set status = excluded.status, marked_at = current_timestamp`
+	studentIds := make([]int, len(marks))
+	for i, mark := range marks {
+		if _, err := tx.ExecContext(ctx, query, sessionId, mark.StudentId, mark.Status); err != nil {
+			return err
+		}
+		studentIds[i] = mark.StudentId
+	}
+
+	if len(studentIds) > 0 {
+		args := []any{courseId}
+		for _, id := range studentIds {
+			args = append(args, id)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(studentIds)), ", ")
+		query = `select student_id from enrollments where course_id = ? and status = 'enrolled'
+			and student_id in (` + placeholders + `)`
+		enrolled, err := querySQLiteIds(ctx, tx, query, args...)
+		if err != nil {
+			return err
+		}
+		if err := checkEnrolled(studentIds, enrolled); err != nil {
+			return err
+		}
+	}
+
+	if others != "" {
+		// every enrolled student that doesn't have a mark of this session by now is one of the others.
+		query = `insert into attendance (session_id, student_id, status)
+			select ?, e.student_id, ? from enrollments e
+			where e.course_id = ? and e.status = 'enrolled'
+				and not exists (select 1 from attendance a where a.session_id = ? and a.student_id = e.student_id)`
+		if _, err := tx.ExecContext(ctx, query, sessionId, others, courseId, sessionId); err != nil {
+			return err
+		}
+	}
+
+	after, err := s.courseAttendance(ctx, tx, courseId)
+	if err != nil {
+		return err
+	}
+	for _, alert := range attendanceAlerts(before, after) {
+		e, payload, err := attendanceEvent(ctx, alert)
+		if err != nil {
+			return err
+		}
+		if err := insertSQLiteEvent(ctx, tx, e, payload); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) ListAttendance(ctx context.Context, courseId, sessionId int) ([]AttendanceMark, error) {
+	if _, err := s.GetSession(ctx, courseId, sessionId); err != nil {
+		return nil, err
+	}
+
+	query := `select session_id, student_id, status, marked_at from attendance where session_id = ? order by student_id`
+	rows, err := s.db.QueryContext(ctx, query, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var marks []AttendanceMark
+	for rows.Next() {
+		var mark AttendanceMark
+		if err := rows.Scan(&mark.SessionId, &mark.StudentId, &mark.Status, &mark.MarkedAt); err != nil {
+			return nil, err
+		}
+		marks = append(marks, mark)
+	}
+	return marks, rows.Err()
+}
+
+func (s *SQLiteDataStore) CourseAttendance(ctx context.Context, courseId int) ([]AttendanceSummary, error) {
+	if err := sqliteCourseExists(ctx, s.db, courseId); err != nil {
+		return nil, err
+	}
+	return s.courseAttendance(ctx, s.db, courseId)
+}
+
+func (s *SQLiteDataStore) StudentAttendance(ctx context.Context, studentId int) ([]AttendanceSummary, error) {
+	var exists bool
+	query := `select exists (select 1 from students where id = ? and deleted_at is null)`
+	if err := s.db.QueryRowContext(ctx, query, studentId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(errStudentNotFound)
+	}
+
+	rows, err := s.db.QueryContext(ctx, sqliteAttendanceQuery+` where e.student_id = ? and e.status = 'enrolled'
+		group by e.course_id, e.student_id order by e.course_id`, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttendanceSummaries(rows, s.AttendanceThreshold)
+}
+
+// sqliteAttendanceQuery counts the marks of enrolled students, to be narrowed down and grouped by course and student.
+const sqliteAttendanceQuery = `select e.course_id, e.student_id, count(a.status),
+		count(case when a.status = 'present' then 1 end), count(case when a.status = 'late' then 1 end),
+		count(case when a.status = 'absent' then 1 end), count(case when a.status = 'excused' then 1 end)
+	from enrollments e
+	left join class_sessions s on s.course_id = e.course_id
+	left join attendance a on a.session_id = s.id and a.student_id = e.student_id`
+
+func (s *SQLiteDataStore) courseAttendance(ctx context.Context, q sqliteQuerier, courseId int) (
+	[]AttendanceSummary, error) {
+	rows, err := q.QueryContext(ctx, sqliteAttendanceQuery+` where e.course_id = ? and e.status = 'enrolled'
+		group by e.course_id, e.student_id order by e.student_id`, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttendanceSummaries(rows, s.AttendanceThreshold)
+}
+
+func getSQLiteSession(ctx context.Context, q sqliteQuerier, courseId, id int) (*ClassSession, error) {
+	query := `select ` + sessionColumns + ` from class_sessions where id = ? and course_id = ?`
+	cs, err := scanSession(q.QueryRowContext(ctx, query, id, courseId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errSessionNotFound)
+		}
+		return nil, err
+	}
+	return cs, nil
+}
+
+// querySQLiteIds reads the single integer column query selects.
+func querySQLiteIds(ctx context.Context, q sqliteQuerier, query string, args ...any) ([]int, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,143 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func (s *SQLiteDataStore) CreateCourse(ctx context.Context, c Course) (int, error) {
+	query := `insert into courses (code, title, description, capacity, start_date, end_date) values (?, ?, ?, ?, ?, ?)`
+	res, err := s.db.ExecContext(ctx, query, c.Code, c.Title, c.Description, c.Capacity, c.StartDate.String(),
+		c.EndDate.String())
+	if err != nil {
+		return 0, sqliteCourseError(err)
+	}
+
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *SQLiteDataStore) GetCourse(ctx context.Context, id int) (*Course, error) {
+	query := `select ` + courseColumns + ` from courses where id = ?`
+	c, err := scanSQLiteCourse(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errCourseNotFound)
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *SQLiteDataStore) UpdateCourse(ctx context.Context, id int, c Course) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `update courses set code = ?, title = ?, description = ?, capacity = ?, start_date = ?, end_date = ?
+		where id = ?`
+	res, err := tx.ExecContext(ctx, query, c.Code, c.Title, c.Description, c.Capacity, c.StartDate.String(),
+		c.EndDate.String(), id)
+	if err != nil {
+		return sqliteCourseError(err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New(errCourseNotFound)
+	}
+
+	// seats added to the course go to its waitlist. Students keep theirs when seats are taken away.
+	if err := fillSQLiteSeats(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteCourse deletes a course along with its enrollments, assessments, scores, sessions and attendance. sqlite doesn't
+// enforce foreign keys unless it's asked to.
+func (s *SQLiteDataStore) DeleteCourse(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `delete from courses where id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New(errCourseNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, `delete from enrollments where course_id = ?`, id); err != nil {
+		return err
+	}
+	query := `delete from scores where assessment_id in (select id from assessments where course_id = ?)`
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `delete from assessments where course_id = ?`, id); err != nil {
+		return err
+	}
+	query = `delete from attendance where session_id in (select id from class_sessions where course_id = ?)`
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `delete from class_sessions where course_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) ListCourses(ctx context.Context, opts ListOptions) ([]Course, error) {
+	query := `select ` + courseColumns + ` from courses where id > ? order by id`
+	args := []any{opts.AfterId}
+	if opts.Limit > 0 {
+		query += ` limit ?`
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []Course
+	for rows.Next() {
+		c, err := scanSQLiteCourse(rows)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, *c)
+	}
+	return courses, rows.Err()
+}
+
+// scanSQLiteCourse scans a course selected with courseColumns. go-sqlite3 reads columns declared as dates into times.
+func scanSQLiteCourse(row interface{ Scan(...any) error }) (*Course, error) {
+	var c Course
+	err := row.Scan(&c.Id, &c.Code, &c.Title, &c.Description, &c.Capacity, &c.StartDate.Time, &c.EndDate.Time)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// sqliteCourseError turns the violation of the unique code into errCourseCodeTaken.
+func sqliteCourseError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return errors.New(errCourseCodeTaken)
+	}
+	return err
+}
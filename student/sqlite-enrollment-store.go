@@ -0,0 +1,217 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func (s *SQLiteDataStore) Enroll(ctx context.Context, courseId, studentId int) (*Enrollment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// the insert is the first statement, so the transaction holds the write lock before the seats are counted, and no
+	// other enrollment can take the last one in between.
+	query := `insert into enrollments (course_id, student_id, status)
+		select c.id, s.id, case when (select count(*) from enrollments where course_id = c.id and status = 'enrolled')
+			< c.capacity then 'enrolled' else 'waitlisted' end
+		from courses c, students s where c.id = ? and s.id = ? and s.deleted_at is null`
+	res, err := tx.ExecContext(ctx, query, courseId, studentId)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return nil, errors.New(errAlreadyEnrolled)
+		}
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		// either the course or the student is missing.
+		if err := sqliteCourseExists(ctx, tx, courseId); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(errStudentNotFound)
+	}
+
+	e, err := getSQLiteEnrollment(ctx, tx, courseId, studentId)
+	if err != nil {
+		return nil, err
+	}
+	return e, tx.Commit()
+}
+
+func (s *SQLiteDataStore) GetEnrollment(ctx context.Context, courseId, studentId int) (*Enrollment, error) {
+	return getSQLiteEnrollment(ctx, s.db, courseId, studentId)
+}
+
+func (s *SQLiteDataStore) Withdraw(ctx context.Context, courseId, studentId int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `delete from enrollments where course_id = ? and student_id = ?`, courseId, studentId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		if err := sqliteCourseExists(ctx, tx, courseId); err != nil {
+			return err
+		}
+		return errors.New(errEnrollmentNotFound)
+	}
+
+	if err := fillSQLiteSeats(ctx, tx, courseId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) ListEnrollments(ctx context.Context, courseId int) ([]Enrollment, error) {
+	if err := sqliteCourseExists(ctx, s.db, courseId); err != nil {
+		return nil, err
+	}
+
+	query := `select ` + enrollmentColumns + ` from enrollments e where e.course_id = ?
+		order by e.status = 'waitlisted', e.id`
+	rows, err := s.db.QueryContext(ctx, query, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var enrollments []Enrollment
+	for rows.Next() {
+		e, err := scanSQLiteEnrollment(rows)
+		if err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, *e)
+	}
+	return enrollments, rows.Err()
+}
+
+func (s *SQLiteDataStore) ListStudentCourses(ctx context.Context, studentId int) ([]StudentCourse, error) {
+	var exists bool
+	query := `select exists (select 1 from students where id = ? and deleted_at is null)`
+	if err := s.db.QueryRowContext(ctx, query, studentId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(errStudentNotFound)
+	}
+
+	query = `select c.id, c.code, c.title, c.description, c.capacity, c.start_date, c.end_date, ` + enrollmentColumns + `
+		from enrollments e join courses c on c.id = e.course_id where e.student_id = ? order by c.id`
+	rows, err := s.db.QueryContext(ctx, query, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []StudentCourse
+	for rows.Next() {
+		var sc StudentCourse
+		c, e := &sc.Course, &sc.Enrollment
+		err := rows.Scan(&c.Id, &c.Code, &c.Title, &c.Description, &c.Capacity, &c.StartDate.Time, &c.EndDate.Time,
+			&e.CourseId, &e.StudentId, &e.Status, &e.Position, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, sc)
+	}
+	return courses, rows.Err()
+}
+
+// releaseSQLiteEnrollments applies the EnrolledStudents policy to a student that's being deleted in tx.
+func (s *SQLiteDataStore) releaseSQLiteEnrollments(ctx context.Context, tx *sql.Tx, studentId int) error {
+	if s.EnrolledStudents != WithdrawEnrolledStudents {
+		var enrolled bool
+		query := `select exists (select 1 from enrollments where student_id = ?)`
+		if err := tx.QueryRowContext(ctx, query, studentId).Scan(&enrolled); err != nil {
+			return err
+		}
+		if enrolled {
+			return errors.New(errStudentEnrolled)
+		}
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `delete from enrollments where student_id = ? returning course_id`, studentId)
+	if err != nil {
+		return err
+	}
+	var courseIds []int
+	for rows.Next() {
+		var courseId int
+		if err := rows.Scan(&courseId); err != nil {
+			rows.Close()
+			return err
+		}
+		courseIds = append(courseIds, courseId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, courseId := range courseIds {
+		if err := fillSQLiteSeats(ctx, tx, courseId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillSQLiteSeats gives the free seats of a course to the students at the front of its waitlist. A course can have
+// more students than seats, when its capacity was lowered after they enrolled.
+func fillSQLiteSeats(ctx context.Context, tx *sql.Tx, courseId int) error {
+	query := `update enrollments set status = 'enrolled' where id in (
+		select id from enrollments where course_id = ? and status = 'waitlisted' order by id
+		limit max(0, (select capacity from courses where id = ?)
+			- (select count(*) from enrollments where course_id = ? and status = 'enrolled')))`
+	_, err := tx.ExecContext(ctx, query, courseId, courseId, courseId)
+	return err
+}
+
+// sqliteCourseExists fails with errCourseNotFound if there's no course with the id.
+func sqliteCourseExists(ctx context.Context, q sqliteQuerier, courseId int) error {
+	var exists bool
+	query := `select exists (select 1 from courses where id = ?)`
+	if err := q.QueryRowContext(ctx, query, courseId).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New(errCourseNotFound)
+	}
+	return nil
+}
+
+func getSQLiteEnrollment(ctx context.Context, q sqliteQuerier, courseId, studentId int) (*Enrollment, error) {
+	query := `select ` + enrollmentColumns + ` from enrollments e where e.course_id = ? and e.student_id = ?`
+	e, err := scanSQLiteEnrollment(q.QueryRowContext(ctx, query, courseId, studentId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errEnrollmentNotFound)
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+func scanSQLiteEnrollment(row interface{ Scan(...any) error }) (*Enrollment, error) {
+	var e Enrollment
+	if err := row.Scan(&e.CourseId, &e.StudentId, &e.Status, &e.Position, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
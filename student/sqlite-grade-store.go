@@ -0,0 +1,189 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+func (s *SQLiteDataStore) CreateAssessment(ctx context.Context, a Assessment) (int, error) {
+	if err := sqliteCourseExists(ctx, s.db, a.CourseId); err != nil {
+		return 0, err
+	}
+
+	query := `insert into assessments (course_id, title, weight, max_score) values (?, ?, ?, ?)`
+	res, err := s.db.ExecContext(ctx, query, a.CourseId, a.Title, a.Weight, a.MaxScore)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *SQLiteDataStore) GetAssessment(ctx context.Context, courseId, id int) (*Assessment, error) {
+	return getSQLiteAssessment(ctx, s.db, courseId, id)
+}
+
+func (s *SQLiteDataStore) UpdateAssessment(ctx context.Context, courseId, id int, a Assessment) error {
+	query := `update assessments set title = ?, weight = ?, max_score = ? where id = ? and course_id = ?`
+	res, err := s.db.ExecContext(ctx, query, a.Title, a.Weight, a.MaxScore, id, courseId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New(errAssessmentNotFound)
+	}
+	return nil
+}
+
+func (s *SQLiteDataStore) DeleteAssessment(ctx context.Context, courseId, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `delete from assessments where id = ? and course_id = ?`, id, courseId)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New(errAssessmentNotFound)
+	}
+
+	if _, err := tx.ExecContext(ctx, `delete from scores where assessment_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) ListAssessments(ctx context.Context, courseId int) ([]Assessment, error) {
+	if err := sqliteCourseExists(ctx, s.db, courseId); err != nil {
+		return nil, err
+	}
+
+	query := `select ` + assessmentColumns + ` from assessments where course_id = ? order by id`
+	rows, err := s.db.QueryContext(ctx, query, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assessments []Assessment
+	for rows.Next() {
+		a, err := scanAssessment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assessments = append(assessments, *a)
+	}
+	return assessments, rows.Err()
+}
+
+func (s *SQLiteDataStore) RecordScores(ctx context.Context, courseId, assessmentId int, scores []Score) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the scores are written first, so the transaction holds the write lock before the enrollments are checked, and no
+	// student can be withdrawn in between. They're rolled back if a student isn't enrolled.
+	query := `insert into scores (assessment_id, student_id, score) values (?, ?, ?)
+		on conflict (assessment_id, student_id) do update set score = excluded.score, graded_at = current_timestamp`
+	studentIds := make([]int, len(scores))
+	for i, score := range scores {
+		if _, err := tx.ExecContext(ctx, query, assessmentId, score.StudentId, score.Score); err != nil {
+			return err
+		}
+		studentIds[i] = score.StudentId
+	}
+
+	if _, err := getSQLiteAssessment(ctx, tx, courseId, assessmentId); err != nil {
+		return err
+	}
+
+	args := []any{courseId}
+	for _, id := range studentIds {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(studentIds)), ", ")
+	query = `select student_id from enrollments where course_id = ? and status = 'enrolled'
+		and student_id in (` + placeholders + `)`
+	enrolled, err := querySQLiteIds(ctx, tx, query, args...)
+	if err != nil {
+		return err
+	}
+	if err := checkEnrolled(studentIds, enrolled); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) ListScores(ctx context.Context, courseId, assessmentId int) ([]Score, error) {
+	if _, err := s.GetAssessment(ctx, courseId, assessmentId); err != nil {
+		return nil, err
+	}
+
+	query := `select assessment_id, student_id, score, graded_at from scores where assessment_id = ? order by student_id`
+	rows, err := s.db.QueryContext(ctx, query, assessmentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []Score
+	for rows.Next() {
+		var score Score
+		if err := rows.Scan(&score.AssessmentId, &score.StudentId, &score.Score, &score.GradedAt); err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	return scores, rows.Err()
+}
+
+func (s *SQLiteDataStore) StudentGrades(ctx context.Context, studentId int) ([]CourseGrades, error) {
+	var exists bool
+	query := `select exists (select 1 from students where id = ? and deleted_at is null)`
+	if err := s.db.QueryRowContext(ctx, query, studentId).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New(errStudentNotFound)
+	}
+
+	query = `select c.id, c.code, c.title, c.description, c.capacity, c.start_date, c.end_date,
+			a.id, a.title, a.weight, a.max_score, s.score
+		from enrollments e
+		join courses c on c.id = e.course_id
+		left join assessments a on a.course_id = c.id
+		left join scores s on s.assessment_id = a.id and s.student_id = e.student_id
+		where e.student_id = ? and e.status = 'enrolled'
+		order by c.id, a.id`
+	rows, err := s.db.QueryContext(ctx, query, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCourseGrades(rows)
+}
+
+func getSQLiteAssessment(ctx context.Context, q sqliteQuerier, courseId, id int) (*Assessment, error) {
+	query := `select ` + assessmentColumns + ` from assessments where id = ? and course_id = ?`
+	a, err := scanAssessment(q.QueryRowContext(ctx, query, id, courseId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errAssessmentNotFound)
+		}
+		return nil, err
+	}
+	return a, nil
+}
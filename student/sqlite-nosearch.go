@@ -0,0 +1,9 @@
+//go:build !sqlite_fts5
+
+package student
+
+// initSearch does nothing: go-sqlite3 is only built with FTS5 under the sqlite_fts5 tag, and without it the store
+// doesn't implement SearchStore.
+func (s *SQLiteDataStore) initSearch() error {
+	return nil
+}
@@ -0,0 +1,99 @@
+//go:build sqlite_fts5
+
+package student
+
+import (
+	"context"
+	"strings"
+)
+
+// initSearch indexes the names of students in students_fts, an FTS5 table kept in sync with students by triggers. It
+// only holds the index, the names themselves are read from students.
+func (s *SQLiteDataStore) initSearch() error {
+	var exists bool
+	err := s.db.QueryRow(`select count(*) > 0 from sqlite_master where name = 'students_fts'`).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	createSearchQuery := `create virtual table if not exists students_fts using fts5(
+		name, content = 'students', content_rowid = 'id', tokenize = 'unicode61 remove_diacritics 2'
+	);
+	create trigger if not exists students_fts_insert after insert on students
+	begin
+		insert into students_fts (rowid, name) values (new.id, new.name);
+	end;
+	create trigger if not exists students_fts_delete after delete on students
+	begin
+		insert into students_fts (students_fts, rowid, name) values ('delete', old.id, old.name);
+	end;
+	create trigger if not exists students_fts_update after update of name on students
+	begin
+		insert into students_fts (students_fts, rowid, name) values ('delete', old.id, old.name);
+		insert into students_fts (rowid, name) values (new.id, new.name);
+	end`
+
+	_, err = s.db.Exec(createSearchQuery)
+	if err != nil {
+		return err
+	}
+
+	// students that existed before the index are indexed once, when it's created.
+	if !exists {
+		_, err = s.db.Exec(`insert into students_fts (students_fts) values ('rebuild')`)
+	}
+	return err
+}
+
+// sqliteSearchQuery ranks matches with bm25, which scores better matches lower, so it's negated to rank them higher.
+const sqliteSearchQuery = `with matches as (
+	select s.id, s.name, s.age, s.deleted_at, -bm25(students_fts) as rank,
+		snippet(students_fts, 0, char(2), char(3), '…', 16) as snippet
+	from students_fts join students s on s.id = students_fts.rowid
+	where students_fts match ? and s.deleted_at is null
+)
+select ` + studentColumns + `, rank, snippet from matches`
+
+// SearchStudents matches every word of the query, as a prefix of a word of the name. FTS5 has no typo tolerance, but
+// names still match while they're being typed.
+func (s *SQLiteDataStore) SearchStudents(ctx context.Context, q string, opts ListOptions) ([]SearchResult, error) {
+	query := sqliteSearchQuery
+	args := []any{sqliteMatchQuery(q)}
+	if opts.AfterId > 0 {
+		query += ` where (-rank, id) > (select -rank, id from matches where id = ?)`
+		args = append(args, opts.AfterId)
+	}
+	query += ` order by rank desc, id`
+	if opts.Limit > 0 {
+		query += ` limit ?`
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		err := rows.Scan(&r.Student.Id, &r.Student.Name, &r.Student.Age, &r.Student.DeletedAt, &r.Rank, &r.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		r.Snippet = markSnippet(r.Snippet, r.Student.Name)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// sqliteMatchQuery turns a query into an FTS5 one, with each of its words quoted, so that FTS5 syntax in them is
+// matched literally, and made a prefix.
+func sqliteMatchQuery(q string) string {
+	words := strings.Fields(q)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"*`
+	}
+	return strings.Join(words, " ")
+}
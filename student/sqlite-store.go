@@ -1,14 +1,23 @@
 package student
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"iter"
 	"log"
 	"os"
+	"strings"
+	"time"
 )
 
 type SQLiteDataStore struct {
-	db *sql.DB
+	db                  *sql.DB
+	Revisions           RevisionPolicy
+	EnrolledStudents    EnrolledStudentPolicy
+	AttendanceThreshold AttendanceThreshold
 }
 
 func NewSQLiteDataStore() *SQLiteDataStore {
@@ -22,7 +31,12 @@ func NewSQLiteDataStore() *SQLiteDataStore {
 	}
 	// TODO: do we need to close connection?
 	// defer db.Close()
-	store := &SQLiteDataStore{db: db}
+	store := &SQLiteDataStore{
+		db:                  db,
+		Revisions:           NewRevisionPolicy(),
+		EnrolledStudents:    NewEnrolledStudentPolicy(),
+		AttendanceThreshold: NewAttendanceThreshold(),
+	}
 
 	err = store.init()
 	if err != nil {
@@ -37,79 +51,453 @@ func (s *SQLiteDataStore) init() error {
 	createQuery := `create table if not exists students (
 		id integer primary key autoincrement,
 		name text not null,
-		age integer
+		age integer,
+		deleted_at timestamp
 	)`
 
 	_, err := s.db.Exec(createQuery)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// databases created before soft deletes don't have the column yet.
+	err = s.addColumnIfMissing("students", "deleted_at", "timestamp")
+	if err != nil {
+		return err
+	}
+
+	createAPIKeysQuery := `create table if not exists api_keys (
+		id integer primary key autoincrement,
+		name text not null,
+		prefix text not null,
+		key_hash text not null unique,
+		scopes text not null default '',
+		created_at timestamp not null default current_timestamp,
+		last_used_at timestamp,
+		revoked_at timestamp
+	)`
+
+	_, err = s.db.Exec(createAPIKeysQuery)
+	if err != nil {
+		return err
+	}
+
+	// audit_events is append-only, the triggers make sure it stays that way.
+	createAuditEventsQuery := `create table if not exists audit_events (
+		id integer primary key autoincrement,
+		actor text not null,
+		action text not null,
+		student_id integer,
+		before text,
+		after text,
+		metadata text,
+		request_id text not null default '',
+		created_at timestamp not null default current_timestamp
+	);
+	create index if not exists audit_events_student_id_idx on audit_events (student_id);
+	create trigger if not exists audit_events_no_update before update on audit_events
+	begin
+		select raise(abort, 'audit_events is append-only');
+	end;
+	create trigger if not exists audit_events_no_delete before delete on audit_events
+	begin
+		select raise(abort, 'audit_events is append-only');
+	end`
+
+	_, err = s.db.Exec(createAuditEventsQuery)
+	if err != nil {
+		return err
+	}
+
+	// revisions are timestamped with millisecond precision, current_timestamp only has seconds.
+	createRevisionsQuery := `create table if not exists student_revisions (
+		student_id integer not null,
+		revision integer not null,
+		action text not null,
+		actor text not null,
+		name text not null,
+		age integer,
+		deleted_at timestamp,
+		created_at timestamp not null default (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+		primary key (student_id, revision)
+	)`
+
+	_, err = s.db.Exec(createRevisionsQuery)
+	if err != nil {
+		return err
+	}
+
+	// students that existed before revisions were tracked start their history at their current state.
+	backfillRevisionsQuery := `insert into student_revisions (student_id, revision, action, actor, name, age, deleted_at)
+		select id, 1, 'student.created', 'system', name, age, deleted_at from students
+		where id not in (select student_id from student_revisions)`
+
+	_, err = s.db.Exec(backfillRevisionsQuery)
+	if err != nil {
+		return err
+	}
+
+	createIdempotencyKeysQuery := `create table if not exists idempotency_keys (
+		principal text not null,
+		key text not null,
+		fingerprint text not null,
+		status integer not null default 0,
+		headers text,
+		body blob,
+		created_at timestamp not null default current_timestamp,
+		expires_at timestamp not null,
+		primary key (principal, key)
+	)`
+
+	_, err = s.db.Exec(createIdempotencyKeysQuery)
+	if err != nil {
+		return err
+	}
+
+	createOutboxEventsQuery := `create table if not exists outbox_events (
+		id integer primary key autoincrement,
+		student_id integer not null,
+		type text not null,
+		payload text not null,
+		created_at timestamp not null default current_timestamp,
+		dispatched_at timestamp
+	);
+	create index if not exists outbox_events_pending_idx on outbox_events (id) where dispatched_at is null;
+	create table if not exists outbox_state (
+		id integer primary key check (id = 1),
+		compacted_through integer not null default 0
+	);
+	insert or ignore into outbox_state (id) values (1)`
+
+	_, err = s.db.Exec(createOutboxEventsQuery)
+	if err != nil {
+		return err
+	}
+
+	// next_attempt_at has millisecond precision, so that short backoffs work as expected.
+	createWebhooksQuery := `create table if not exists webhooks (
+		id integer primary key autoincrement,
+		url text not null,
+		events text not null,
+		secret text not null,
+		created_at timestamp not null default current_timestamp
+	);
+	create table if not exists webhook_deliveries (
+		id integer primary key autoincrement,
+		webhook_id integer not null references webhooks (id),
+		event_id integer not null,
+		event_type text not null,
+		payload text not null,
+		status text not null default 'pending',
+		attempts integer not null default 0,
+		next_attempt_at timestamp default (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+		response_status integer not null default 0,
+		last_error text not null default '',
+		created_at timestamp not null default current_timestamp,
+		delivered_at timestamp,
+		unique (webhook_id, event_id)
+	);
+	create index if not exists webhook_deliveries_due_idx on webhook_deliveries (next_attempt_at)
+		where status = 'pending'`
+
+	_, err = s.db.Exec(createWebhooksQuery)
+	if err != nil {
+		return err
+	}
+
+	createCoursesQuery := `create table if not exists courses (
+		id integer primary key autoincrement,
+		code text not null unique,
+		title text not null,
+		description text not null default '',
+		capacity integer not null check (capacity > 0),
+		start_date date not null,
+		end_date date not null,
+		check (end_date >= start_date)
+	)`
+
+	_, err = s.db.Exec(createCoursesQuery)
+	if err != nil {
+		return err
+	}
+
+	// waitlists are served in id order.
+	createEnrollmentsQuery := `create table if not exists enrollments (
+		id integer primary key autoincrement,
+		course_id integer not null references courses (id),
+		student_id integer not null references students (id),
+		status text not null check (status in ('enrolled', 'waitlisted')),
+		created_at timestamp not null default current_timestamp,
+		unique (course_id, student_id)
+	);
+	create index if not exists enrollments_student_id_idx on enrollments (student_id);
+	create index if not exists enrollments_waitlist_idx on enrollments (course_id, id) where status = 'waitlisted'`
+
+	_, err = s.db.Exec(createEnrollmentsQuery)
+	if err != nil {
+		return err
+	}
+
+	createGradesQuery := `create table if not exists assessments (
+		id integer primary key autoincrement,
+		course_id integer not null references courses (id),
+		title text not null,
+		weight real not null check (weight > 0),
+		max_score real not null check (max_score > 0)
+	);
+	create index if not exists assessments_course_id_idx on assessments (course_id);
+	create table if not exists scores (
+		assessment_id integer not null references assessments (id),
+		student_id integer not null references students (id),
+		score real not null check (score >= 0),
+		graded_at timestamp not null default current_timestamp,
+		primary key (assessment_id, student_id)
+	);
+	create index if not exists scores_student_id_idx on scores (student_id)`
+
+	_, err = s.db.Exec(createGradesQuery)
+	if err != nil {
+		return err
+	}
+
+	createAttendanceQuery := `create table if not exists class_sessions (
+		id integer primary key autoincrement,
+		course_id integer not null references courses (id),
+		topic text not null,
+		starts_at timestamp not null
+	);
+	create index if not exists class_sessions_course_id_idx on class_sessions (course_id, starts_at);
+	create table if not exists attendance (
+		session_id integer not null references class_sessions (id),
+		student_id integer not null references students (id),
+		status text not null check (status in ('present', 'absent', 'late', 'excused')),
+		marked_at timestamp not null default current_timestamp,
+		primary key (session_id, student_id)
+	);
+	create index if not exists attendance_student_id_idx on attendance (student_id)`
+
+	_, err = s.db.Exec(createAttendanceQuery)
+	if err != nil {
+		return err
+	}
+	return s.initSearch()
+}
+
+// addColumnIfMissing adds a column to an existing table. sqlite has no `add column if not exists`.
+func (s *SQLiteDataStore) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(`select name from pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf(`alter table %s add column %s %s`, table, column, definition))
+	return err
 }
 
-func (s *SQLiteDataStore) CreateStudent(student Student) error {
+func (s *SQLiteDataStore) CreateStudent(ctx context.Context, student Student) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `insert into students (name, age) values (?, ?)`
-	_, err := s.db.Exec(query, student.Name, student.Age)
+	res, err := tx.ExecContext(ctx, query, student.Name, student.Age)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	student.Id = int(id)
+
+	if err := s.recordMutation(ctx, tx, newMutation(ctx, AuditStudentCreated, student.Id, nil, &student)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) GetStudent(ctx context.Context, studentId int) (*Student, error) {
+	query := `select ` + studentColumns + ` from students where id = ? and deleted_at is null`
+	row := s.db.QueryRowContext(ctx, query, studentId)
+
+	return scanSQLiteStudent(row)
 }
 
-func (s *SQLiteDataStore) GetStudent(studentId int) (*Student, error) {
-	query := `select * from students where id = ?`
-	row := s.db.QueryRow(query, studentId)
+// GetStudents reads the students in a single query.
+func (s *SQLiteDataStore) GetStudents(ctx context.Context, ids []int) ([]Student, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
 
-	var id, age int
-	var name string
-	if err := row.Scan(&id, &name, &age); err != nil {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	query := `select ` + studentColumns + ` from students where id in (` + placeholders + `) and deleted_at is null order by id`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	student := &Student{id, name, age}
-	return student, nil
+	var students []Student
+	for rows.Next() {
+		student, err := scanSQLiteStudent(rows)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, *student)
+	}
+	return students, rows.Err()
 }
 
-func (s *SQLiteDataStore) UpdateStudent(studentId int, student Student) error {
-	query := `update students set name = ?, age = ? where id = ?`
-	res, err := s.db.Exec(query, student.Name, student.Age, studentId)
+func (s *SQLiteDataStore) UpdateStudent(ctx context.Context, studentId int, student Student) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
+	before, err := getSQLiteStudentTx(ctx, tx, studentId)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return errors.New(errStudentNotFound)
+	query := `update students set name = ?, age = ? where id = ?`
+	if _, err := tx.ExecContext(ctx, query, student.Name, student.Age, studentId); err != nil {
+		return err
 	}
-	return nil
+
+	after := &Student{Id: studentId, Name: student.Name, Age: student.Age}
+	if err := s.recordMutation(ctx, tx, newMutation(ctx, AuditStudentUpdated, studentId, before, after)); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *SQLiteDataStore) DeleteStudent(studentId int) error {
-	query := `delete from students where id = ?`
-	res, err := s.db.Exec(query, studentId)
+func (s *SQLiteDataStore) DeleteStudent(ctx context.Context, studentId int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
+	before, err := getSQLiteStudentTx(ctx, tx, studentId)
 	if err != nil {
 		return err
 	}
+	if err := s.releaseSQLiteEnrollments(ctx, tx, studentId); err != nil {
+		return err
+	}
 
-	if rowsAffected == 0 {
-		return errors.New(errStudentNotFound)
+	// students are only soft deleted, PurgeStudents gets rid of them for good once they've been in the trash for long
+	// enough.
+	query := `update students set deleted_at = current_timestamp where id = ? returning ` + studentColumns
+	deleted, err := scanSQLiteStudent(tx.QueryRowContext(ctx, query, studentId))
+	if err != nil {
+		return err
 	}
-	return nil
+
+	m := newMutation(ctx, AuditStudentDeleted, studentId, before, nil)
+	m.state = deleted
+	if err := s.recordMutation(ctx, tx, m); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) ListStudents(ctx context.Context, opts ListOptions) ([]Student, error) {
+	return listSQLiteStudents(ctx, s.db, opts)
+}
+
+// StreamStudents reads the students off the cursor as they're iterated over.
+func (s *SQLiteDataStore) StreamStudents(ctx context.Context, opts ListOptions) iter.Seq2[Student, error] {
+	return func(yield func(Student, error) bool) {
+		query, args := sqliteListQuery(opts)
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(Student{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			student, err := scanStudentFields(rows, opts.Fields)
+			if err != nil {
+				yield(Student{}, err)
+				return
+			}
+			if !yield(*student, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(Student{}, err)
+		}
+	}
+}
+
+// ListStudentsVersioned reads the students and the latest event id in one transaction, so they agree.
+func (s *SQLiteDataStore) ListStudentsVersioned(ctx context.Context, opts ListOptions) ([]Student, int64, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	var resourceVersion int64
+	if err := tx.QueryRowContext(ctx, sqliteLatestEventIdQuery).Scan(&resourceVersion); err != nil {
+		return nil, 0, err
+	}
+
+	students, err := listSQLiteStudents(ctx, tx, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return students, resourceVersion, tx.Commit()
 }
 
-func (s *SQLiteDataStore) ListStudents() ([]Student, error) {
-	query := `select * from students`
-	rows, err := s.db.Query(query)
+func sqliteListQuery(opts ListOptions) (string, []any) {
+	columns := studentColumnsFor(opts.Fields)
+	query := `select ` + columns + ` from students where deleted_at is null`
+	if opts.OnlyDeleted {
+		query = `select ` + columns + ` from students where deleted_at is not null`
+	}
+
+	var args []any
+	if opts.AfterId > 0 {
+		query += ` and id > ?`
+		args = append(args, opts.AfterId)
+	}
+	query += ` order by id`
+	if opts.Limit > 0 {
+		query += ` limit ?`
+		args = append(args, opts.Limit)
+	}
+	return query, args
+}
+
+func listSQLiteStudents(ctx context.Context, q sqliteQuerier, opts ListOptions) ([]Student, error) {
+	query, args := sqliteListQuery(opts)
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -118,12 +506,923 @@ func (s *SQLiteDataStore) ListStudents() ([]Student, error) {
 
 	var students []Student
 	for rows.Next() {
-		var id, age int
-		var name string
-		if err := rows.Scan(&id, &name, &age); err != nil {
+		student, err := scanStudentFields(rows, opts.Fields)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, *student)
+	}
+	return students, rows.Err()
+}
+
+// sqliteMedianAgeQuery averages the one or two ages in the middle. sqlite has no percentile function built in.
+const sqliteMedianAgeQuery = `select avg(age) from (
+	select age from students where deleted_at is null and age is not null order by age
+	limit 2 - (select count(age) from students where deleted_at is null) % 2
+	offset (select (count(age) - 1) / 2 from students where deleted_at is null)
+)`
+
+// StudentStats reads the summary and the histogram in one transaction, so that they agree.
+func (s *SQLiteDataStore) StudentStats(ctx context.Context, ageBuckets []int) (*StudentStats, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stats := StudentStats{AgeHistogram: newAgeHistogram(ageBuckets)}
+	query := `select count(*), min(age), max(age), avg(age) from students where deleted_at is null`
+	err = tx.QueryRowContext(ctx, query).Scan(&stats.Count, &stats.MinAge, &stats.MaxAge, &stats.MeanAge)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, sqliteMedianAgeQuery).Scan(&stats.MedianAge); err != nil {
+		return nil, err
+	}
+
+	// the buckets are numbered as newAgeHistogram numbers them.
+	bucket := `0`
+	args := make([]any, len(ageBuckets))
+	if len(ageBuckets) > 0 {
+		var b strings.Builder
+		b.WriteString(`case`)
+		for i, boundary := range ageBuckets {
+			fmt.Fprintf(&b, ` when age < ? then %d`, i)
+			args[i] = boundary
+		}
+		fmt.Fprintf(&b, ` else %d end`, len(ageBuckets))
+		bucket = b.String()
+	}
+
+	query = `select ` + bucket + ` as bucket, count(*) from students
+		where deleted_at is null and age is not null group by bucket`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
 			return nil, err
 		}
-		students = append(students, Student{id, name, age})
+		stats.AgeHistogram[bucket].Count = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &stats, tx.Commit()
+}
+
+func (s *SQLiteDataStore) RestoreStudent(ctx context.Context, studentId int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `update students set deleted_at = null where id = ? and deleted_at is not null returning ` + studentColumns
+	after, err := scanSQLiteStudent(tx.QueryRowContext(ctx, query, studentId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New(errStudentNotFound)
+		}
+		return err
+	}
+
+	if err := s.recordMutation(ctx, tx, newMutation(ctx, AuditStudentRestored, studentId, nil, after)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) PurgeStudents(ctx context.Context, deletedBefore time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `delete from students where deleted_at < ? returning ` + studentColumns
+	rows, err := tx.QueryContext(ctx, query, sqliteTime(deletedBefore))
+	if err != nil {
+		return 0, err
+	}
+
+	var purged []*Student
+	for rows.Next() {
+		student, err := scanSQLiteStudent(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		purged = append(purged, student)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, student := range purged {
+		if _, err := tx.ExecContext(ctx, `delete from enrollments where student_id = ?`, student.Id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `delete from scores where student_id = ?`, student.Id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `delete from attendance where student_id = ?`, student.Id); err != nil {
+			return 0, err
+		}
+		if err := s.recordMutation(ctx, tx, newMutation(ctx, AuditStudentPurged, student.Id, student, nil)); err != nil {
+			return 0, err
+		}
+	}
+	return len(purged), tx.Commit()
+}
+
+func scanSQLiteStudent(row interface{ Scan(...any) error }) (*Student, error) {
+	var s Student
+	if err := row.Scan(&s.Id, &s.Name, &s.Age, &s.DeletedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// getSQLiteStudentTx reads a student inside tx, for the snapshot recorded alongside a mutation. sqlite has no row
+// locks; the write lock the transaction takes is what keeps the snapshot accurate.
+func getSQLiteStudentTx(ctx context.Context, tx *sql.Tx, id int) (*Student, error) {
+	query := `select ` + studentColumns + ` from students where id = ? and deleted_at is null`
+
+	student, err := scanSQLiteStudent(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errStudentNotFound)
+		}
+		return nil, err
+	}
+	return student, nil
+}
+
+// recordMutation writes everything that has to be committed together with a change to a student.
+func (s *SQLiteDataStore) recordMutation(ctx context.Context, tx *sql.Tx, m mutation) error {
+	e, err := m.auditEvent()
+	if err != nil {
+		return err
+	}
+	if err := insertSQLiteAuditEvent(ctx, tx, e); err != nil {
+		return err
+	}
+	if err := s.recordRevision(ctx, tx, m); err != nil {
+		return err
+	}
+	return s.insertOutboxEvent(ctx, tx, m)
+}
+
+func (s *SQLiteDataStore) insertOutboxEvent(ctx context.Context, tx *sql.Tx, m mutation) error {
+	e, payload, err := m.event()
+	if err != nil {
+		return err
+	}
+	return insertSQLiteEvent(ctx, tx, e, payload)
+}
+
+// insertSQLiteEvent writes e to the outbox, for events that come with no mutation, like alerts.
+func insertSQLiteEvent(ctx context.Context, tx *sql.Tx, e Event, payload []byte) error {
+	query := `insert into outbox_events (student_id, type, payload) values (?, ?, ?)`
+	_, err := tx.ExecContext(ctx, query, e.StudentId, e.Type, string(payload))
+	return err
+}
+
+func (s *SQLiteDataStore) PendingEvents(ctx context.Context, limit int, skip []int) ([]Event, error) {
+	args := make([]any, 0, len(skip)+1)
+	for _, id := range skip {
+		args = append(args, id)
+	}
+	args = append(args, limit)
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(skip)), ", ")
+	query := `select id, payload from outbox_events where dispatched_at is null and student_id not in (` + placeholders +
+		`) order by id limit ?`
+	return s.queryEvents(ctx, query, args...)
+}
+
+func (s *SQLiteDataStore) EventsAfter(ctx context.Context, afterId int64, limit int) ([]Event, error) {
+	query := `select id, payload from outbox_events where id > ? order by id limit ?`
+	return s.queryEvents(ctx, query, afterId, limit)
+}
+
+// sqliteLatestEventIdQuery reads the newest event id, which once the outbox has been compacted may only be remembered
+// as how far it was compacted.
+const sqliteLatestEventIdQuery = `select max(
+	(select coalesce(max(id), 0) from outbox_events),
+	(select coalesce(max(compacted_through), 0) from outbox_state))`
+
+func (s *SQLiteDataStore) LatestEventId(ctx context.Context) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, sqliteLatestEventIdQuery).Scan(&id)
+	return id, err
+}
+
+func (s *SQLiteDataStore) queryEvents(ctx context.Context, query string, args ...any) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var id int64
+		var payload string
+		if err := rows.Scan(&id, &payload); err != nil {
+			return nil, err
+		}
+
+		e, err := decodeEvent(id, []byte(payload))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLiteDataStore) MarkEventsDispatched(ctx context.Context, ids []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		query := `update outbox_events set dispatched_at = ? where id = ?`
+		if _, err := tx.ExecContext(ctx, query, sqliteTime(time.Now()), id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteDispatchedEvents compacts the outbox, and remembers how far it got so that watches can tell when they've
+// missed events.
+func (s *SQLiteDataStore) DeleteDispatchedEvents(ctx context.Context, before time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var compacted int64
+	query := `select coalesce(max(id), 0) from outbox_events where dispatched_at < ?`
+	if err := tx.QueryRowContext(ctx, query, sqliteTime(before)).Scan(&compacted); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, `delete from outbox_events where dispatched_at < ?`, sqliteTime(before))
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	query = `update outbox_state set compacted_through = max(compacted_through, ?)`
+	if _, err := tx.ExecContext(ctx, query, compacted); err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), tx.Commit()
+}
+
+func (s *SQLiteDataStore) CompactedEventId(ctx context.Context) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `select coalesce(max(compacted_through), 0) from outbox_state`).Scan(&id)
+	return id, err
+}
+
+// recordRevision appends the state m left the student in to its history, and prunes the history according to the
+// retention policy. A student that's gone for good takes its history with it.
+func (s *SQLiteDataStore) recordRevision(ctx context.Context, tx *sql.Tx, m mutation) error {
+	snapshot := m.revisionSnapshot()
+	if snapshot == nil {
+		_, err := tx.ExecContext(ctx, `delete from student_revisions where student_id = ?`, m.studentId)
+		return err
+	}
+
+	query := `insert into student_revisions (student_id, revision, action, actor, name, age, deleted_at)
+		select ?1, coalesce(max(revision), 0) + 1, ?2, ?3, ?4, ?5, ?6 from student_revisions where student_id = ?1
+		returning revision`
+	var revision int
+	err := tx.QueryRowContext(ctx, query, m.studentId, m.action, m.actor, snapshot.Name, snapshot.Age,
+		sqliteNullTime(snapshot.DeletedAt)).Scan(&revision)
+	if err != nil {
+		return err
+	}
+
+	if s.Revisions.MaxRevisions > 0 {
+		query := `delete from student_revisions where student_id = ? and revision <= ?`
+		if _, err := tx.ExecContext(ctx, query, m.studentId, revision-s.Revisions.MaxRevisions); err != nil {
+			return err
+		}
+	}
+	if s.Revisions.MaxAge > 0 {
+		query := `delete from student_revisions where student_id = ? and revision < ? and created_at < ?`
+		cutoff := sqliteTimeMillis(time.Now().Add(-s.Revisions.MaxAge))
+		if _, err := tx.ExecContext(ctx, query, m.studentId, revision, cutoff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteDataStore) ListRevisions(ctx context.Context, studentId int) ([]Revision, error) {
+	query := `select student_id, revision, action, actor, name, age, deleted_at, created_at from student_revisions
+		where student_id = ? order by revision`
+	rows, err := s.db.QueryContext(ctx, query, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		rev, err := scanSQLiteRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *SQLiteDataStore) ListRevisionsOf(ctx context.Context, studentIds []int) (map[int][]Revision, error) {
+	if len(studentIds) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, len(studentIds))
+	for i, id := range studentIds {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(studentIds)), ", ")
+	query := `select student_id, revision, action, actor, name, age, deleted_at, created_at from student_revisions
+		where student_id in (` + placeholders + `) order by student_id, revision`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make(map[int][]Revision)
+	for rows.Next() {
+		rev, err := scanSQLiteRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions[rev.StudentId] = append(revisions[rev.StudentId], *rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *SQLiteDataStore) GetStudentAsOf(ctx context.Context, studentId int, asOf time.Time) (*Student, error) {
+	query := `select student_id, revision, action, actor, name, age, deleted_at, created_at from student_revisions
+		where student_id = ? and created_at <= ? order by revision desc limit 1`
+	rev, err := scanSQLiteRevision(s.db.QueryRowContext(ctx, query, studentId, sqliteTimeMillis(asOf)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errStudentNotFound)
+		}
+		return nil, err
+	}
+
+	// the student existed, but it had already been deleted by then.
+	if rev.Student.DeletedAt != nil {
+		return nil, errors.New(errStudentNotFound)
+	}
+	return &rev.Student, nil
+}
+
+func (s *SQLiteDataStore) RevertStudent(ctx context.Context, studentId, revision int, trash bool) (*Student, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// unlike other mutations, reverts apply to deleted students too — reverting to a revision from before the delete
+	// brings the student back.
+	query := `select ` + studentColumns + ` from students where id = ?`
+	before, err := scanSQLiteStudent(tx.QueryRowContext(ctx, query, studentId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errStudentNotFound)
+		}
+		return nil, err
+	}
+
+	query = `select student_id, revision, action, actor, name, age, deleted_at, created_at from student_revisions
+		where student_id = ? and revision = ?`
+	rev, err := scanSQLiteRevision(tx.QueryRowContext(ctx, query, studentId, revision))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errRevisionNotFound)
+		}
+		return nil, err
+	}
+
+	deleted := rev.Student.DeletedAt != nil
+	if deleted != (before.DeletedAt != nil) && !trash {
+		return nil, errors.New(errRevertMovesTrash)
+	}
+	// reverting into the trash deletes the student, so its enrollments go the way DeleteStudent sends them.
+	if deleted && before.DeletedAt == nil {
+		if err := s.releaseSQLiteEnrollments(ctx, tx, studentId); err != nil {
+			return nil, err
+		}
+	}
+
+	// a student reverted into the trash is deleted now, not when the revision was, so PurgeStudents gives it the full
+	// retention period.
+	query = `update students set name = ?, age = ?,
+		deleted_at = case when ? then coalesce(deleted_at, current_timestamp) end
+		where id = ? returning ` + studentColumns
+	after, err := scanSQLiteStudent(tx.QueryRowContext(ctx, query, rev.Student.Name, rev.Student.Age, deleted, studentId))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordMutation(ctx, tx, newMutation(ctx, AuditStudentReverted, studentId, before, after)); err != nil {
+		return nil, err
+	}
+	return after, tx.Commit()
+}
+
+func scanSQLiteRevision(row interface{ Scan(...any) error }) (*Revision, error) {
+	var r Revision
+	err := row.Scan(&r.StudentId, &r.Revision, &r.Action, &r.Actor, &r.Student.Name, &r.Student.Age,
+		&r.Student.DeletedAt, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.Student.Id = r.StudentId
+	return &r, nil
+}
+
+// sqliteQuerier is what *sql.DB and *sql.Tx have in common.
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertSQLiteAuditEvent(ctx context.Context, q sqliteQuerier, e AuditEvent) error {
+	query := `insert into audit_events (actor, action, student_id, before, after, metadata, request_id)
+		values (?, ?, ?, ?, ?, ?, ?)`
+	_, err := q.ExecContext(ctx, query, e.Actor, e.Action, e.StudentId, nullableJSON(e.Before), nullableJSON(e.After),
+		nullableJSON(e.Metadata), e.RequestId)
+	return err
+}
+
+func (s *SQLiteDataStore) RecordAuditEvent(ctx context.Context, e AuditEvent) error {
+	return insertSQLiteAuditEvent(ctx, s.db, e)
+}
+
+func (s *SQLiteDataStore) ListAuditEvents(ctx context.Context, f AuditFilter) ([]AuditEvent, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if f.StudentId != nil {
+		where("student_id = ?", *f.StudentId)
+	}
+	if f.Actor != "" {
+		where("actor = ?", f.Actor)
+	}
+	if f.Action != "" {
+		where("action = ?", f.Action)
+	}
+	if f.Since != nil {
+		where("created_at >= ?", sqliteTime(*f.Since))
+	}
+	if f.Until != nil {
+		where("created_at < ?", sqliteTime(*f.Until))
+	}
+	if f.BeforeId > 0 {
+		where("id < ?", f.BeforeId)
+	}
+
+	query := `select id, actor, action, student_id, before, after, metadata, request_id, created_at from audit_events`
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	query += " order by id desc limit ?"
+	args = append(args, auditLimit(f.Limit))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		var e AuditEvent
+		var before, after, metadata sql.NullString
+		err := rows.Scan(&e.Id, &e.Actor, &e.Action, &e.StudentId, &before, &after, &metadata, &e.RequestId, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Before, e.After, e.Metadata = rawJSON(before), rawJSON(after), rawJSON(metadata)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// sqliteTime formats t the way current_timestamp does, so the two compare correctly as text.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(time.DateTime)
+}
+
+// sqliteTimeMillis formats t like sqliteTime, with millisecond precision, the way strftime('%Y-%m-%d %H:%M:%f') does.
+func sqliteTimeMillis(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000")
+}
+
+func sqliteNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqliteTime(*t)
+}
+
+func rawJSON(s sql.NullString) json.RawMessage {
+	if !s.Valid {
+		return nil
+	}
+	return json.RawMessage(s.String)
+}
+
+func (s *SQLiteDataStore) ReserveIdempotencyKey(ctx context.Context, r IdempotencyRecord) (*IdempotencyRecord, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `delete from idempotency_keys where principal = ? and key = ? and expires_at < ?`
+	if _, err := tx.ExecContext(ctx, query, r.Principal, r.Key, sqliteTime(time.Now())); err != nil {
+		return nil, err
+	}
+
+	query = `insert into idempotency_keys (principal, key, fingerprint, expires_at) values (?, ?, ?, ?)
+		on conflict do nothing`
+	res, err := tx.ExecContext(ctx, query, r.Principal, r.Key, r.Fingerprint, sqliteTime(r.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 1 {
+		return nil, tx.Commit()
+	}
+
+	query = `select principal, key, fingerprint, status, headers, body, expires_at from idempotency_keys
+		where principal = ? and key = ?`
+	var existing IdempotencyRecord
+	var headers sql.NullString
+	err = tx.QueryRowContext(ctx, query, r.Principal, r.Key).Scan(&existing.Principal, &existing.Key,
+		&existing.Fingerprint, &existing.Status, &headers, &existing.Body, &existing.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &existing.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &existing, tx.Commit()
+}
+
+func (s *SQLiteDataStore) CompleteIdempotencyKey(ctx context.Context, r IdempotencyRecord) error {
+	headers, err := json.Marshal(r.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `update idempotency_keys set status = ?, headers = ?, body = ? where principal = ? and key = ?`
+	_, err = s.db.ExecContext(ctx, query, r.Status, string(headers), r.Body, r.Principal, r.Key)
+	return err
+}
+
+func (s *SQLiteDataStore) ReleaseIdempotencyKey(ctx context.Context, principal, key string) error {
+	query := `delete from idempotency_keys where principal = ? and key = ?`
+	_, err := s.db.ExecContext(ctx, query, principal, key)
+	return err
+}
+
+func (s *SQLiteDataStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	query := `delete from idempotency_keys where expires_at < ?`
+	res, err := s.db.ExecContext(ctx, query, sqliteTime(now))
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	return int(rowsAffected), err
+}
+
+func (s *SQLiteDataStore) CreateAPIKey(ctx context.Context, k APIKey) (int, error) {
+	query := `insert into api_keys (name, prefix, key_hash, scopes) values (?, ?, ?, ?)`
+	res, err := s.db.ExecContext(ctx, query, k.Name, k.Prefix, k.Hash, strings.Join(k.Scopes, ","))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *SQLiteDataStore) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	query := `select id, name, prefix, scopes, created_at, last_used_at, revoked_at from api_keys where key_hash = ?`
+	row := s.db.QueryRowContext(ctx, query, hash)
+
+	k, err := scanSQLiteAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errAPIKeyNotFound)
+		}
+		return nil, err
+	}
+	k.Hash = hash
+	return k, nil
+}
+
+func (s *SQLiteDataStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	query := `select id, name, prefix, scopes, created_at, last_used_at, revoked_at from api_keys order by id`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		k, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLiteDataStore) RevokeAPIKey(ctx context.Context, id int) error {
+	query := `update api_keys set revoked_at = current_timestamp where id = ? and revoked_at is null`
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(errAPIKeyNotFound)
+	}
+	return nil
+}
+
+func (s *SQLiteDataStore) TouchAPIKey(ctx context.Context, id int) error {
+	query := `update api_keys set last_used_at = current_timestamp where id = ?`
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// scanSQLiteAPIKey scans an api_keys row. sqlite has no array type, so scopes are kept as a comma separated list.
+func scanSQLiteAPIKey(row interface{ Scan(...any) error }) (*APIKey, error) {
+	var k APIKey
+	var scopes string
+	if err := row.Scan(&k.Id, &k.Name, &k.Prefix, &scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+	return &k, nil
+}
+
+// webhooks are stored with their events as a comma separated list, like the scopes of API keys.
+func (s *SQLiteDataStore) CreateWebhook(ctx context.Context, wh Webhook) (int, error) {
+	query := `insert into webhooks (url, events, secret) values (?, ?, ?)`
+	res, err := s.db.ExecContext(ctx, query, wh.URL, strings.Join(wh.Events, ","), wh.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *SQLiteDataStore) GetWebhook(ctx context.Context, id int) (*Webhook, error) {
+	query := `select id, url, events, secret, created_at from webhooks where id = ?`
+	wh, err := scanSQLiteWebhook(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(errWebhookNotFound)
+		}
+		return nil, err
+	}
+	return wh, nil
+}
+
+func (s *SQLiteDataStore) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	query := `select id, url, events, secret, created_at from webhooks order by id`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []Webhook
+	for rows.Next() {
+		wh, err := scanSQLiteWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *wh)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *SQLiteDataStore) DeleteWebhook(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `delete from webhook_deliveries where webhook_id = ?`, id); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `delete from webhooks where id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New(errWebhookNotFound)
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteDataStore) EnqueueWebhookDeliveries(ctx context.Context, e Event, payload []byte) error {
+	query := `insert into webhook_deliveries (webhook_id, event_id, event_type, payload)
+		select id, ?1, ?2, ?3 from webhooks where ',' || events || ',' like '%,' || ?2 || ',%'
+		on conflict do nothing`
+	_, err := s.db.ExecContext(ctx, query, e.Id, e.Type, string(payload))
+	return err
+}
+
+func (s *SQLiteDataStore) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error) {
+	query := `select ` + webhookDeliveryColumns + ` from webhook_deliveries
+		where status = 'pending' and next_attempt_at <= ? order by id limit ?`
+	return s.queryWebhookDeliveries(ctx, query, sqliteTimeMillis(now), limit)
+}
+
+func (s *SQLiteDataStore) RecordWebhookAttempt(ctx context.Context, d WebhookDelivery) error {
+	var nextAttemptAt any
+	if d.NextAttemptAt != nil {
+		nextAttemptAt = sqliteTimeMillis(*d.NextAttemptAt)
+	}
+
+	query := `update webhook_deliveries set status = ?, attempts = ?, next_attempt_at = ?, response_status = ?,
+		last_error = ?, delivered_at = ? where id = ?`
+	_, err := s.db.ExecContext(ctx, query, d.Status, d.Attempts, nextAttemptAt, d.ResponseStatus, d.LastError,
+		sqliteNullTime(d.DeliveredAt), d.Id)
+	return err
+}
+
+func (s *SQLiteDataStore) ListWebhookDeliveries(ctx context.Context, webhookId int) ([]WebhookDelivery, error) {
+	query := `select ` + webhookDeliveryColumns + ` from webhook_deliveries where webhook_id = ? order by id desc`
+	return s.queryWebhookDeliveries(ctx, query, webhookId)
+}
+
+func (s *SQLiteDataStore) ListDeadWebhookDeliveries(ctx context.Context) ([]WebhookDelivery, error) {
+	query := `select ` + webhookDeliveryColumns + ` from webhook_deliveries where status = 'dead' order by id desc`
+	return s.queryWebhookDeliveries(ctx, query)
+}
+
+func (s *SQLiteDataStore) RetryWebhookDelivery(ctx context.Context, webhookId int, deliveryId int64) error {
+	query := `update webhook_deliveries set status = 'pending', attempts = 0, next_attempt_at = ?
+		where id = ? and webhook_id = ?`
+	res, err := s.db.ExecContext(ctx, query, sqliteTimeMillis(time.Now()), deliveryId, webhookId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New(errWebhookDeliveryNotFound)
+	}
+	return nil
+}
+
+func (s *SQLiteDataStore) queryWebhookDeliveries(ctx context.Context, query string, args ...any) ([]WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload string
+		err := rows.Scan(&d.Id, &d.WebhookId, &d.EventId, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = json.RawMessage(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanSQLiteWebhook(row interface{ Scan(...any) error }) (*Webhook, error) {
+	var wh Webhook
+	var events string
+	if err := row.Scan(&wh.Id, &wh.URL, &events, &wh.Secret, &wh.CreatedAt); err != nil {
+		return nil, err
+	}
+	wh.Events = strings.Split(events, ",")
+	return &wh, nil
+}
+
+type sqliteStudentImport struct {
+	s  *SQLiteDataStore
+	tx *sql.Tx
+}
+
+func (s *SQLiteDataStore) BeginImport(ctx context.Context) (StudentImport, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteStudentImport{s: s, tx: tx}, nil
+}
+
+func (i *sqliteStudentImport) Insert(ctx context.Context, students []Student) ([]Student, error) {
+	stmt, err := i.tx.PrepareContext(ctx, `insert into students (name, age) values (?, ?) returning id`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	created := make([]Student, 0, len(students))
+	for _, student := range students {
+		if err := stmt.QueryRowContext(ctx, student.Name, student.Age).Scan(&student.Id); err != nil {
+			return nil, err
+		}
+
+		m := newMutation(ctx, AuditStudentCreated, student.Id, nil, &student)
+		if err := i.s.recordMutation(ctx, i.tx, m); err != nil {
+			return nil, err
+		}
+		created = append(created, student)
+	}
+	return created, nil
+}
+
+func (i *sqliteStudentImport) Commit(context.Context) error {
+	return i.tx.Commit()
+}
+
+func (i *sqliteStudentImport) Rollback(context.Context) error {
+	err := i.tx.Rollback()
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
 	}
-	return students, nil
+	return err
 }
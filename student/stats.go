@@ -0,0 +1,97 @@
+package student
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultAgeBuckets are the boundaries of the age histogram when the client doesn't ask for others.
+var defaultAgeBuckets = []int{18, 25, 35, 45, 55}
+
+// maxAgeBuckets caps how many boundaries a histogram can be asked for with.
+const maxAgeBuckets = 100
+
+// StudentStats sums up the live students. The ages are null when there are no students.
+type StudentStats struct {
+	Count        int         `json:"count"`
+	MinAge       *int        `json:"min_age"`
+	MaxAge       *int        `json:"max_age"`
+	MeanAge      *float64    `json:"mean_age"`
+	MedianAge    *float64    `json:"median_age"`
+	AgeHistogram []AgeBucket `json:"age_histogram"`
+}
+
+// AgeBucket is a bar of the age histogram: the students at least From years old, and younger than To. The first bucket
+// has no From, and the last no To, so that every student falls in one.
+type AgeBucket struct {
+	From  *int `json:"from,omitempty"`
+	To    *int `json:"to,omitempty"`
+	Count int  `json:"count"`
+}
+
+// newAgeHistogram returns the empty buckets that ascending boundaries split ages into, one more than there are
+// boundaries. Stores count students in bucket i when they're younger than boundaries[i], and older than those before.
+func newAgeHistogram(boundaries []int) []AgeBucket {
+	buckets := make([]AgeBucket, len(boundaries)+1)
+	for i := range boundaries {
+		buckets[i].To = &boundaries[i]
+		buckets[i+1].From = &boundaries[i]
+	}
+	return buckets
+}
+
+// parseAgeBuckets reads the boundaries of the age histogram, comma separated and in ascending order.
+func parseAgeBuckets(v string) ([]int, error) {
+	if v == "" {
+		return slices.Clone(defaultAgeBuckets), nil
+	}
+
+	parts := strings.Split(v, ",")
+	if len(parts) > maxAgeBuckets {
+		return nil, fmt.Errorf("at most %d buckets can be asked for", maxAgeBuckets)
+	}
+	boundaries := make([]int, len(parts))
+	for i, part := range parts {
+		boundary, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || boundary < 0 {
+			return nil, errInvalidParam("buckets")
+		}
+		if i > 0 && boundary <= boundaries[i-1] {
+			return nil, errors.New("buckets must be in ascending order")
+		}
+		boundaries[i] = boundary
+	}
+	return boundaries, nil
+}
+
+// StudentStats serves the count and age distribution of the live students. The buckets query parameter sets the
+// boundaries of the age histogram.
+func (s *Server) StudentStats(w http.ResponseWriter, r *http.Request) {
+	if s.Stats == nil {
+		RespondWithError(w, "Statistics are not available", http.StatusNotImplemented)
+		return
+	}
+
+	boundaries, err := parseAgeBuckets(r.URL.Query().Get("buckets"))
+	if err != nil {
+		RespondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := s.Stats.StudentStats(r.Context(), boundaries)
+	if err != nil {
+		s.Logger.Error("error computing student stats", "error", err)
+		RespondWithError(w, "Failed to compute student stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		s.Logger.Error("error encoding response", "error", err)
+	}
+}
@@ -0,0 +1,247 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transcript is the record of a student's grades, in the courses it's enrolled in.
+type Transcript struct {
+	Student Student            `json:"student"`
+	Courses []TranscriptCourse `json:"courses"`
+	// GPA is the mean of the grade points of the graded courses, nil until one is graded.
+	GPA         *float64  `json:"gpa"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// TranscriptCourse is a course on a transcript. The grade is nil until one of its assessments is scored.
+type TranscriptCourse struct {
+	Course      Course             `json:"course"`
+	Assessments []GradedAssessment `json:"assessments"`
+	// Grade is the weighted percentage of the scored assessments.
+	Grade       *float64 `json:"grade"`
+	Letter      string   `json:"letter,omitempty"`
+	GradePoints *float64 `json:"grade_points"`
+}
+
+// transcriptRepresentations are the formats transcripts are rendered in, named by their subtype with ?format=.
+var transcriptRepresentations = []representation{
+	{
+		mediaType: "application/json",
+		encode:    func(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) },
+	},
+	{
+		mediaType: "text/html",
+		encode:    func(w io.Writer, v any) error { return transcriptTemplate.Execute(w, v) },
+	},
+	{
+		mediaType: "application/pdf",
+		encode:    encodeTranscriptPDF,
+	},
+}
+
+var transcriptTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{"number": formatNumber}).
+	Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Transcript of {{.Student.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { padding: 0.25em 1em 0.25em 0; text-align: left; border-bottom: 1px solid #ddd; }
+</style>
+</head>
+<body>
+<h1>Transcript</h1>
+<p>{{.Student.Name}}, student {{.Student.Id}}<br>Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}</p>
+{{range .Courses}}
+<h2>{{.Course.Code}} {{.Course.Title}}</h2>
+<p>{{.Course.StartDate}} to {{.Course.EndDate}}</p>
+<table>
+<thead><tr><th>Assessment</th><th>Weight</th><th>Score</th></tr></thead>
+<tbody>
+{{range .Assessments}}<tr><td>{{.Assessment.Title}}</td><td>{{number .Assessment.Weight}}</td><td>{{if .Score}}{{number .Score}} / {{number .Assessment.MaxScore}}{{else}}not scored{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+<p>Grade: {{if .Grade}}{{number .Grade}}%, {{.Letter}} ({{number .GradePoints}}){{else}}not graded yet{{end}}</p>
+{{else}}
+<p>Not enrolled in any course.</p>
+{{end}}
+<p><strong>GPA: {{if .GPA}}{{number .GPA}}{{else}}not graded yet{{end}}</strong></p>
+</body>
+</html>
+`))
+
+// encodeTranscriptPDF lays a transcript out like its HTML rendering, one course after the other.
+func encodeTranscriptPDF(w io.Writer, v any) error {
+	t, ok := v.(*Transcript)
+	if !ok {
+		return fmt.Errorf("can't render %T as a pdf", v)
+	}
+
+	var doc pdfDocument
+	doc.line(18, true, pdfText{0, "Transcript"})
+	doc.line(12, false, pdfText{0, fmt.Sprintf("%s, student %d", t.Student.Name, t.Student.Id)})
+	doc.line(9, false, pdfText{0, "Generated " + t.GeneratedAt.Format("2006-01-02 15:04 MST")})
+
+	for _, c := range t.Courses {
+		doc.space(12)
+		doc.line(12, true, pdfText{0, c.Course.Code + "  " + c.Course.Title})
+		doc.line(9, false, pdfText{0, fmt.Sprintf("%s to %s", c.Course.StartDate, c.Course.EndDate)})
+		doc.line(9, true, pdfText{12, "Assessment"}, pdfText{300, "Weight"}, pdfText{380, "Score"})
+		for _, a := range c.Assessments {
+			score := "not scored"
+			if a.Score != nil {
+				score = formatNumber(a.Score) + " / " + formatNumber(a.Assessment.MaxScore)
+			}
+			doc.line(9, false, pdfText{12, a.Assessment.Title}, pdfText{300, formatNumber(a.Assessment.Weight)},
+				pdfText{380, score})
+		}
+
+		grade := "not graded yet"
+		if c.Grade != nil {
+			grade = fmt.Sprintf("%s%%, %s (%s)", formatNumber(c.Grade), c.Letter, formatNumber(c.GradePoints))
+		}
+		doc.line(10, true, pdfText{12, "Grade: " + grade})
+	}
+	if len(t.Courses) == 0 {
+		doc.space(12)
+		doc.line(10, false, pdfText{0, "Not enrolled in any course."})
+	}
+
+	gpa := "not graded yet"
+	if t.GPA != nil {
+		gpa = formatNumber(t.GPA)
+	}
+	doc.space(12)
+	doc.line(12, true, pdfText{0, "GPA: " + gpa})
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// formatNumber renders a number, or what a pointer to one points to, as briefly as it can be.
+func formatNumber(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return formatNumber(*v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// StudentTranscript renders the transcript of a student in the format asked for by ?format=json|html|pdf, or by the
+// Accept header when there's no format. Transcripts are generated as they're asked for, they're never stored.
+func (s *Server) StudentTranscript(w http.ResponseWriter, r *http.Request) {
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid studentId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Grades == nil {
+		RespondWithError(w, "Grades are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	rep, ok := s.negotiateTranscript(w, r)
+	if !ok {
+		return
+	}
+
+	transcript, err := s.transcript(r.Context(), studentId)
+	if err != nil {
+		s.Logger.Error("error generating transcript", "studentId", studentId, "error", err)
+		if errors.Is(err, sql.ErrNoRows) || err.Error() == errStudentNotFound {
+			RespondWithError(w, "student not found", http.StatusNotFound)
+			return
+		}
+		RespondWithError(w, "error generating transcript", http.StatusInternalServerError)
+		return
+	}
+
+	if rep.mediaType == "application/pdf" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="transcript-%d.pdf"`, studentId))
+	}
+	s.render(w, rep, http.StatusOK, transcript)
+}
+
+// negotiateTranscript picks the format of a transcript. It responds itself when none the client asked for is
+// available.
+func (s *Server) negotiateTranscript(w http.ResponseWriter, r *http.Request) (representation, bool) {
+	w.Header().Add("Vary", "Accept")
+
+	var formats, mediaTypes []string
+	for _, rep := range transcriptRepresentations {
+		_, format, _ := strings.Cut(rep.mediaType, "/")
+		formats = append(formats, format)
+		mediaTypes = append(mediaTypes, rep.mediaType)
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" {
+		for i, f := range formats {
+			if f == format {
+				return transcriptRepresentations[i], true
+			}
+		}
+		RespondWithError(w, "Invalid format, expected one of "+strings.Join(formats, ", "), http.StatusBadRequest)
+		return representation{}, false
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return transcriptRepresentations[0], true
+	}
+	rep, ok := preferred(parseAccept(accept), transcriptRepresentations)
+	if !ok {
+		RespondWithJSONError(w, http.StatusNotAcceptable, ErrorResponse{
+			Error:   "not_acceptable",
+			Message: "none of the media types in the Accept header can be rendered",
+			Details: map[string]any{"supported": mediaTypes},
+		})
+		return representation{}, false
+	}
+	return rep, true
+}
+
+// transcript puts the transcript of a live student together, grading its courses.
+func (s *Server) transcript(ctx context.Context, studentId int) (*Transcript, error) {
+	student, err := s.Store.GetStudent(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+	grades, err := s.Grades.StudentGrades(ctx, studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transcript{Student: *student, Courses: make([]TranscriptCourse, 0, len(grades)), GeneratedAt: time.Now().UTC()}
+	for _, g := range grades {
+		c := TranscriptCourse{Course: g.Course, Assessments: g.Assessments, Grade: courseGrade(g.Assessments)}
+		if c.Assessments == nil {
+			c.Assessments = []GradedAssessment{}
+		}
+		if c.Grade != nil {
+			letter, points := letterGrade(*c.Grade)
+			c.Letter, c.GradePoints = letter, &points
+		}
+		t.Courses = append(t.Courses, c)
+	}
+	t.GPA = gpa(t.Courses)
+	return t, nil
+}
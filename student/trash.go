@@ -0,0 +1,82 @@
+package student
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RestoreStudent takes a student out of the trash.
+func (s *Server) RestoreStudent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	studentId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid studentId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Trash == nil {
+		RespondWithError(w, "Restoring students is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	if err := s.Trash.RestoreStudent(r.Context(), studentId); err != nil {
+		s.Logger.Error("error restoring student", "studentId", studentId, "error", err)
+
+		errMsg, statusCode := "error restoring student", http.StatusInternalServerError
+		if err.Error() == errStudentNotFound {
+			errMsg, statusCode = "no deleted student with that id", http.StatusNotFound
+		}
+
+		RespondWithError(w, errMsg, statusCode)
+		return
+	}
+
+	s.notifyEvents()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeRetention reads how long deleted students are kept for from PURGE_RETENTION. Without it, they are kept until
+// purged by hand.
+func PurgeRetention() (time.Duration, bool) {
+	v := os.Getenv(purgeRetention)
+	if v == "" {
+		return 0, false
+	}
+
+	retention, err := time.ParseDuration(v)
+	if err != nil || retention <= 0 {
+		log.Fatalf("invalid %q: %q", purgeRetention, v)
+	}
+	return retention, true
+}
+
+// PurgeTrash permanently deletes students that have been in the trash for longer than retention, every interval,
+// until ctx is done.
+func PurgeTrash(ctx context.Context, trash TrashStore, retention, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := trash.PurgeStudents(ctx, time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("error purging deleted students", "error", err)
+		} else if n > 0 {
+			logger.Info("purged deleted students", "count", n, "retention", retention.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -3,7 +3,6 @@ package student
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -11,19 +10,60 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type contextKey string
 
+// Student is tagged for every format it can be rendered in, see representations.
 type Student struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
-	Age  int    `json:"age"`
+	Id        int        `json:"id" xml:"id" yaml:"id" msgpack:"id"`
+	Name      string     `json:"name" xml:"name" yaml:"name" msgpack:"name"`
+	Age       int        `json:"age" xml:"age" yaml:"age" msgpack:"age"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" xml:"deleted_at,omitempty" yaml:"deleted_at,omitempty" msgpack:"deleted_at,omitempty"`
 }
 
+// studentColumns is the column list both stores select students with, in the order they're scanned in.
+const studentColumns = "id, name, age, deleted_at"
+
+type ListOptions struct {
+	// OnlyDeleted lists the trash — soft-deleted students — instead of the live ones.
+	OnlyDeleted bool
+	// AfterId and Limit page through the students, which are listed in id order: only those after AfterId are listed,
+	// at most Limit of them. Zero values don't page.
+	AfterId int
+	Limit   int
+	// Fields are the columns to read, from studentFields. Empty reads them all, the others are left zero.
+	Fields []string
+}
+
+// maxListLimit caps how many students a page can hold.
+const maxListLimit = 1000
+
 type Server struct {
-	Store  Store
-	Logger *slog.Logger
+	Store          Store
+	Batch          BatchStore
+	Search         SearchStore
+	Stats          StatsStore
+	Courses        CourseStore
+	Enrollments    EnrollmentStore
+	Grades         GradeStore
+	Attendance     AttendanceStore
+	Keys           APIKeyStore
+	Audit          AuditStore
+	Trash          TrashStore
+	Revisions      RevisionStore
+	Idempotency    IdempotencyStore
+	Webhooks       WebhookStore
+	Events         *EventBroker
+	Watch          WatchStore
+	Import         ImportStore
+	Streamer       StudentStreamer
+	Validator      *OpenAPIValidator
+	IdempotencyTTL time.Duration
+	JWT            *JWTVerifier
+	Policy         *Policy
+	Logger         *slog.Logger
 }
 
 func NewServer(s Store) *Server {
@@ -33,23 +73,139 @@ func NewServer(s Store) *Server {
 		Store:  s,
 		Logger: logger,
 	}
+
+	if batch, ok := s.(BatchStore); ok {
+		srv.Batch = batch
+	}
+	if search, ok := s.(SearchStore); ok {
+		srv.Search = search
+	}
+	if stats, ok := s.(StatsStore); ok {
+		srv.Stats = stats
+	}
+	if courses, ok := s.(CourseStore); ok {
+		srv.Courses = courses
+	}
+	if enrollments, ok := s.(EnrollmentStore); ok {
+		srv.Enrollments = enrollments
+	}
+	if grades, ok := s.(GradeStore); ok {
+		srv.Grades = grades
+	}
+	if attendance, ok := s.(AttendanceStore); ok {
+		srv.Attendance = attendance
+	}
+	if keys, ok := s.(APIKeyStore); ok {
+		srv.Keys = keys
+	}
+	if audit, ok := s.(AuditStore); ok {
+		srv.Audit = audit
+	}
+	if trash, ok := s.(TrashStore); ok {
+		srv.Trash = trash
+	}
+	if revisions, ok := s.(RevisionStore); ok {
+		srv.Revisions = revisions
+	}
+	if idempotency, ok := s.(IdempotencyStore); ok {
+		srv.Idempotency = idempotency
+	}
+	if webhooks, ok := s.(WebhookStore); ok {
+		srv.Webhooks = webhooks
+	}
+	if watch, ok := s.(WatchStore); ok {
+		srv.Watch = watch
+	}
+	if imp, ok := s.(ImportStore); ok {
+		srv.Import = imp
+	}
+	if streamer, ok := s.(StudentStreamer); ok {
+		srv.Streamer = streamer
+	}
 	return srv
 }
 
+// parseListOptions reads the filters of a list of students from the query. It responds itself when they're invalid,
+// or not allowed.
+func (s *Server) parseListOptions(w http.ResponseWriter, r *http.Request) (ListOptions, bool) {
+	var opts ListOptions
+	switch r.URL.Query().Get("deleted") {
+	case "":
+	case "only":
+		// the trash is for admins only, which the route alone can't tell.
+		if !s.require(w, r, ScopeStudentsDelete) {
+			return opts, false
+		}
+		opts.OnlyDeleted = true
+	default:
+		RespondWithError(w, "Invalid value for deleted, expected \"only\"", http.StatusBadRequest)
+		return opts, false
+	}
+
+	if v := r.URL.Query().Get("after_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 0 {
+			RespondWithError(w, errInvalidParam("after_id").Error(), http.StatusBadRequest)
+			return opts, false
+		}
+		opts.AfterId = id
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			RespondWithError(w, errInvalidParam("limit").Error(), http.StatusBadRequest)
+			return opts, false
+		}
+		opts.Limit = min(limit, maxListLimit)
+	}
+	return opts, true
+}
+
 func (s *Server) ListStudents(w http.ResponseWriter, r *http.Request) {
-	students, err := s.Store.ListStudents()
+	opts, ok := s.parseListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("watch") == "true" {
+		if opts.OnlyDeleted {
+			RespondWithError(w, "Watching deleted students is not supported", http.StatusBadRequest)
+			return
+		}
+		s.watchStudents(w, r)
+		return
+	}
+
+	rep, ok := s.negotiate(w, r, true)
+	if !ok {
+		return
+	}
+	p, ok := s.parseProjection(w, r, rep)
+	if !ok {
+		return
+	}
+	opts.Fields = p.columns()
+
+	students, resourceVersion, err := s.listStudents(r.Context(), opts)
 	if err != nil {
 		RespondWithError(w, "Failed to list students", http.StatusInternalServerError)
 		return
 	}
+	if students == nil {
+		students = []Student{}
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(students)
+	body, err := s.projectList(r.Context(), p, students)
 	if err != nil {
-		s.Logger.Error("error encoding response", "error", err)
-		RespondWithError(w, "Internal error", http.StatusInternalServerError)
+		s.Logger.Error("error embedding related resources", "error", err)
+		RespondWithError(w, "Failed to list students", http.StatusInternalServerError)
 		return
 	}
+
+	if resourceVersion != "" {
+		w.Header().Set(ResourceVersionHeader, resourceVersion)
+	}
+	s.render(w, rep, http.StatusOK, body)
 }
 
 func (s *Server) CreateStudent(w http.ResponseWriter, r *http.Request) {
@@ -59,19 +215,18 @@ func (s *Server) CreateStudent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var student Student
-	if err := json.NewDecoder(r.Body).Decode(&student); err != nil {
-		s.Logger.Error("error unmarshalling request body", "error", err)
-		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+	if !s.decode(w, r, &student) {
 		return
 	}
 
-	err := s.Store.CreateStudent(student)
+	err := s.Store.CreateStudent(r.Context(), student)
 	if err != nil {
 		s.Logger.Error("error creating student", "error", err)
 		RespondWithError(w, "Error creating student", http.StatusInternalServerError)
 		return
 	}
 
+	s.notifyEvents()
 	w.WriteHeader(http.StatusCreated)
 	// TODO: why not set application type content/json
 	_, _ = w.Write([]byte("student created successfully"))
@@ -107,7 +262,21 @@ func (s *Server) GetStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	student, err := s.Store.GetStudent(studentId)
+	rep, ok := s.negotiate(w, r, false)
+	if !ok {
+		return
+	}
+	p, ok := s.parseProjection(w, r, rep)
+	if !ok {
+		return
+	}
+
+	if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+		s.getStudentAsOf(w, r, rep, p, studentId, asOf)
+		return
+	}
+
+	student, err := s.Store.GetStudent(r.Context(), studentId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			msg := fmt.Sprintf("No student found with id %q", studentId)
@@ -122,12 +291,18 @@ func (s *Server) GetStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err = json.NewEncoder(w).Encode(student); err != nil {
-		s.Logger.Error("error encoding response", "error", err)
-		RespondWithError(w, "Internal Error", http.StatusInternalServerError)
+	s.renderStudent(w, r, rep, p, *student)
+}
+
+// renderStudent renders a student as the projection asks.
+func (s *Server) renderStudent(w http.ResponseWriter, r *http.Request, rep representation, p projection, student Student) {
+	body, err := s.project(r.Context(), p, student)
+	if err != nil {
+		s.Logger.Error("error embedding related resources", "studentId", student.Id, "error", err)
+		RespondWithError(w, "Error getting student", http.StatusInternalServerError)
 		return
 	}
+	s.render(w, rep, http.StatusOK, body)
 }
 
 func (s *Server) UpdateStudent(w http.ResponseWriter, r *http.Request) {
@@ -139,13 +314,11 @@ func (s *Server) UpdateStudent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var payload Student
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		s.Logger.Error("error unmarshalling request body", "error", err)
-		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+	if !s.decode(w, r, &payload) {
 		return
 	}
 
-	err := s.Store.UpdateStudent(studentId, payload)
+	err := s.Store.UpdateStudent(r.Context(), studentId, payload)
 	if err != nil {
 		s.Logger.Error("error updating student", "studentId", studentId, "error", err)
 		msg := fmt.Sprintf("Error updating student with id %q. Error: %v", studentId, err)
@@ -153,6 +326,7 @@ func (s *Server) UpdateStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.notifyEvents()
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -164,18 +338,26 @@ func (s *Server) DeleteStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.Store.DeleteStudent(studentId); err != nil {
+	if err := s.Store.DeleteStudent(r.Context(), studentId); err != nil {
 		s.Logger.Error("error deleting student", "studentId", studentId, "error", err)
 
 		errMsg, statusCode := "error deleting student", http.StatusInternalServerError
-		if err.Error() == errStudentNotFound {
+		switch err.Error() {
+		case errStudentNotFound:
 			errMsg, statusCode = "student not found", http.StatusNotFound
+		case errStudentEnrolled:
+			RespondWithJSONError(w, http.StatusConflict, ErrorResponse{
+				Error:   "student_enrolled",
+				Message: "the student is enrolled, or waitlisted, in courses, withdraw it from them first",
+			})
+			return
 		}
 
 		RespondWithError(w, errMsg, statusCode)
 		return
 	}
 
+	s.notifyEvents()
 	w.WriteHeader(http.StatusNoContent)
 	_, _ = w.Write([]byte("student deleted"))
 }
@@ -1,9 +1,29 @@
 package student
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 )
 
+// ErrorResponse is the body of errors that clients are expected to act on programmatically.
+type ErrorResponse struct {
+	Error   string         `json:"error"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
 func RespondWithError(w http.ResponseWriter, msg string, status int) {
 	http.Error(w, msg, status)
 }
+
+func RespondWithJSONError(w http.ResponseWriter, status int, body ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func errInvalidParam(name string) error {
+	return fmt.Errorf("invalid %s", name)
+}
@@ -0,0 +1,172 @@
+package student
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// watch event types, as a Kubernetes client expects them.
+const (
+	WatchAdded    = "ADDED"
+	WatchModified = "MODIFIED"
+	WatchDeleted  = "DELETED"
+	// WatchBookmark carries no change, only the resource version the watch has caught up to.
+	WatchBookmark = "BOOKMARK"
+)
+
+// ResourceVersionHeader is the header lists carry their resource version in.
+const ResourceVersionHeader = "X-Resource-Version"
+
+// WatchEvent is a single line of a watch. Resource versions are change event ids: a list is current as of its resource
+// version, and a watch started from it sees every change after it.
+type WatchEvent struct {
+	Type            string   `json:"type"`
+	ResourceVersion int64    `json:"resource_version"`
+	Object          *Student `json:"object,omitempty"`
+}
+
+// watchEventType maps a change event to what it means for the list of live students. Purges have no effect on it, the
+// student was already deleted. Neither do reverts of a deleted student to another deleted state.
+func watchEventType(e Event) string {
+	switch e.Type {
+	case AuditStudentCreated, AuditStudentRestored:
+		return WatchAdded
+	case AuditStudentUpdated, AuditStudentReverted:
+		// a revert can bring a student back from the trash, or send it there.
+		wasDeleted := e.Previous != nil && e.Previous.DeletedAt != nil
+		isDeleted := e.Student != nil && e.Student.DeletedAt != nil
+		switch {
+		case wasDeleted && isDeleted:
+			return ""
+		case wasDeleted:
+			return WatchAdded
+		case isDeleted:
+			return WatchDeleted
+		}
+		return WatchModified
+	case AuditStudentDeleted:
+		return WatchDeleted
+	default:
+		return ""
+	}
+}
+
+func encodeWatchEvent(e Event) (string, error) {
+	typ := watchEventType(e)
+	if typ == "" {
+		return "", nil
+	}
+	return marshalWatchEvent(WatchEvent{Type: typ, ResourceVersion: e.Id, Object: e.Student})
+}
+
+func watchBookmark(lastId int64) string {
+	line, _ := marshalWatchEvent(WatchEvent{Type: WatchBookmark, ResourceVersion: lastId})
+	return line
+}
+
+func marshalWatchEvent(we WatchEvent) (string, error) {
+	line, err := json.Marshal(we)
+	if err != nil {
+		return "", err
+	}
+	return string(line) + "\n", nil
+}
+
+// listStudents lists students, along with their resource version when the store keeps one.
+func (s *Server) listStudents(ctx context.Context, opts ListOptions) ([]Student, string, error) {
+	if s.Watch == nil {
+		students, err := s.Store.ListStudents(ctx, opts)
+		return students, "", err
+	}
+
+	students, resourceVersion, err := s.Watch.ListStudentsVersioned(ctx, opts)
+	return students, strconv.FormatInt(resourceVersion, 10), err
+}
+
+// errResourceVersionTooOld means the history since a resource version has been compacted away.
+type errResourceVersionTooOld struct {
+	resourceVersion, oldest int64
+}
+
+func (e errResourceVersionTooOld) Error() string {
+	return fmt.Sprintf("resource version %d is too old, the oldest is %d", e.resourceVersion, e.oldest)
+}
+
+// beginWatch returns what a watch from resourceVersion starts with. Without one, that's a list of the live students and
+// the resource version it's current as of. Resource versions older than the outbox holds fail with an
+// errResourceVersionTooOld.
+func (s *Server) beginWatch(ctx context.Context, resourceVersion *int64) ([]Student, int64, error) {
+	if resourceVersion == nil {
+		return s.Watch.ListStudentsVersioned(ctx, ListOptions{})
+	}
+
+	compacted, err := s.Watch.CompactedEventId(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if *resourceVersion < compacted {
+		return nil, 0, errResourceVersionTooOld{resourceVersion: *resourceVersion, oldest: compacted}
+	}
+	return nil, *resourceVersion, nil
+}
+
+// watchStudents streams changes to the live students as newline delimited WatchEvents, from resourceVersion on. Without
+// one, the watch starts with an ADDED event for every student. Watching from before the outbox was compacted is
+// answered with a 410, and the client is expected to list again.
+func (s *Server) watchStudents(w http.ResponseWriter, r *http.Request) {
+	if s.Events == nil || s.Watch == nil {
+		RespondWithError(w, "Watching students is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	var from *int64
+	if v := r.URL.Query().Get("resourceVersion"); v != "" {
+		rv, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || rv < 0 {
+			RespondWithError(w, "Invalid resourceVersion", http.StatusBadRequest)
+			return
+		}
+		from = &rv
+	}
+
+	ctx := r.Context()
+	initial, resourceVersion, err := s.beginWatch(ctx, from)
+	if err != nil {
+		var tooOld errResourceVersionTooOld
+		if errors.As(err, &tooOld) {
+			RespondWithJSONError(w, http.StatusGone, ErrorResponse{
+				Error:   "resource_version_too_old",
+				Message: "the history since this resource version has been compacted, list again",
+				Details: map[string]any{"resource_version": tooOld.resourceVersion, "oldest_resource_version": tooOld.oldest},
+			})
+			return
+		}
+
+		s.Logger.Error("error starting watch", "error", err)
+		RespondWithError(w, "Failed to watch students", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sw := newStreamWriter(w)
+	for _, student := range initial {
+		line, err := marshalWatchEvent(WatchEvent{Type: WatchAdded, ResourceVersion: resourceVersion, Object: &student})
+		if err != nil || sw.write(line) != nil {
+			return
+		}
+	}
+	if err := sw.rc.Flush(); err != nil {
+		s.Logger.Error("error flushing watch", "error", err)
+		return
+	}
+
+	s.streamEvents(ctx, sw.eventStream(resourceVersion, encodeWatchEvent, watchBookmark))
+}
@@ -0,0 +1,490 @@
+package student
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// webhook delivery statuses. A delivery is pending until it succeeds, or it's dead-lettered after too many attempts.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// headers sent with every webhook delivery.
+const (
+	WebhookIdHeader        = "X-Webhook-Id"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookBackoff     = 10 * time.Second
+	defaultWebhookMaxBackoff  = time.Hour
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultWebhookBatch       = 100
+)
+
+// WebhookEvents are the event types webhooks can subscribe to. Restores and reverts are events of their own rather than
+// updates, so a webhook that keeps track of students should subscribe to them too.
+var WebhookEvents = []string{
+	AuditStudentCreated, AuditStudentUpdated, AuditStudentDeleted, AuditStudentRestored, AuditStudentReverted,
+	EventAttendanceLow,
+}
+
+// Webhook is a subscription to student events, delivered by POSTing them to URL.
+type Webhook struct {
+	Id     int      `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs the deliveries. It's only ever returned when the webhook is created.
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is an event on its way to a webhook, and the record of how that went.
+type WebhookDelivery struct {
+	Id             int64           `json:"id"`
+	WebhookId      int             `json:"webhook_id"`
+	EventId        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// SignWebhook is the signature of a delivery: the hex HMAC-SHA256, keyed with the webhook's secret, of the timestamp
+// header, a dot, and the body. Receivers should recompute it and compare in constant time.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func validateWebhook(wh Webhook) map[string]any {
+	details := make(map[string]any)
+
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		details["url"] = "must be an absolute http or https URL"
+	}
+
+	if len(wh.Events) == 0 {
+		details["events"] = fmt.Sprintf("must list at least one of %v", WebhookEvents)
+	}
+	for _, event := range wh.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			details["events"] = fmt.Sprintf("unknown event %q, must be one of %v", event, WebhookEvents)
+		}
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// WebhooksHandler lists the webhooks, or registers a new one.
+func (s *Server) WebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if s.Webhooks == nil {
+		RespondWithError(w, "Webhooks are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		webhooks, err := s.Webhooks.ListWebhooks(r.Context())
+		if err != nil {
+			s.Logger.Error("error listing webhooks", "error", err)
+			RespondWithError(w, "Failed to list webhooks", http.StatusInternalServerError)
+			return
+		}
+		if webhooks == nil {
+			webhooks = []Webhook{}
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, webhooks)
+	case http.MethodPost:
+		s.createWebhook(w, r)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.Logger.Error("error unmarshalling request body", "error", err)
+		RespondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	wh := Webhook{URL: req.URL, Events: req.Events, Secret: req.Secret}
+	if details := validateWebhook(wh); details != nil {
+		RespondWithJSONError(w, http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_webhook",
+			Message: "the webhook is invalid",
+			Details: details,
+		})
+		return
+	}
+
+	if wh.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			s.Logger.Error("error generating webhook secret", "error", err)
+			RespondWithError(w, "Error creating webhook", http.StatusInternalServerError)
+			return
+		}
+		wh.Secret = secret
+	}
+
+	id, err := s.Webhooks.CreateWebhook(r.Context(), wh)
+	if err != nil {
+		s.Logger.Error("error creating webhook", "error", err)
+		RespondWithError(w, "Error creating webhook", http.StatusInternalServerError)
+		return
+	}
+
+	created, err := s.Webhooks.GetWebhook(r.Context(), id)
+	if err != nil {
+		s.Logger.Error("error reading webhook", "webhookId", id, "error", err)
+		RespondWithError(w, "Error creating webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/webhooks/%d", id))
+	respondWithJSON(w, s.Logger, http.StatusCreated, struct {
+		*Webhook
+		Secret string `json:"secret"`
+	}{created, created.Secret})
+}
+
+// WebhookHandler reads or deletes a single webhook.
+func (s *Server) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid webhookId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Webhooks == nil {
+		RespondWithError(w, "Webhooks are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		wh, err := s.Webhooks.GetWebhook(r.Context(), webhookId)
+		if err != nil {
+			s.respondWebhookError(w, "error reading webhook", webhookId, err)
+			return
+		}
+		respondWithJSON(w, s.Logger, http.StatusOK, wh)
+	case http.MethodDelete:
+		if err := s.Webhooks.DeleteWebhook(r.Context(), webhookId); err != nil {
+			s.respondWebhookError(w, "error deleting webhook", webhookId, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// WebhookDeliveries is the delivery log of a webhook, newest first.
+func (s *Server) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid webhookId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Webhooks == nil {
+		RespondWithError(w, "Webhooks are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	if _, err := s.Webhooks.GetWebhook(r.Context(), webhookId); err != nil {
+		s.respondWebhookError(w, "error reading webhook", webhookId, err)
+		return
+	}
+
+	deliveries, err := s.Webhooks.ListWebhookDeliveries(r.Context(), webhookId)
+	if err != nil {
+		s.Logger.Error("error listing webhook deliveries", "webhookId", webhookId, "error", err)
+		RespondWithError(w, "Failed to list webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []WebhookDelivery{}
+	}
+	respondWithJSON(w, s.Logger, http.StatusOK, deliveries)
+}
+
+// RetryWebhookDelivery sends a delivery again, from scratch. It's how dead letters are redriven.
+func (s *Server) RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondWithError(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	webhookId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		RespondWithError(w, "Invalid webhookId", http.StatusBadRequest)
+		return
+	}
+	deliveryId, err := strconv.ParseInt(r.PathValue("deliveryId"), 10, 64)
+	if err != nil {
+		RespondWithError(w, "Invalid deliveryId", http.StatusBadRequest)
+		return
+	}
+
+	if s.Webhooks == nil {
+		RespondWithError(w, "Webhooks are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	if err := s.Webhooks.RetryWebhookDelivery(r.Context(), webhookId, deliveryId); err != nil {
+		s.respondWebhookError(w, "error retrying webhook delivery", webhookId, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// WebhookDeadLetters lists the deliveries, across all webhooks, that were given up on.
+func (s *Server) WebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if s.Webhooks == nil {
+		RespondWithError(w, "Webhooks are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	deliveries, err := s.Webhooks.ListDeadWebhookDeliveries(r.Context())
+	if err != nil {
+		s.Logger.Error("error listing dead webhook deliveries", "error", err)
+		RespondWithError(w, "Failed to list dead webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []WebhookDelivery{}
+	}
+	respondWithJSON(w, s.Logger, http.StatusOK, deliveries)
+}
+
+func (s *Server) respondWebhookError(w http.ResponseWriter, msg string, webhookId int, err error) {
+	s.Logger.Error(msg, "webhookId", webhookId, "error", err)
+
+	switch err.Error() {
+	case errWebhookNotFound:
+		RespondWithError(w, "webhook not found", http.StatusNotFound)
+	case errWebhookDeliveryNotFound:
+		RespondWithError(w, "webhook delivery not found", http.StatusNotFound)
+	default:
+		RespondWithError(w, msg, http.StatusInternalServerError)
+	}
+}
+
+func respondWithJSON(w http.ResponseWriter, logger *slog.Logger, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("error encoding response", "error", err)
+	}
+}
+
+// WebhookSink fans events out to the webhooks subscribed to them. It only queues the deliveries, WebhookDeliverer
+// sends them.
+type WebhookSink struct {
+	Webhooks WebhookStore
+}
+
+func (s *WebhookSink) Deliver(ctx context.Context, e Event) error {
+	if !slices.Contains(WebhookEvents, e.Type) {
+		return nil
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.Webhooks.EnqueueWebhookDeliveries(ctx, e, payload)
+}
+
+// WebhookDeliverer sends queued webhook deliveries. A failed delivery is retried with exponential backoff, and
+// dead-lettered once it has failed MaxAttempts times. Any 2xx response counts as delivered.
+type WebhookDeliverer struct {
+	Webhooks WebhookStore
+	Client   *http.Client
+	Logger   *slog.Logger
+
+	Interval    time.Duration
+	MaxAttempts int
+	// Backoff is the wait after the first failure, doubled after every failure after that, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Run sends due deliveries every interval, until ctx is done.
+func (d *WebhookDeliverer) Run(ctx context.Context) {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil {
+			d.Logger.Error("error delivering webhooks", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue makes a single attempt at every delivery that's due, and returns how many it attempted.
+func (d *WebhookDeliverer) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := d.Webhooks.DueWebhookDeliveries(ctx, time.Now(), defaultWebhookBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := make(map[int]*Webhook)
+	for _, delivery := range deliveries {
+		wh, ok := webhooks[delivery.WebhookId]
+		if !ok {
+			wh, err = d.Webhooks.GetWebhook(ctx, delivery.WebhookId)
+			if err != nil {
+				return 0, err
+			}
+			webhooks[delivery.WebhookId] = wh
+		}
+
+		d.attempt(ctx, wh, &delivery)
+		if err := d.Webhooks.RecordWebhookAttempt(ctx, delivery); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends the delivery once, and updates it with the outcome.
+func (d *WebhookDeliverer) attempt(ctx context.Context, wh *Webhook, delivery *WebhookDelivery) {
+	delivery.Attempts++
+	delivery.ResponseStatus = 0
+	delivery.LastError = ""
+
+	status, err := d.send(ctx, wh, delivery)
+	delivery.ResponseStatus = status
+	if err == nil {
+		now := time.Now()
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.maxAttempts() {
+		d.Logger.Warn("webhook delivery dead-lettered", "webhookId", wh.Id, "deliveryId", delivery.Id,
+			"attempts", delivery.Attempts, "error", err)
+		delivery.Status = WebhookDeliveryDead
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	next := time.Now().Add(d.backoff(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+}
+
+func (d *WebhookDeliverer) send(ctx context.Context, wh *Webhook, delivery *WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIdHeader, strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(wh.Secret, timestamp, delivery.Payload))
+
+	client := d.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDeliverer) backoff(attempts int) time.Duration {
+	backoff, maxBackoff := d.Backoff, d.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultWebhookBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultWebhookMaxBackoff
+	}
+
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+func (d *WebhookDeliverer) maxAttempts() int {
+	if d.MaxAttempts <= 0 {
+		return defaultWebhookMaxAttempts
+	}
+	return d.MaxAttempts
+}
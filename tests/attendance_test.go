@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// rates renders attendance summaries as student:rate, with a ! for those below the threshold.
+func rates(summaries []student.AttendanceSummary) string {
+	var s []string
+	for _, a := range summaries {
+		rate := "-"
+		if a.Rate != nil {
+			rate = fmt.Sprint(*a.Rate)
+		}
+		if a.BelowThreshold {
+			rate += "!"
+		}
+		s = append(s, fmt.Sprintf("%d:%s", a.StudentId, rate))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestAttendance_Marking(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	ctx := context.Background()
+	courseId := newEnrollmentTest(t, store, 10, 4)
+	for id := 1; id <= 3; id++ {
+		_, _ = store.Enroll(ctx, courseId, id)
+	}
+
+	url := target.URL + "/api/v1/courses/1/sessions"
+	for _, body := range []string{
+		`{"topic":"Monitoring","starts_at":"2026-11-09T10:00:00Z"}`,
+		`{"topic":"Introduction","starts_at":"2026-11-02T10:00:00+01:00"}`,
+	} {
+		if status, body := sendWithKey(t, http.MethodPost, url, key, body); status != http.StatusCreated {
+			t.Fatalf("expected status %d creating a session, got %d: %s", http.StatusCreated, status, body)
+		}
+	}
+	var sessions []student.ClassSession
+	_, body := getWithKey(t, url, key, "")
+	_ = json.Unmarshal([]byte(body), &sessions)
+	if len(sessions) != 2 || sessions[0].Topic != "Introduction" || !sessions[0].StartsAt.Equal(time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the sessions in the order they start, got %s", body)
+	}
+
+	// everyone not listed is marked with the status.
+	status, body := sendWithKey(t, http.MethodPut, url+"/2/attendance", key,
+		`{"status":"present","marks":[{"student_id":2,"status":"late"},{"student_id":3,"status":"absent"}]}`)
+	if status != http.StatusNoContent {
+		t.Fatalf("expected status %d marking attendance, got %d: %s", http.StatusNoContent, status, body)
+	}
+	var marks []student.AttendanceMark
+	_, body = getWithKey(t, url+"/2/attendance", key, "")
+	_ = json.Unmarshal([]byte(body), &marks)
+	if len(marks) != 3 || marks[0].Status != "present" || marks[1].Status != "late" || marks[2].Status != "absent" {
+		t.Errorf("expected students 1, 2 and 3 to be present, late and absent, got %s", body)
+	}
+	_, _ = sendWithKey(t, http.MethodPut, url+"/1/attendance", key,
+		`{"marks":[{"student_id":1,"status":"excused"},{"student_id":2,"status":"present"},{"student_id":3,"status":"absent"}]}`)
+
+	// nothing is marked when one of the students can't be.
+	status, body = sendWithKey(t, http.MethodPut, url+"/1/attendance", key,
+		`{"marks":[{"student_id":3,"status":"present"},{"student_id":4,"status":"present"}]}`)
+	if status != http.StatusConflict || !strings.Contains(body, `"not_enrolled"`) || !strings.Contains(body, `"student_id":4`) {
+		t.Errorf("expected marking a student that isn't enrolled to be a conflict, got %d %s", status, body)
+	}
+
+	var summaries []student.AttendanceSummary
+	_, body = getWithKey(t, target.URL+"/api/v1/courses/1/attendance", key, "")
+	_ = json.Unmarshal([]byte(body), &summaries)
+	if got, want := rates(summaries), "1:100 2:100 3:0!"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if len(summaries) == 3 && (summaries[0].Sessions != 2 || summaries[0].Excused != 1 || summaries[1].Late != 1) {
+		t.Errorf("expected the marks to be counted, got %s", body)
+	}
+	_, body = getWithKey(t, target.URL+"/api/v1/courses/1/attendance?below_threshold=true", key, "")
+	_ = json.Unmarshal([]byte(body), &summaries)
+	if got, want := rates(summaries), "3:0!"; got != want {
+		t.Errorf("expected only the students below the threshold, %s, got %s", want, got)
+	}
+	_, body = getWithKey(t, target.URL+"/api/v1/students/2/attendance", key, "")
+	_ = json.Unmarshal([]byte(body), &summaries)
+	if got, want := rates(summaries), "2:100"; got != want || summaries[0].CourseId != courseId {
+		t.Errorf("expected the attendance of student 2, %s, got %s", want, body)
+	}
+
+	for _, tc := range []struct {
+		body, field string
+	}{
+		{`{}`, `"marks"`},
+		{`{"status":"asleep"}`, `"status"`},
+		{`{"marks":[{"student_id":1,"status":"gone"}]}`, `"marks[0].status"`},
+		{`{"marks":[{"status":"present"}]}`, `"marks[0].student_id"`},
+		{`{"marks":[{"student_id":1,"status":"present"},{"student_id":1,"status":"late"}]}`, `"marks[1].student_id"`},
+	} {
+		status, body := sendWithKey(t, http.MethodPut, url+"/1/attendance", key, tc.body)
+		if status != http.StatusBadRequest || !strings.Contains(body, "invalid_attendance") || !strings.Contains(body, tc.field) {
+			t.Errorf("expected %s to be invalid in %s, got %d %s", tc.field, tc.body, status, body)
+		}
+	}
+	if status, body = sendWithKey(t, http.MethodPost, url, key, `{"topic":" "}`); status != http.StatusBadRequest ||
+		!strings.Contains(body, `"topic"`) || !strings.Contains(body, `"starts_at"`) {
+		t.Errorf("expected the session to be invalid, got %d %s", status, body)
+	}
+	if status, _ = getWithKey(t, url+"/9/attendance", key, ""); status != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestAttendance_Alerts(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	store.AttendanceThreshold = 50
+	ctx := context.Background()
+	courseId := newEnrollmentTest(t, store, 10, 2)
+	_, _ = store.Enroll(ctx, courseId, 1)
+	_, _ = store.Enroll(ctx, courseId, 2)
+
+	start := time.Date(2026, 11, 2, 10, 0, 0, 0, time.UTC)
+	var sessionIds []int
+	for i := range 5 {
+		id, err := store.CreateSession(ctx, student.ClassSession{CourseId: courseId, Topic: "Session", StartsAt: start.AddDate(0, 0, 7*i)})
+		if err != nil {
+			t.Fatalf("Error creating session: %v", err)
+		}
+		sessionIds = append(sessionIds, id)
+	}
+	mark := func(session int, status string) {
+		t.Helper()
+		marks := []student.AttendanceMark{{StudentId: 1, Status: status}}
+		if err := store.MarkAttendance(ctx, courseId, sessionIds[session], marks, student.AttendancePresent); err != nil {
+			t.Fatalf("Error marking attendance: %v", err)
+		}
+	}
+	alerts := func() []student.Event {
+		t.Helper()
+		events, err := store.EventsAfter(ctx, 0, 100)
+		if err != nil {
+			t.Fatalf("Error reading events: %v", err)
+		}
+		var alerts []student.Event
+		for _, e := range events {
+			if e.Type == student.EventAttendanceLow {
+				alerts = append(alerts, e)
+			}
+		}
+		return alerts
+	}
+
+	// 1 of 2 is right at the threshold, 1 of 3 is below it, and 1 of 4 is still below it.
+	mark(0, student.AttendancePresent)
+	mark(1, student.AttendanceAbsent)
+	if got := alerts(); len(got) != 0 {
+		t.Fatalf("expected no alert at the threshold, got %+v", got)
+	}
+	mark(2, student.AttendanceAbsent)
+	mark(3, student.AttendanceAbsent)
+	got := alerts()
+	if len(got) != 1 || got[0].StudentId != 1 || got[0].Attendance == nil || *got[0].Attendance.Rate != 33.33 {
+		t.Fatalf("expected a single alert for student 1 at 33.33%%, got %+v", got)
+	}
+
+	// excused absences don't count, and the student is alerted on again once it falls below the threshold again.
+	mark(2, student.AttendanceExcused)
+	mark(3, student.AttendanceExcused)
+	if got := alerts(); len(got) != 1 {
+		t.Fatalf("expected no alert going back up, got %+v", got)
+	}
+	mark(4, student.AttendanceAbsent)
+	if got := alerts(); len(got) != 2 {
+		t.Errorf("expected a second alert, got %+v", got)
+	}
+
+	if err := store.DeleteSession(ctx, courseId, sessionIds[4]); err != nil {
+		t.Fatalf("Error deleting session: %v", err)
+	}
+	summaries, _ := store.CourseAttendance(ctx, courseId)
+	if got, want := rates(summaries), "1:50 2:100"; got != want {
+		t.Errorf("expected the marks of the session to go with it, %s, got %s", want, got)
+	}
+
+	if err := store.DeleteCourse(ctx, courseId); err != nil {
+		t.Fatalf("Error deleting course: %v", err)
+	}
+	if _, err := store.GetSession(ctx, courseId, sessionIds[0]); err == nil || err.Error() != "session not found" {
+		t.Errorf("expected the sessions to go with the course, got %v", err)
+	}
+	if summaries, err := store.StudentAttendance(ctx, 1); err != nil || len(summaries) != 0 {
+		t.Errorf("expected no attendance once the course is deleted, got %+v %v", summaries, err)
+	}
+}
+
+func TestAttendance_AlertsNeedAttendanceRead(t *testing.T) {
+	store, s, target, _ := newAPITest(t)
+	s.Events = student.NewEventBroker(store, s.Logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := s.Events.Start(ctx); err != nil {
+		t.Fatalf("Error starting event broker: %v", err)
+	}
+
+	courseId := newEnrollmentTest(t, store, 10, 1)
+	_, _ = store.Enroll(ctx, courseId, 1)
+	sessionId, _ := store.CreateSession(ctx, student.ClassSession{CourseId: courseId, Topic: "Introduction", StartsAt: time.Now()})
+	lastId, _ := store.LatestEventId(ctx)
+
+	marks := []student.AttendanceMark{{StudentId: 1, Status: student.AttendanceAbsent}}
+	if err := store.MarkAttendance(ctx, courseId, sessionId, marks, ""); err != nil {
+		t.Fatalf("Error marking attendance: %v", err)
+	}
+	_ = store.CreateStudent(ctx, student.Student{Name: "Dutta", Age: 33})
+
+	for _, tc := range []struct {
+		scopes []string
+		event  string
+	}{
+		{[]string{student.ScopeStudentsRead}, student.AuditStudentCreated},
+		{[]string{student.ScopeStudentsRead, student.ScopeAttendanceRead}, student.EventAttendanceLow},
+	} {
+		plain, key, _ := student.GenerateAPIKey("events", tc.scopes)
+		_, _ = store.CreateAPIKey(ctx, key)
+
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, target.URL+"/api/v1/students/events", nil)
+		request.Header.Set("X-API-Key", plain)
+		request.Header.Set("Last-Event-ID", fmt.Sprint(lastId))
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("Error opening event stream: %v", err)
+		}
+		t.Cleanup(func() { response.Body.Close() })
+
+		if msg := nextEvent(t, readSSE(bufio.NewReader(response.Body))); msg.event != tc.event {
+			t.Errorf("%v: expected %s first, got %+v", tc.scopes, tc.event, msg)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// newTestSQLiteStore returns a store backed by a fresh sqlite database in the test's temp dir.
+func newTestSQLiteStore(t *testing.T) *student.SQLiteDataStore {
+	t.Helper()
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "students.db"))
+	return student.NewSQLiteDataStore()
+}
+
+// asActor returns a context that looks like it came through Authenticate and RequestID.
+func asActor(subject, requestId string) context.Context {
+	ctx := context.WithValue(context.Background(), student.PrincipalKey, &student.Principal{Subject: subject})
+	return context.WithValue(ctx, student.RequestIdKey, requestId)
+}
+
+func TestAudit_RecordsMutations(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	if err := store.CreateStudent(asActor("alice", "req-1"), student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+	if err := store.UpdateStudent(asActor("bob", "req-2"), 1, student.Student{Name: "Swagnik", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+	if err := store.DeleteStudent(asActor("carol", "req-3"), 1); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+
+	studentId := 1
+	events, err := store.ListAuditEvents(context.Background(), student.AuditFilter{StudentId: &studentId})
+	if err != nil {
+		t.Fatalf("Error listing audit events: %v", err)
+	}
+
+	want := []struct{ actor, action, requestId string }{
+		{"carol", student.AuditStudentDeleted, "req-3"},
+		{"bob", student.AuditStudentUpdated, "req-2"},
+		{"alice", student.AuditStudentCreated, "req-1"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(events))
+	}
+	for i, w := range want {
+		if events[i].Actor != w.actor || events[i].Action != w.action || events[i].RequestId != w.requestId {
+			t.Errorf("event %d: expected %+v, got %+v", i, w, events[i])
+		}
+	}
+
+	var before, after student.Student
+	_ = json.Unmarshal(events[1].Before, &before)
+	_ = json.Unmarshal(events[1].After, &after)
+	if before.Age != 32 || after.Age != 33 {
+		t.Errorf("expected update from age 32 to 33, got %d to %d", before.Age, after.Age)
+	}
+	if events[0].After != nil {
+		t.Errorf("expected no after snapshot on delete, got %s", events[0].After)
+	}
+}
+
+func TestAudit_FailedMutationIsNotAudited(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	if err := store.UpdateStudent(asActor("bob", "req-1"), 42, student.Student{Name: "Nobody"}); err == nil {
+		t.Fatalf("expected an error updating a missing student")
+	}
+
+	events, _ := store.ListAuditEvents(context.Background(), student.AuditFilter{})
+	if len(events) != 0 {
+		t.Errorf("expected no audit events, got %d", len(events))
+	}
+}
+
+func TestListAuditEvents_Handler(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	_ = store.CreateStudent(asActor("alice", "req-1"), student.Student{Name: "A", Age: 20})
+	_ = store.CreateStudent(asActor("bob", "req-2"), student.Student{Name: "B", Age: 21})
+
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/audit?actor=bob", nil)
+	response := httptest.NewRecorder()
+	s.ListAuditEvents(response, request)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.Code)
+	}
+
+	var events []student.AuditEvent
+	if err := json.NewDecoder(response.Body).Decode(&events); err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if len(events) != 1 || events[0].Actor != "bob" {
+		t.Errorf("expected bob's event only, got %+v", events)
+	}
+}
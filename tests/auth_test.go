@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/mocks"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+	"go.uber.org/mock/gomock"
+)
+
+// okHandler stands in for the student handlers behind the middleware, and checks the key was put in the context.
+func okHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(student.APIKeyKey).(*student.APIKey); !ok {
+			t.Errorf("expected api key in request context")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestAuthenticate_MissingKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students", nil)
+	response := httptest.NewRecorder()
+
+	s := &student.Server{
+		Store:  mocks.NewMockStore(ctrl),
+		Keys:   mocks.NewMockAPIKeyStore(ctrl),
+		Logger: NewTestLogger(),
+	}
+	s.Authenticate(okHandler(t)).ServeHTTP(response, request)
+
+	statusWant := http.StatusUnauthorized
+	statusGot := response.Code
+
+	if statusWant != statusGot {
+		t.Errorf("expected status %d, got %d", statusWant, statusGot)
+	}
+}
+
+func TestAuthenticate_UnknownKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students", nil)
+	request.Header.Set("X-API-Key", "o2n_deadbeef_nope")
+	response := httptest.NewRecorder()
+
+	mockKeys := mocks.NewMockAPIKeyStore(ctrl)
+	mockKeys.EXPECT().GetAPIKeyByHash(gomock.Any(), student.HashAPIKey("o2n_deadbeef_nope")).Return(nil, errors.New("api key not found"))
+
+	s := &student.Server{
+		Keys:   mockKeys,
+		Logger: NewTestLogger(),
+	}
+	s.Authenticate(okHandler(t)).ServeHTTP(response, request)
+
+	statusWant := http.StatusUnauthorized
+	statusGot := response.Code
+
+	if statusWant != statusGot {
+		t.Errorf("expected status %d, got %d", statusWant, statusGot)
+	}
+}
+
+func TestAuthenticate_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	plain, key, err := student.GenerateAPIKey("dashboard", []string{student.ScopeStudentsRead})
+	if err != nil {
+		t.Fatalf("Error generating api key: %v", err)
+	}
+	key.Id = 7
+
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students", nil)
+	request.Header.Set("X-API-Key", plain)
+	response := httptest.NewRecorder()
+
+	mockKeys := mocks.NewMockAPIKeyStore(ctrl)
+	mockKeys.EXPECT().GetAPIKeyByHash(gomock.Any(), key.Hash).Return(&key, nil)
+	mockKeys.EXPECT().TouchAPIKey(gomock.Any(), 7).Return(nil)
+
+	s := &student.Server{
+		Keys:   mockKeys,
+		Logger: NewTestLogger(),
+	}
+	s.Authenticate(okHandler(t)).ServeHTTP(response, request)
+
+	statusWant := http.StatusOK
+	statusGot := response.Code
+
+	if statusWant != statusGot {
+		t.Errorf("expected status %d, got %d", statusWant, statusGot)
+	}
+}
+
+func TestAuthenticate_Failure_Revoked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	plain, key, _ := student.GenerateAPIKey("dashboard", []string{student.ScopeStudentsRead})
+	revokedAt := time.Now()
+	key.RevokedAt = &revokedAt
+
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students", nil)
+	request.Header.Set("X-API-Key", plain)
+	response := httptest.NewRecorder()
+
+	mockKeys := mocks.NewMockAPIKeyStore(ctrl)
+	mockKeys.EXPECT().GetAPIKeyByHash(gomock.Any(), key.Hash).Return(&key, nil)
+
+	s := &student.Server{
+		Keys:   mockKeys,
+		Logger: NewTestLogger(),
+	}
+	s.Authenticate(okHandler(t)).ServeHTTP(response, request)
+
+	statusWant := http.StatusUnauthorized
+	statusGot := response.Code
+
+	if statusWant != statusGot {
+		t.Errorf("expected status %d, got %d", statusWant, statusGot)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/client"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func newClientTest(t *testing.T) (*student.SQLiteDataStore, *client.Client) {
+	store, _, target, key := newAPITest(t)
+	c := client.New(target.URL, key)
+	c.Backoff = time.Millisecond
+	return store, c
+}
+
+func TestClient_CRUD(t *testing.T) {
+	_, c := newClientTest(t)
+	ctx := context.Background()
+
+	if err := c.Create(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+
+	got, err := c.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Error getting student: %v", err)
+	}
+	if want := (student.Student{Id: 1, Name: "Swagnik", Age: 32}); *got != want {
+		t.Errorf("expected %+v, got %+v", want, *got)
+	}
+
+	if err := c.Update(ctx, 1, student.Student{Name: "Swagnik Dutta", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+	if got, _ := c.Get(ctx, 1); got.Name != "Swagnik Dutta" || got.Age != 33 {
+		t.Errorf("expected the update to be applied, got %+v", got)
+	}
+
+	if err := c.Delete(ctx, 1); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+	trash, err := c.List(ctx, student.ListOptions{OnlyDeleted: true})
+	if err != nil || len(trash) != 1 || trash[0].Id != 1 {
+		t.Errorf("expected student 1 in the trash, got %+v, %v", trash, err)
+	}
+}
+
+func TestClient_Paging(t *testing.T) {
+	store, c := newClientTest(t)
+	ctx := context.Background()
+	for range 7 {
+		_ = store.CreateStudent(ctx, student.Student{Name: "Someone", Age: 20})
+	}
+
+	page, err := c.List(ctx, student.ListOptions{AfterId: 2, Limit: 3})
+	if err != nil {
+		t.Fatalf("Error listing students: %v", err)
+	}
+	if len(page) != 3 || page[0].Id != 3 || page[2].Id != 5 {
+		t.Errorf("expected students 3 to 5, got %+v", page)
+	}
+
+	var ids []int
+	for s, err := range c.All(ctx, student.ListOptions{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("Error iterating students: %v", err)
+		}
+		ids = append(ids, s.Id)
+	}
+	if len(ids) != 7 || ids[0] != 1 || ids[6] != 7 {
+		t.Errorf("expected students 1 to 7, got %v", ids)
+	}
+}
+
+func TestClient_Failure_TypedErrors(t *testing.T) {
+	store, c := newClientTest(t)
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, 42)
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.RequestId == "" {
+		t.Errorf("expected a 404 *client.Error with a request id, got %#v", err)
+	}
+
+	// a viewer can't delete, and is told so with a structured error.
+	plain, key, _ := student.GenerateAPIKey("viewer", []string{student.ScopeStudentsRead})
+	_, _ = store.CreateAPIKey(ctx, key)
+	viewer := client.New(c.BaseURL, plain)
+
+	err = viewer.Delete(ctx, 1)
+	if !errors.Is(err, client.ErrForbidden) || !errors.As(err, &apiErr) || apiErr.Code != "forbidden" {
+		t.Errorf("expected a forbidden error, got %v", err)
+	}
+	if apiErr.Details["permission"] != student.ScopeStudentsDelete {
+		t.Errorf("expected the missing permission in the details, got %v", apiErr.Details)
+	}
+
+	if _, err := client.New(c.BaseURL, "nope").List(ctx, student.ListOptions{}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+// flakyProxy answers the first failures requests with status, before passing requests through to target.
+type flakyProxy struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	keys     []string
+	proxy    *httputil.ReverseProxy
+}
+
+func (p *flakyProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.keys = append(p.keys, r.Header.Get("Idempotency-Key"))
+	fail := p.failures > 0
+	p.failures--
+	p.mu.Unlock()
+
+	if fail {
+		if p.status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		http.Error(w, "try again", p.status)
+		return
+	}
+	p.proxy.ServeHTTP(w, r)
+}
+
+func newFlakyClient(t *testing.T, failures, status int) (*client.Client, *flakyProxy, *student.SQLiteDataStore) {
+	store, c := newClientTest(t)
+	target, _ := url.Parse(c.BaseURL)
+
+	flaky := &flakyProxy{failures: failures, status: status, proxy: httputil.NewSingleHostReverseProxy(target)}
+	proxy := httptest.NewServer(flaky)
+	t.Cleanup(proxy.Close)
+
+	c.BaseURL = proxy.URL
+	return c, flaky, store
+}
+
+func TestClient_RetriesWithTheSameIdempotencyKey(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		c, flaky, store := newFlakyClient(t, 2, status)
+
+		if err := c.Create(context.Background(), student.Student{Name: "Swagnik", Age: 32}); err != nil {
+			t.Fatalf("%d: Error creating student: %v", status, err)
+		}
+
+		if len(flaky.keys) != 3 || flaky.keys[0] == "" || flaky.keys[0] != flaky.keys[2] {
+			t.Errorf("%d: expected 3 attempts with the same Idempotency-Key, got %q", status, flaky.keys)
+		}
+		if students, _ := store.ListStudents(context.Background(), student.ListOptions{}); len(students) != 1 {
+			t.Errorf("%d: expected 1 student to be created, got %d", status, len(students))
+		}
+	}
+}
+
+func TestClient_Failure_RetriesExhausted(t *testing.T) {
+	c, flaky, _ := newFlakyClient(t, 10, http.StatusBadGateway)
+	c.MaxRetries = 2
+
+	_, err := c.List(context.Background(), student.ListOptions{})
+	if !errors.Is(err, client.ErrServer) {
+		t.Errorf("expected ErrServer, got %v", err)
+	}
+	if len(flaky.keys) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(flaky.keys))
+	}
+}
+
+func TestClient_Failure_NotImplementedIsntRetried(t *testing.T) {
+	c, flaky, _ := newFlakyClient(t, 10, http.StatusNotImplemented)
+
+	_, err := c.List(context.Background(), student.ListOptions{})
+	if !errors.Is(err, client.ErrServer) {
+		t.Errorf("expected ErrServer, got %v", err)
+	}
+	if len(flaky.keys) != 1 {
+		t.Errorf("expected a single attempt, got %d", len(flaky.keys))
+	}
+}
+
+func TestClient_Failure_ContextCancelled(t *testing.T) {
+	c, _, _ := newFlakyClient(t, 10, http.StatusServiceUnavailable)
+	c.Backoff = time.Hour
+	c.MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Get(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to stop the retries, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected to give up as soon as the context is done")
+	}
+}
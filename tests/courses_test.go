@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func sendWithKey(t *testing.T, method, url, key, body string) (int, string) {
+	t.Helper()
+
+	request, _ := http.NewRequest(method, url, strings.NewReader(body))
+	request.Header.Set("X-API-Key", key)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	defer response.Body.Close()
+
+	b, _ := io.ReadAll(response.Body)
+	return response.StatusCode, strings.TrimSpace(string(b))
+}
+
+func newCourse(code string) student.Course {
+	return student.Course{
+		Code:      code,
+		Title:     "Site Reliability Engineering",
+		Capacity:  30,
+		StartDate: student.NewDate(2026, time.November, 2),
+		EndDate:   student.NewDate(2027, time.January, 29),
+	}
+}
+
+func TestCourses_CRUD(t *testing.T) {
+	_, _, target, key := newAPITest(t)
+
+	status, body := sendWithKey(t, http.MethodPost, target.URL+"/api/v1/courses", key,
+		`{"code":"SRE-101","title":"Site Reliability Engineering","capacity":30,"start_date":"2026-11-02","end_date":"2027-01-29"}`)
+	want := `{"id":1,"code":"SRE-101","title":"Site Reliability Engineering","description":"","capacity":30,"start_date":"2026-11-02","end_date":"2027-01-29"}`
+	if status != http.StatusCreated || body != want {
+		t.Fatalf("expected the created course %s, got %d %s", want, status, body)
+	}
+
+	status, body = sendWithKey(t, http.MethodPatch, target.URL+"/api/v1/courses/1", key,
+		`{"code":"SRE-101","title":"SRE","description":"The bootcamp.","capacity":40,"start_date":"2026-11-02","end_date":"2027-01-29"}`)
+	if status != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNoContent, status, body)
+	}
+
+	status, body = getWithKey(t, target.URL+"/api/v1/courses/1", key, "")
+	want = `{"id":1,"code":"SRE-101","title":"SRE","description":"The bootcamp.","capacity":40,"start_date":"2026-11-02","end_date":"2027-01-29"}`
+	if status != http.StatusOK || body != want {
+		t.Errorf("expected the updated course %s, got %d %s", want, status, body)
+	}
+
+	if status, body = sendWithKey(t, http.MethodDelete, target.URL+"/api/v1/courses/1", key, ""); status != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNoContent, status, body)
+	}
+	if status, _ = getWithKey(t, target.URL+"/api/v1/courses/1", key, ""); status != http.StatusNotFound {
+		t.Errorf("expected status %d after deleting, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestCourses_Failure(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	_, _ = store.CreateCourse(context.Background(), newCourse("SRE-101"))
+
+	status, body := sendWithKey(t, http.MethodPost, target.URL+"/api/v1/courses", key,
+		`{"code":"SRE 102","capacity":0,"start_date":"2026-11-02","end_date":"2026-11-01"}`)
+	var invalid student.ErrorResponse
+	_ = json.Unmarshal([]byte(body), &invalid)
+	if status != http.StatusBadRequest || invalid.Error != "invalid_course" || len(invalid.Details) != 4 {
+		t.Errorf("expected code, title, capacity and end_date to be invalid, got %d %s", status, body)
+	}
+
+	if status, body = sendWithKey(t, http.MethodPost, target.URL+"/api/v1/courses", key, `{"start_date":"tomorrow"}`); status != http.StatusBadRequest {
+		t.Errorf("expected status %d for a malformed date, got %d: %s", http.StatusBadRequest, status, body)
+	}
+
+	status, body = sendWithKey(t, http.MethodPost, target.URL+"/api/v1/courses", key,
+		`{"code":"SRE-101","title":"Again","capacity":10,"start_date":"2026-11-02","end_date":"2026-11-02"}`)
+	if status != http.StatusConflict || !strings.Contains(body, "course_code_taken") {
+		t.Errorf("expected a conflict for a taken code, got %d %s", status, body)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if status, _ = sendWithKey(t, method, target.URL+"/api/v1/courses/42", key, ""); status != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", method, http.StatusNotFound, status)
+		}
+	}
+
+	// courses are managed with their own permissions.
+	plain, viewer, _ := student.GenerateAPIKey("viewer", []string{student.ScopeStudentsRead, student.ScopeStudentsWrite})
+	_, _ = store.CreateAPIKey(context.Background(), viewer)
+	if status, _ = getWithKey(t, target.URL+"/api/v1/courses", plain, ""); status != http.StatusForbidden {
+		t.Errorf("expected status %d without courses:read, got %d", http.StatusForbidden, status)
+	}
+}
+
+func TestCourseStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) student.CourseStore{
+		"sqlite": func(t *testing.T) student.CourseStore { return newTestSQLiteStore(t) },
+		"memory": func(t *testing.T) student.CourseStore { return student.NewMemoryCourseStore() },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			ctx := context.Background()
+			for _, code := range []string{"SRE-101", "SRE-102", "SRE-103"} {
+				if _, err := store.CreateCourse(ctx, newCourse(code)); err != nil {
+					t.Fatalf("Error creating course: %v", err)
+				}
+			}
+
+			course, err := store.GetCourse(ctx, 2)
+			if err != nil || course.Code != "SRE-102" || !course.EndDate.Equal(newCourse("").EndDate.Time) {
+				t.Errorf("expected SRE-102, got %+v %v", course, err)
+			}
+
+			page, _ := store.ListCourses(ctx, student.ListOptions{AfterId: 1, Limit: 1})
+			if len(page) != 1 || page[0].Id != 2 {
+				t.Errorf("expected a page of course 2, got %+v", page)
+			}
+
+			if err := store.UpdateCourse(ctx, 3, newCourse("SRE-101")); err == nil {
+				t.Errorf("expected taking another course's code to fail")
+			}
+			if err := store.UpdateCourse(ctx, 3, newCourse("SRE-103")); err != nil {
+				t.Errorf("expected keeping a course's own code to succeed, got %v", err)
+			}
+			if err := store.DeleteCourse(ctx, 3); err != nil {
+				t.Errorf("Error deleting course: %v", err)
+			}
+			if err := store.UpdateCourse(ctx, 3, newCourse("SRE-103")); err == nil {
+				t.Errorf("expected updating a deleted course to fail")
+			}
+		})
+	}
+}
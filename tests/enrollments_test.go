@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// newEnrollmentTest creates a course with capacity seats, and students students.
+func newEnrollmentTest(t *testing.T, store *student.SQLiteDataStore, capacity, students int) int {
+	t.Helper()
+
+	ctx := context.Background()
+	course := newCourse("SRE-101")
+	course.Capacity = capacity
+	courseId, err := store.CreateCourse(ctx, course)
+	if err != nil {
+		t.Fatalf("Error creating course: %v", err)
+	}
+	for i := range students {
+		if err := store.CreateStudent(ctx, student.Student{Name: fmt.Sprintf("Student %d", i+1), Age: 20}); err != nil {
+			t.Fatalf("Error creating student: %v", err)
+		}
+	}
+	return courseId
+}
+
+// statuses renders enrollments as student:status:position, in order.
+func statuses(enrollments []student.Enrollment) string {
+	var s []string
+	for _, e := range enrollments {
+		s = append(s, fmt.Sprintf("%d:%s:%d", e.StudentId, e.Status, e.Position))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestEnrollments_Waitlist(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	newEnrollmentTest(t, store, 2, 4)
+	url := target.URL + "/api/v1/courses/1/enrollments"
+
+	for id := 1; id <= 4; id++ {
+		status, body := sendWithKey(t, http.MethodPost, url, key, fmt.Sprintf(`{"student_id":%d}`, id))
+		if status != http.StatusCreated {
+			t.Fatalf("expected status %d enrolling student %d, got %d: %s", http.StatusCreated, id, status, body)
+		}
+	}
+
+	status, body := sendWithKey(t, http.MethodPost, url, key, `{"student_id":3}`)
+	if status != http.StatusConflict || !strings.Contains(body, "already_enrolled") {
+		t.Errorf("expected a conflict enrolling a student twice, got %d %s", status, body)
+	}
+
+	var enrollments []student.Enrollment
+	_, body = getWithKey(t, url, key, "")
+	_ = json.Unmarshal([]byte(body), &enrollments)
+	if got, want := statuses(enrollments), "1:enrolled:0 2:enrolled:0 3:waitlisted:1 4:waitlisted:2"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	// the seat that's freed goes to the front of the waitlist.
+	if status, body = sendWithKey(t, http.MethodDelete, url+"/1", key, ""); status != http.StatusNoContent {
+		t.Fatalf("expected status %d withdrawing, got %d: %s", http.StatusNoContent, status, body)
+	}
+	_, body = getWithKey(t, url+"/4", key, "")
+	if !strings.Contains(body, `"status":"waitlisted","position":1`) {
+		t.Errorf("expected student 4 to be first on the waitlist, got %s", body)
+	}
+
+	var courses []student.StudentCourse
+	status, body = getWithKey(t, target.URL+"/api/v1/students/3/courses", key, "")
+	_ = json.Unmarshal([]byte(body), &courses)
+	if status != http.StatusOK || len(courses) != 1 || courses[0].Course.Code != "SRE-101" ||
+		courses[0].Enrollment.Status != student.EnrollmentEnrolled {
+		t.Errorf("expected student 3 to be enrolled in SRE-101, got %d %s", status, body)
+	}
+
+	for _, tc := range []struct{ method, url, body string }{
+		{http.MethodPost, target.URL + "/api/v1/courses/42/enrollments", `{"student_id":1}`},
+		{http.MethodPost, url, `{"student_id":42}`},
+		{http.MethodDelete, url + "/1", ""},
+		{http.MethodGet, target.URL + "/api/v1/students/42/courses", ""},
+	} {
+		if status, body = sendWithKey(t, tc.method, tc.url, key, tc.body); status != http.StatusNotFound {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tc.method, tc.url, http.StatusNotFound, status, body)
+		}
+	}
+	if status, _ = sendWithKey(t, http.MethodPost, url, key, `{}`); status != http.StatusBadRequest {
+		t.Errorf("expected status %d without a student, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestEnrollments_CapacityIsEnforcedConcurrently(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	courseId := newEnrollmentTest(t, store, 5, 20)
+
+	var wg sync.WaitGroup
+	for id := 1; id <= 20; id++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Enroll(context.Background(), courseId, id); err != nil {
+				t.Errorf("Error enrolling student %d: %v", id, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	enrollments, _ := store.ListEnrollments(context.Background(), courseId)
+	var enrolled, waitlisted int
+	for _, e := range enrollments {
+		switch e.Status {
+		case student.EnrollmentEnrolled:
+			enrolled++
+		case student.EnrollmentWaitlisted:
+			waitlisted++
+			if e.Position != waitlisted {
+				t.Errorf("expected position %d, got %d", waitlisted, e.Position)
+			}
+		}
+	}
+	if enrolled != 5 || waitlisted != 15 {
+		t.Errorf("expected 5 students enrolled and 15 waitlisted, got %d and %d", enrolled, waitlisted)
+	}
+}
+
+func TestEnrollments_CapacityChanges(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+	courseId := newEnrollmentTest(t, store, 2, 4)
+	for id := 1; id <= 4; id++ {
+		_, _ = store.Enroll(ctx, courseId, id)
+	}
+
+	// students keep their seats when there are fewer of them, and the waitlist waits for more to free up.
+	course := newCourse("SRE-101")
+	course.Capacity = 1
+	if err := store.UpdateCourse(ctx, courseId, course); err != nil {
+		t.Fatalf("Error updating course: %v", err)
+	}
+	_ = store.Withdraw(ctx, courseId, 1)
+	enrollments, _ := store.ListEnrollments(ctx, courseId)
+	if got, want := statuses(enrollments), "2:enrolled:0 3:waitlisted:1 4:waitlisted:2"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	course.Capacity = 3
+	if err := store.UpdateCourse(ctx, courseId, course); err != nil {
+		t.Fatalf("Error updating course: %v", err)
+	}
+	enrollments, _ = store.ListEnrollments(ctx, courseId)
+	if got, want := statuses(enrollments), "2:enrolled:0 3:enrolled:0 4:enrolled:0"; got != want {
+		t.Errorf("expected the new seats to go to the waitlist, %s, got %s", want, got)
+	}
+
+	if err := store.DeleteCourse(ctx, courseId); err != nil {
+		t.Fatalf("Error deleting course: %v", err)
+	}
+	if courses, _ := store.ListStudentCourses(ctx, 2); len(courses) != 0 {
+		t.Errorf("expected the enrollments to go with the course, got %+v", courses)
+	}
+}
+
+func TestEnrollments_DeletingStudents(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	ctx := context.Background()
+	courseId := newEnrollmentTest(t, store, 1, 2)
+	_, _ = store.Enroll(ctx, courseId, 1)
+	_, _ = store.Enroll(ctx, courseId, 2)
+
+	status, body := sendWithKey(t, http.MethodDelete, target.URL+"/api/v1/students/1", key, "")
+	if status != http.StatusConflict || !strings.Contains(body, "student_enrolled") {
+		t.Errorf("expected deleting an enrolled student to be blocked, got %d %s", status, body)
+	}
+
+	store.EnrolledStudents = student.WithdrawEnrolledStudents
+	if status, body = sendWithKey(t, http.MethodDelete, target.URL+"/api/v1/students/1", key, ""); status != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, status, body)
+	}
+	enrollments, _ := store.ListEnrollments(ctx, courseId)
+	if got, want := statuses(enrollments), "2:enrolled:0"; got != want {
+		t.Errorf("expected the student's seat to go to the waitlist, %s, got %s", want, got)
+	}
+	if _, err := store.Enroll(ctx, courseId, 1); err == nil || err.Error() != "student not found" {
+		t.Errorf("expected deleted students not to be enrolled, got %v", err)
+	}
+}
+
+func TestEnrollments_RevertingStudentsIntoTheTrash(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	ctx := context.Background()
+	courseId := newEnrollmentTest(t, store, 1, 2)
+	// student 1 is deleted and brought back, so that its second revision is a deleted one.
+	_ = store.DeleteStudent(ctx, 1)
+	_ = store.RestoreStudent(ctx, 1)
+	_, _ = store.Enroll(ctx, courseId, 1)
+	_, _ = store.Enroll(ctx, courseId, 2)
+
+	revert := target.URL + "/api/v1/students/1/revert?revision=2"
+	status, body := sendWithKey(t, http.MethodPost, revert, key, "")
+	if status != http.StatusConflict || !strings.Contains(body, "student_enrolled") {
+		t.Errorf("expected reverting an enrolled student into the trash to be blocked, got %d %s", status, body)
+	}
+	if _, err := store.GetStudent(ctx, 1); err != nil {
+		t.Errorf("expected the student to stay live, got %v", err)
+	}
+
+	store.EnrolledStudents = student.WithdrawEnrolledStudents
+	if status, body = sendWithKey(t, http.MethodPost, revert, key, ""); status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, status, body)
+	}
+	enrollments, _ := store.ListEnrollments(ctx, courseId)
+	if got, want := statuses(enrollments), "2:enrolled:0"; got != want {
+		t.Errorf("expected the student's seat to go to the waitlist, %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// sseMessage is a single message, or a comment, read off an event stream.
+type sseMessage struct {
+	id, event, data, comment string
+}
+
+// readSSE returns a channel of the messages on the stream, closed when it ends.
+func readSSE(body *bufio.Reader) <-chan sseMessage {
+	messages := make(chan sseMessage, 16)
+	go func() {
+		defer close(messages)
+
+		var msg sseMessage
+		for {
+			line, err := body.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				if msg != (sseMessage{}) {
+					messages <- msg
+				}
+				msg = sseMessage{}
+			case strings.HasPrefix(line, ":"):
+				msg.comment = strings.TrimSpace(line[1:])
+			default:
+				field, value, _ := strings.Cut(line, ": ")
+				switch field {
+				case "id":
+					msg.id = value
+				case "event":
+					msg.event = value
+				case "data":
+					msg.data = value
+				}
+			}
+		}
+	}()
+	return messages
+}
+
+// nextEvent returns the next message that isn't a comment.
+func nextEvent(t *testing.T, messages <-chan sseMessage) sseMessage {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				t.Fatalf("event stream ended")
+			}
+			if msg.event != "" {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for an event")
+		}
+	}
+}
+
+// newEventsTest serves handler with an event broker running, which polls the outbox every 10ms. configure, unless
+// nil, sets the broker's other options before it starts.
+func newEventsTest(t *testing.T, handler func(*student.Server, http.ResponseWriter, *http.Request),
+	configure func(*student.EventBroker)) (*student.SQLiteDataStore, *student.Server, *httptest.Server) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+	s.Events = student.NewEventBroker(store, s.Logger)
+	s.Events.PollInterval = 10 * time.Millisecond
+	if configure != nil {
+		configure(s.Events)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := s.Events.Start(ctx); err != nil {
+		t.Fatalf("Error starting event broker: %v", err)
+	}
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handler(s, w, r) }))
+	t.Cleanup(target.Close)
+	return store, s, target
+}
+
+func openEventStream(t *testing.T, url, lastEventId string) <-chan sseMessage {
+	t.Helper()
+
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventId != "" {
+		request.Header.Set("Last-Event-ID", lastEventId)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error opening event stream: %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, response.StatusCode)
+	}
+	if ct := response.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected content type text/event-stream, got %q", ct)
+	}
+	return readSSE(bufio.NewReader(response.Body))
+}
+
+func TestStudentEvents_StreamsMutations(t *testing.T) {
+	_, s, target := newEventsTest(t, (*student.Server).StudentEvents, nil)
+	messages := openEventStream(t, target.URL, "")
+
+	request, _ := http.NewRequest(http.MethodPost, "/api/v1/students/add", strings.NewReader(`{"name":"Swagnik","age":32}`))
+	response := httptest.NewRecorder()
+	s.CreateStudent(response, request)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, response.Code)
+	}
+
+	msg := nextEvent(t, messages)
+	if msg.id != "1" || msg.event != student.AuditStudentCreated {
+		t.Errorf("expected event 1 %s, got %+v", student.AuditStudentCreated, msg)
+	}
+
+	var e student.Event
+	if err := json.Unmarshal([]byte(msg.data), &e); err != nil {
+		t.Fatalf("Error decoding event: %v", err)
+	}
+	if e.Student == nil || e.Student.Name != "Swagnik" {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestStudentEvents_ResumesFromLastEventId(t *testing.T) {
+	store, _, target := newEventsTest(t, (*student.Server).StudentEvents, nil)
+
+	ctx := context.Background()
+	if err := store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+	if err := store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+	if err := store.DeleteStudent(ctx, 1); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+
+	messages := openEventStream(t, target.URL, "1")
+	for _, want := range []struct{ id, event string }{
+		{"2", student.AuditStudentUpdated},
+		{"3", student.AuditStudentDeleted},
+	} {
+		msg := nextEvent(t, messages)
+		if msg.id != want.id || msg.event != want.event {
+			t.Errorf("expected event %s %s, got %+v", want.id, want.event, msg)
+		}
+	}
+}
+
+func TestStudentEvents_Heartbeat(t *testing.T) {
+	_, _, target := newEventsTest(t, (*student.Server).StudentEvents, func(b *student.EventBroker) {
+		b.Heartbeat = 10 * time.Millisecond
+	})
+	messages := openEventStream(t, target.URL, "")
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-messages:
+			if msg.comment == "heartbeat" {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for a heartbeat")
+		}
+	}
+}
+
+func TestEventBroker_DropsSlowSubscribers(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	broker := student.NewEventBroker(store, NewTestLogger())
+	broker.Buffer = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := broker.Start(ctx); err != nil {
+		t.Fatalf("Error starting event broker: %v", err)
+	}
+
+	sub := broker.Subscribe()
+	for range 3 {
+		if err := store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+			t.Fatalf("Error creating student: %v", err)
+		}
+	}
+	broker.Notify()
+
+	// a subscriber that isn't keeping up gets what fits in its buffer, then its channel is closed.
+	time.Sleep(200 * time.Millisecond)
+	timeout := time.After(5 * time.Second)
+	received := 0
+	for {
+		select {
+		case _, ok := <-sub.C:
+			if !ok {
+				if received > 1 {
+					t.Errorf("expected at most 1 event before being dropped, got %d", received)
+				}
+				return
+			}
+			received++
+		case <-timeout:
+			t.Fatalf("expected the slow subscriber to be dropped")
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func newExportTest(t *testing.T) *student.Server {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	ctx := context.Background()
+	for _, name := range []string{"Swagnik", "Dutta", "Someone"} {
+		if err := store.CreateStudent(ctx, student.Student{Name: name, Age: 32}); err != nil {
+			t.Fatalf("Error creating student: %v", err)
+		}
+	}
+	if err := store.DeleteStudent(ctx, 3); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+	return s
+}
+
+func export(s *student.Server, query string, header http.Header) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students/export"+query, nil)
+	for k, v := range header {
+		request.Header[k] = v
+	}
+
+	response := httptest.NewRecorder()
+	s.ExportStudents(response, request)
+	return response
+}
+
+func TestExport_Formats(t *testing.T) {
+	s := newExportTest(t)
+
+	tests := []struct {
+		format      string
+		contentType string
+		decode      func(t *testing.T, body []byte) []string
+	}{
+		{"csv", "text/csv; charset=utf-8", func(t *testing.T, body []byte) []string {
+			records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+			if err != nil {
+				t.Fatalf("Error reading csv: %v", err)
+			}
+			if strings.Join(records[0], ",") != "id,name,age,deleted_at" {
+				t.Errorf("unexpected header %v", records[0])
+			}
+			var names []string
+			for _, record := range records[1:] {
+				names = append(names, record[1])
+			}
+			return names
+		}},
+		{"ndjson", "application/x-ndjson", func(t *testing.T, body []byte) []string {
+			var names []string
+			dec := json.NewDecoder(bytes.NewReader(body))
+			for dec.More() {
+				var st student.Student
+				if err := dec.Decode(&st); err != nil {
+					t.Fatalf("Error decoding ndjson: %v", err)
+				}
+				names = append(names, st.Name)
+			}
+			return names
+		}},
+		{"json", "application/json", func(t *testing.T, body []byte) []string {
+			var students []student.Student
+			if err := json.Unmarshal(body, &students); err != nil {
+				t.Fatalf("Error decoding json: %v", err)
+			}
+			var names []string
+			for _, st := range students {
+				names = append(names, st.Name)
+			}
+			return names
+		}},
+		{"parquet", "application/vnd.apache.parquet", func(t *testing.T, body []byte) []string {
+			type row struct {
+				Name string `parquet:"name"`
+			}
+			rows, err := parquet.Read[row](bytes.NewReader(body), int64(len(body)))
+			if err != nil {
+				t.Fatalf("Error reading parquet: %v", err)
+			}
+			var names []string
+			for _, r := range rows {
+				names = append(names, r.Name)
+			}
+			return names
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			response := export(s, "?format="+tt.format, nil)
+			if response.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, response.Code, response.Body.String())
+			}
+			if ct := response.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("expected content type %q, got %q", tt.contentType, ct)
+			}
+			if cd := response.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment; filename=") ||
+				!strings.HasSuffix(cd, "."+tt.format+`"`) {
+				t.Errorf("unexpected content disposition %q", cd)
+			}
+
+			// deleted students aren't exported, as they aren't listed.
+			names := tt.decode(t, response.Body.Bytes())
+			if strings.Join(names, ",") != "Swagnik,Dutta" {
+				t.Errorf("expected Swagnik and Dutta, got %v", names)
+			}
+		})
+	}
+}
+
+func TestExport_Gzip(t *testing.T) {
+	s := newExportTest(t)
+
+	response := export(s, "?format=ndjson", http.Header{"Accept-Encoding": {"br, gzip"}})
+	if response.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzipped response")
+	}
+
+	gz, err := gzip.NewReader(response.Body)
+	if err != nil {
+		t.Fatalf("Error reading gzip: %v", err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Error reading gzip: %v", err)
+	}
+	if n := strings.Count(string(body), "\n"); n != 2 {
+		t.Errorf("expected 2 students, got %d", n)
+	}
+}
+
+func TestExport_Failure_InvalidFormat(t *testing.T) {
+	s := newExportTest(t)
+
+	if response := export(s, "?format=xlsx", nil); response.Code != http.StatusBadRequest {
+		t.Errorf("expected status code %d, got %d", http.StatusBadRequest, response.Code)
+	}
+}
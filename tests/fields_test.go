@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/mocks"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+	"go.uber.org/mock/gomock"
+)
+
+func getWithKey(t *testing.T, url, key, accept string) (int, string) {
+	t.Helper()
+
+	request, _ := http.NewRequest(http.MethodGet, url, nil)
+	request.Header.Set("X-API-Key", key)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	return response.StatusCode, strings.TrimSpace(string(body))
+}
+
+func TestFields_TrimsResponses(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	for _, name := range []string{"Swagnik", "Dutta"} {
+		_ = store.CreateStudent(context.Background(), student.Student{Name: name, Age: 32})
+	}
+
+	tests := []struct {
+		path   string
+		accept string
+		want   string
+	}{
+		{"/api/v1/students/1?fields=name", "", `{"name":"Swagnik"}`},
+		{"/api/v1/students?fields=age,id", "", `[{"id":1,"age":32},{"id":2,"age":32}]`},
+		{"/api/v1/students?fields=name", "text/csv", "name\nSwagnik\nDutta"},
+		{"/api/v1/students/2?fields=id,name", "application/xml", `<student><id>2</id><name>Dutta</name></student>`},
+	}
+
+	for _, tt := range tests {
+		status, body := getWithKey(t, target.URL+tt.path, key, tt.accept)
+		if status != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.path, http.StatusOK, status, body)
+			continue
+		}
+		if !strings.HasSuffix(body, tt.want) {
+			t.Errorf("%s: expected %s, got %s", tt.path, tt.want, body)
+		}
+	}
+}
+
+func TestFields_IncludesRevisions(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	_ = store.CreateStudent(context.Background(), student.Student{Name: "Swagnik", Age: 32})
+	_ = store.UpdateStudent(context.Background(), 1, student.Student{Name: "Swagnik Dutta", Age: 33})
+
+	status, body := getWithKey(t, target.URL+"/api/v1/students/1?fields=name&include=revisions", key, "")
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, status, body)
+	}
+
+	var got struct {
+		Id        int
+		Name      string
+		Revisions []student.Revision
+	}
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("Error decoding student: %v", err)
+	}
+	if got.Id != 0 || got.Name != "Swagnik Dutta" || len(got.Revisions) != 2 {
+		t.Errorf("expected the name with 2 revisions, got %s", body)
+	}
+
+	_ = store.CreateStudent(context.Background(), student.Student{Name: "Dutta", Age: 33})
+	var list []struct {
+		Revisions []student.Revision
+	}
+	_, body = getWithKey(t, target.URL+"/api/v1/students?fields=name&include=revisions", key, "")
+	_ = json.Unmarshal([]byte(body), &list)
+	if len(list) != 2 || len(list[0].Revisions) != 2 || len(list[1].Revisions) != 1 || list[1].Revisions[0].StudentId != 2 {
+		t.Errorf("expected each student of the list with its own revisions, got %s", body)
+	}
+}
+
+func TestFields_IncludesRevisionsOfAListAtOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mocks.NewMockStore(ctrl)
+	mockStore.EXPECT().ListStudents(gomock.Any(), gomock.Any()).
+		Return([]student.Student{{Id: 1, Name: "Swagnik"}, {Id: 2, Name: "Dutta"}}, nil)
+	// a single lookup for the whole list, ListRevisions isn't expected to be called.
+	mockRevisions := mocks.NewMockRevisionStore(ctrl)
+	mockRevisions.EXPECT().ListRevisionsOf(gomock.Any(), []int{1, 2}).
+		Return(map[int][]student.Revision{1: {{StudentId: 1, Revision: 1}, {StudentId: 1, Revision: 2}}}, nil)
+
+	s := &student.Server{Store: mockStore, Revisions: mockRevisions, Logger: NewTestLogger()}
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students?fields=name&include=revisions", nil)
+	response := httptest.NewRecorder()
+	s.ListStudents(response, request)
+
+	var got []struct {
+		Name      string
+		Revisions []student.Revision
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Error decoding students: %v", err)
+	}
+	if len(got) != 2 || len(got[0].Revisions) != 2 || len(got[1].Revisions) != 0 {
+		t.Errorf("expected each student with its own revisions, got %s", response.Body.String())
+	}
+}
+
+func TestFields_Failure_Invalid(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	_ = store.CreateStudent(context.Background(), student.Student{Name: "Swagnik", Age: 32})
+
+	tests := []struct {
+		path   string
+		accept string
+	}{
+		{"/api/v1/students?fields=height", ""},
+		{"/api/v1/students/1?include=courses", ""},
+		{"/api/v1/students?include=revisions", "text/csv"},
+	}
+
+	for _, tt := range tests {
+		if status, body := getWithKey(t, target.URL+tt.path, key, tt.accept); status != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.path, http.StatusBadRequest, status, body)
+		}
+	}
+}
+
+func TestFields_SelectsOnlyRequestedColumns(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	_ = store.CreateStudent(context.Background(), student.Student{Name: "Swagnik", Age: 32})
+
+	students, err := store.ListStudents(context.Background(), student.ListOptions{Fields: []string{"id", "age"}})
+	if err != nil {
+		t.Fatalf("Error listing students: %v", err)
+	}
+	if len(students) != 1 || students[0].Id != 1 || students[0].Age != 32 || students[0].Name != "" {
+		t.Errorf("expected only the id and age to be read, got %+v", students)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func TestGrades_Transcript(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	ctx := context.Background()
+	courseId := newEnrollmentTest(t, store, 10, 2)
+	otherId, _ := store.CreateCourse(ctx, newCourse("SRE-102"))
+	for _, id := range []int{courseId, otherId} {
+		if _, err := store.Enroll(ctx, id, 1); err != nil {
+			t.Fatalf("Error enrolling student: %v", err)
+		}
+	}
+
+	url := target.URL + "/api/v1/courses/1/assessments"
+	for _, body := range []string{
+		`{"title":"Midterm","weight":40,"max_score":50}`,
+		`{"title":"Final","weight":60,"max_score":100}`,
+		`{"title":"Project","weight":20,"max_score":10}`,
+	} {
+		if status, body := sendWithKey(t, http.MethodPost, url, key, body); status != http.StatusCreated {
+			t.Fatalf("expected status %d creating an assessment, got %d: %s", http.StatusCreated, status, body)
+		}
+	}
+	_, _ = sendWithKey(t, http.MethodPut, url+"/1/scores", key, `{"scores":[{"student_id":1,"score":45}]}`)
+	_, _ = sendWithKey(t, http.MethodPut, url+"/2/scores", key, `{"scores":[{"student_id":1,"score":80}]}`)
+
+	otherUrl := target.URL + "/api/v1/courses/2/assessments"
+	_, _ = sendWithKey(t, http.MethodPost, otherUrl, key, `{"title":"Quiz","weight":10,"max_score":20}`)
+	_, _ = sendWithKey(t, http.MethodPut, otherUrl+"/4/scores", key, `{"scores":[{"student_id":1,"score":19}]}`)
+
+	// the project isn't scored yet, so it doesn't count: 40% of 90 and 60% of 80 is a B.
+	var transcript student.Transcript
+	status, body := getWithKey(t, target.URL+"/api/v1/students/1/transcript", key, "")
+	_ = json.Unmarshal([]byte(body), &transcript)
+	if status != http.StatusOK || len(transcript.Courses) != 2 {
+		t.Fatalf("expected a transcript of 2 courses, got %d %s", status, body)
+	}
+	first, second := transcript.Courses[0], transcript.Courses[1]
+	if first.Grade == nil || *first.Grade != 84 || first.Letter != "B" || *first.GradePoints != 3 {
+		t.Errorf("expected 84, a B, in the first course, got %s", body)
+	}
+	if len(first.Assessments) != 3 || first.Assessments[2].Score != nil {
+		t.Errorf("expected the project to be listed without a score, got %+v", first.Assessments)
+	}
+	if second.Grade == nil || *second.Grade != 95 || second.Letter != "A" {
+		t.Errorf("expected 95, an A, in the second course, got %s", body)
+	}
+	if transcript.GPA == nil || *transcript.GPA != 3.5 {
+		t.Errorf("expected a GPA of 3.5, got %s", body)
+	}
+
+	status, body = getWithKey(t, target.URL+"/api/v1/students/2/transcript", key, "")
+	if status != http.StatusOK || !strings.Contains(body, `"courses":[],"gpa":null`) {
+		t.Errorf("expected an empty transcript for a student that isn't enrolled, got %d %s", status, body)
+	}
+
+	status, body = getWithKey(t, target.URL+"/api/v1/students/1/transcript", key, "text/html")
+	if status != http.StatusOK || !strings.Contains(body, "Transcript of Student 1") || !strings.Contains(body, "84%, B") {
+		t.Errorf("expected an html transcript, got %d %s", status, body)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, target.URL+"/api/v1/students/1/transcript?format=pdf", nil)
+	request.Header.Set("X-API-Key", key)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	response.Body.Close()
+	if response.Header.Get("Content-Type") != "application/pdf" ||
+		response.Header.Get("Content-Disposition") != `inline; filename="transcript-1.pdf"` {
+		t.Errorf("expected a pdf, got %v", response.Header)
+	}
+	if _, body = getWithKey(t, target.URL+"/api/v1/students/1/transcript?format=pdf", key, ""); !strings.HasPrefix(body, "%PDF-") {
+		t.Errorf("expected a pdf, got %.20q", body)
+	}
+
+	if status, _ = getWithKey(t, target.URL+"/api/v1/students/1/transcript", key, "image/png"); status != http.StatusNotAcceptable {
+		t.Errorf("expected status %d, got %d", http.StatusNotAcceptable, status)
+	}
+	if status, _ = getWithKey(t, target.URL+"/api/v1/students/1/transcript?format=docx", key, ""); status != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, status)
+	}
+	if status, _ = getWithKey(t, target.URL+"/api/v1/students/9/transcript", key, ""); status != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestGrades_Scores(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	ctx := context.Background()
+	courseId := newEnrollmentTest(t, store, 10, 3)
+	_, _ = store.Enroll(ctx, courseId, 1)
+	_, _ = store.Enroll(ctx, courseId, 2)
+	url := target.URL + "/api/v1/courses/1/assessments"
+	_, _ = sendWithKey(t, http.MethodPost, url, key, `{"title":"Midterm","weight":40,"max_score":50}`)
+
+	// nothing is recorded when one of the students can't be scored.
+	status, body := sendWithKey(t, http.MethodPut, url+"/1/scores", key,
+		`{"scores":[{"student_id":1,"score":40},{"student_id":3,"score":30}]}`)
+	if status != http.StatusConflict || !strings.Contains(body, `"not_enrolled"`) || !strings.Contains(body, `"student_id":3`) {
+		t.Errorf("expected scoring a student that isn't enrolled to be a conflict, got %d %s", status, body)
+	}
+	if _, body = getWithKey(t, url+"/1/scores", key, ""); body != "[]" {
+		t.Errorf("expected no scores to be recorded, got %s", body)
+	}
+
+	for _, tc := range []struct {
+		body, field string
+	}{
+		{`{"scores":[]}`, `"scores"`},
+		{`{"scores":[{"student_id":1,"score":51}]}`, `"scores[0].score"`},
+		{`{"scores":[{"student_id":1,"score":-1}]}`, `"scores[0].score"`},
+		{`{"scores":[{"score":10}]}`, `"scores[0].student_id"`},
+		{`{"scores":[{"student_id":1,"score":10},{"student_id":1,"score":20}]}`, `"scores[1].student_id"`},
+	} {
+		status, body := sendWithKey(t, http.MethodPut, url+"/1/scores", key, tc.body)
+		if status != http.StatusBadRequest || !strings.Contains(body, "invalid_scores") || !strings.Contains(body, tc.field) {
+			t.Errorf("expected %s to be invalid in %s, got %d %s", tc.field, tc.body, status, body)
+		}
+	}
+
+	_, _ = sendWithKey(t, http.MethodPut, url+"/1/scores", key,
+		`{"scores":[{"student_id":1,"score":40},{"student_id":2,"score":30}]}`)
+	_, _ = sendWithKey(t, http.MethodPut, url+"/1/scores", key, `{"scores":[{"student_id":2,"score":35}]}`)
+	var scores []student.Score
+	_, body = getWithKey(t, url+"/1/scores", key, "")
+	_ = json.Unmarshal([]byte(body), &scores)
+	if len(scores) != 2 || scores[0].Score != 40 || scores[1].Score != 35 || scores[1].GradedAt.IsZero() {
+		t.Errorf("expected student 2's score to be replaced, got %s", body)
+	}
+
+	if status, body = sendWithKey(t, http.MethodPut, target.URL+"/api/v1/courses/1/assessments/9/scores", key,
+		`{"scores":[{"student_id":1,"score":40}]}`); status != http.StatusNotFound {
+		t.Errorf("expected status %d scoring an assessment that doesn't exist, got %d: %s", http.StatusNotFound, status, body)
+	}
+	status, body = sendWithKey(t, http.MethodPost, url, key, `{"title":" ","weight":101,"max_score":0}`)
+	if status != http.StatusBadRequest ||
+		!strings.Contains(body, `"title"`) || !strings.Contains(body, `"weight"`) || !strings.Contains(body, `"max_score"`) {
+		t.Errorf("expected the assessment to be invalid, got %d %s", status, body)
+	}
+}
+
+func TestGrades_Deletes(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+	courseId := newEnrollmentTest(t, store, 10, 1)
+	_, _ = store.Enroll(ctx, courseId, 1)
+	id, err := store.CreateAssessment(ctx, student.Assessment{CourseId: courseId, Title: "Midterm", Weight: 1, MaxScore: 10})
+	if err != nil {
+		t.Fatalf("Error creating assessment: %v", err)
+	}
+	if err := store.RecordScores(ctx, courseId, id, []student.Score{{StudentId: 1, Score: 5}}); err != nil {
+		t.Fatalf("Error recording scores: %v", err)
+	}
+
+	if _, err := store.GetAssessment(ctx, courseId+1, id); err == nil || err.Error() != "assessment not found" {
+		t.Errorf("expected assessments to be read through their course, got %v", err)
+	}
+	if _, err := store.CreateAssessment(ctx, student.Assessment{CourseId: 9, Title: "Quiz", Weight: 1, MaxScore: 1}); err == nil ||
+		err.Error() != "course not found" {
+		t.Errorf("expected creating an assessment of a course that doesn't exist to fail, got %v", err)
+	}
+
+	if err := store.DeleteAssessment(ctx, courseId, id); err != nil {
+		t.Fatalf("Error deleting assessment: %v", err)
+	}
+	if _, err := store.ListScores(ctx, courseId, id); err == nil || err.Error() != "assessment not found" {
+		t.Errorf("expected the assessment to be deleted, got %v", err)
+	}
+
+	id, _ = store.CreateAssessment(ctx, student.Assessment{CourseId: courseId, Title: "Final", Weight: 1, MaxScore: 10})
+	_ = store.RecordScores(ctx, courseId, id, []student.Score{{StudentId: 1, Score: 5}})
+	if err := store.DeleteCourse(ctx, courseId); err != nil {
+		t.Fatalf("Error deleting course: %v", err)
+	}
+	if _, err := store.GetAssessment(ctx, courseId, id); err == nil || err.Error() != "assessment not found" {
+		t.Errorf("expected the assessments to go with the course, got %v", err)
+	}
+	if grades, err := store.StudentGrades(ctx, 1); err != nil || len(grades) != 0 {
+		t.Errorf("expected no grades once the course is deleted, got %+v %v", grades, err)
+	}
+}
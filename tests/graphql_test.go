@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// graphQLResult is a GraphQL response, with data left for the test to decode.
+type graphQLResult struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// countingBatchStore counts the batches students are looked up in.
+type countingBatchStore struct {
+	student.BatchStore
+	batches atomic.Int32
+}
+
+func (c *countingBatchStore) GetStudents(ctx context.Context, ids []int) ([]student.Student, error) {
+	c.batches.Add(1)
+	return c.BatchStore.GetStudents(ctx, ids)
+}
+
+func postGraphQL(t *testing.T, url, key, query string, variables map[string]any) (int, graphQLResult) {
+	t.Helper()
+
+	body, _ := json.Marshal(student.GraphQLRequest{Query: query, Variables: variables})
+	request, _ := http.NewRequest(http.MethodPost, url+"/graphql", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-API-Key", key)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error sending graphql request: %v", err)
+	}
+	defer response.Body.Close()
+
+	var result graphQLResult
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("Error decoding graphql response: %v", err)
+	}
+	return response.StatusCode, result
+}
+
+func TestGraphQL_CRUD(t *testing.T) {
+	_, _, target, key := newAPITest(t)
+
+	_, result := postGraphQL(t, target.URL, key, `mutation($input: StudentInput!) { createStudent(input: $input) }`,
+		map[string]any{"input": map[string]any{"name": "Swagnik", "age": 32}})
+	if len(result.Errors) != 0 || string(result.Data) != `{"createStudent":true}` {
+		t.Fatalf("expected the student to be created, got %s %v", result.Data, result.Errors)
+	}
+
+	_, result = postGraphQL(t, target.URL, key, `mutation { updateStudent(id: 1, input: {name: "Swagnik Dutta", age: 33}) { id name age } }`, nil)
+	if string(result.Data) != `{"updateStudent":{"age":33,"id":1,"name":"Swagnik Dutta"}}` {
+		t.Errorf("expected the updated student, got %s %v", result.Data, result.Errors)
+	}
+
+	_, result = postGraphQL(t, target.URL, key, `{ student(id: 1) { name revisions { revision action } } missing: student(id: 42) { id } }`, nil)
+	if string(result.Data) != `{"missing":null,"student":{"name":"Swagnik Dutta","revisions":[{"action":"student.created","revision":1},{"action":"student.updated","revision":2}]}}` {
+		t.Errorf("expected the student with its revisions, got %s %v", result.Data, result.Errors)
+	}
+
+	_, result = postGraphQL(t, target.URL, key, `mutation { deleteStudent(id: 1) }`, nil)
+	if string(result.Data) != `{"deleteStudent":true}` {
+		t.Errorf("expected the student to be deleted, got %s %v", result.Data, result.Errors)
+	}
+
+	_, result = postGraphQL(t, target.URL, key, `{ students(deleted: true) { nodes { id deletedAt } } }`, nil)
+	var trash struct {
+		Students struct {
+			Nodes []struct {
+				Id        int
+				DeletedAt *time.Time
+			}
+		}
+	}
+	_ = json.Unmarshal(result.Data, &trash)
+	if len(trash.Students.Nodes) != 1 || trash.Students.Nodes[0].DeletedAt == nil {
+		t.Errorf("expected student 1 in the trash, got %s %v", result.Data, result.Errors)
+	}
+
+	_, result = postGraphQL(t, target.URL, key, `mutation { deleteStudent(id: 1) }`, nil)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "NOT_FOUND" {
+		t.Errorf("expected NOT_FOUND deleting a deleted student, got %v", result.Errors)
+	}
+}
+
+func TestGraphQL_FiltersAndPagination(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+	for i, name := range []string{"Asha", "Bikram", "Asmita", "Chandan", "Ashok"} {
+		_ = store.CreateStudent(context.Background(), student.Student{Name: name, Age: 20 + i})
+	}
+
+	query := `query($after: Int) {
+		students(filter: {nameContains: "as", maxAge: 23}, first: 1, after: $after) { nodes { name } endCursor hasNextPage }
+	}`
+	var names []string
+	var after any
+	for range 3 {
+		_, result := postGraphQL(t, target.URL, key, query, map[string]any{"after": after})
+		var page struct {
+			Students struct {
+				Nodes       []student.Student
+				EndCursor   *int
+				HasNextPage bool
+			}
+		}
+		if err := json.Unmarshal(result.Data, &page); err != nil || len(result.Errors) != 0 {
+			t.Fatalf("Error listing students: %s %v", result.Data, result.Errors)
+		}
+		for _, s := range page.Students.Nodes {
+			names = append(names, s.Name)
+		}
+		if !page.Students.HasNextPage {
+			break
+		}
+		after = *page.Students.EndCursor
+	}
+
+	if strings.Join(names, ",") != "Asha,Asmita" {
+		t.Errorf("expected Asha and Asmita, got %v", names)
+	}
+}
+
+func TestGraphQL_BatchesStudentLookups(t *testing.T) {
+	store, s, target, key := newAPITest(t)
+	batch := &countingBatchStore{BatchStore: store}
+	s.Batch = batch
+	for _, name := range []string{"Swagnik", "Dutta", "Asha"} {
+		_ = store.CreateStudent(context.Background(), student.Student{Name: name, Age: 32})
+	}
+
+	_, result := postGraphQL(t, target.URL, key, `{
+		auditEvents { studentId student { name } }
+		a: student(id: 1) { name }
+		b: student(id: 2) { name }
+	}`, nil)
+	if len(result.Errors) != 0 || !strings.Contains(string(result.Data), `"b":{"name":"Dutta"}`) {
+		t.Fatalf("expected the students, got %s %v", result.Data, result.Errors)
+	}
+	if n := batch.batches.Load(); n != 1 {
+		t.Errorf("expected the students to be looked up in 1 batch, got %d", n)
+	}
+}
+
+func TestGraphQL_Failure_Authorization(t *testing.T) {
+	store, _, target, _ := newAPITest(t)
+	plain, key, _ := student.GenerateAPIKey("viewer", []string{student.ScopeStudentsRead})
+	_, _ = store.CreateAPIKey(context.Background(), key)
+
+	response, err := http.Post(target.URL+"/graphql", "application/json", strings.NewReader(`{"query": "{ students { nodes { id } } }"}`))
+	if err != nil {
+		t.Fatalf("Error sending graphql request: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status %d without credentials, got %d", http.StatusUnauthorized, response.StatusCode)
+	}
+
+	_, result := postGraphQL(t, target.URL, plain, `mutation { createStudent(input: {name: "Swagnik", age: 32}) }`, nil)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "FORBIDDEN" ||
+		result.Errors[0].Extensions["permission"] != student.ScopeStudentsWrite {
+		t.Errorf("expected FORBIDDEN naming students:write, got %v", result.Errors)
+	}
+
+	// the viewer still gets what it's allowed to see.
+	_, result = postGraphQL(t, target.URL, plain, `{ students { nodes { id } } auditEvents { id } }`, nil)
+	if len(result.Errors) != 1 || !strings.Contains(string(result.Data), `"students":{"nodes":[]}`) {
+		t.Errorf("expected the students along with an error for the audit log, got %s %v", result.Data, result.Errors)
+	}
+
+	denials, _ := store.ListAuditEvents(context.Background(), student.AuditFilter{Action: student.AuditAccessDenied})
+	if len(denials) != 2 {
+		t.Errorf("expected 2 denials to be audited, got %d", len(denials))
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, target.URL+"/graphql?query="+url.QueryEscape(`mutation { deleteStudent(id: 1) }`), nil)
+	request.Header.Set("X-API-Key", plain)
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error sending graphql request: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d for a mutation over GET, got %d", http.StatusMethodNotAllowed, response.StatusCode)
+	}
+}
+
+func TestGraphQL_Failure_Limits(t *testing.T) {
+	_, _, target, key := newAPITest(t)
+
+	for query, code := range map[string]string{
+		`{ auditEvents { student { auditEvents { student { auditEvents { student { auditEvents { student { id } } } } } } } } }`: "QUERY_TOO_DEEP",
+		`{ students(first: 1000) { nodes { auditEvents(first: 100) { id } } } }`:                                                 "QUERY_TOO_COMPLEX",
+	} {
+		_, result := postGraphQL(t, target.URL, key, query, nil)
+		if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != code {
+			t.Errorf("expected %s, got %v", code, result.Errors)
+		}
+	}
+
+	// introspection has a depth limit of its own, deep enough for the type references of the introspection query of
+	// GraphQL clients, but no deeper.
+	typeRef := `{ name }`
+	for range 7 {
+		typeRef = `{ kind name ofType ` + typeRef + ` }`
+	}
+	_, result := postGraphQL(t, target.URL, key, `{ __schema { types { name fields { name type `+typeRef+` } } } }`, nil)
+	if len(result.Errors) != 0 {
+		t.Errorf("expected introspection to be allowed, got %v", result.Errors)
+	}
+	nested := `{ name }`
+	for range 8 {
+		nested = `{ fields { type ` + nested + ` } }`
+	}
+	_, result = postGraphQL(t, target.URL, key, `{ __schema { types `+nested+` } }`, nil)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "QUERY_TOO_DEEP" {
+		t.Errorf("expected QUERY_TOO_DEEP, got %v", result.Errors)
+	}
+}
+
+func TestGraphQL_Subscription(t *testing.T) {
+	store, s, target, key := newAPITest(t)
+	s.Events = student.NewEventBroker(store, s.Logger)
+	s.Events.PollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := s.Events.Start(ctx); err != nil {
+		t.Fatalf("Error starting event broker: %v", err)
+	}
+
+	body, _ := json.Marshal(student.GraphQLRequest{Query: `subscription { studentChanged { id type student { name } } }`})
+	request, _ := http.NewRequestWithContext(ctx, http.MethodPost, target.URL+"/graphql", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "text/event-stream")
+	request.Header.Set("X-API-Key", key)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error opening subscription: %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.StatusCode)
+	}
+	messages := readSSE(bufio.NewReader(response.Body))
+
+	// the subscription has started once the stream is open, so this is the first change it sees.
+	if err := store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+	s.Events.Notify()
+
+	msg := nextEvent(t, messages)
+	if msg.event != "next" || msg.data != `{"data":{"studentChanged":{"id":1,"student":{"name":"Swagnik"},"type":"student.created"}}}` {
+		t.Errorf("expected the creation, got %+v", msg)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/studentpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCTest serves StudentService in memory, with event streams on, and returns a client along with a context that
+// carries an API key holding every permission in the default policy.
+func newGRPCTest(t *testing.T) (*student.SQLiteDataStore, *grpc.ClientConn, context.Context) {
+	store, s, _, key := newAPITest(t)
+	s.Events = student.NewEventBroker(store, s.Logger)
+	s.Events.PollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := s.Events.Start(ctx); err != nil {
+		t.Fatalf("Error starting event broker: %v", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := student.NewGRPCServer(s)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///students",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Error dialing grpc server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return store, conn, metadata.AppendToOutgoingContext(ctx, "x-api-key", key)
+}
+
+func TestGRPC_CRUD(t *testing.T) {
+	_, conn, ctx := newGRPCTest(t)
+	client := studentpb.NewStudentServiceClient(conn)
+
+	if _, err := client.Create(ctx, &studentpb.CreateStudentRequest{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+
+	got, err := client.Get(ctx, &studentpb.GetStudentRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("Error getting student: %v", err)
+	}
+	if got.GetId() != 1 || got.GetName() != "Swagnik" || got.GetAge() != 32 || got.GetDeletedAt() != nil {
+		t.Errorf("expected student 1, got %v", got)
+	}
+
+	if _, err := client.Update(ctx, &studentpb.UpdateStudentRequest{Id: 1, Name: "Swagnik Dutta", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+	list, err := client.List(ctx, &studentpb.ListStudentsRequest{})
+	if err != nil {
+		t.Fatalf("Error listing students: %v", err)
+	}
+	if len(list.GetStudents()) != 1 || list.GetStudents()[0].GetName() != "Swagnik Dutta" || list.GetResourceVersion() != 2 {
+		t.Errorf("expected the updated student at resource version 2, got %v", list)
+	}
+
+	if _, err := client.Delete(ctx, &studentpb.DeleteStudentRequest{Id: 1}); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+	if _, err := client.Get(ctx, &studentpb.GetStudentRequest{Id: 1}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for a deleted student, got %v", err)
+	}
+
+	trash, err := client.List(ctx, &studentpb.ListStudentsRequest{OnlyDeleted: true})
+	if err != nil || len(trash.GetStudents()) != 1 || trash.GetStudents()[0].GetDeletedAt() == nil {
+		t.Errorf("expected student 1 in the trash, got %v, %v", trash, err)
+	}
+}
+
+func TestGRPC_Failure_Errors(t *testing.T) {
+	store, conn, ctx := newGRPCTest(t)
+	client := studentpb.NewStudentServiceClient(conn)
+
+	_, err := client.Create(ctx, &studentpb.CreateStudentRequest{Name: " ", Age: 200})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument || len(st.Details()) != 1 {
+		t.Fatalf("expected InvalidArgument with details, got %v", err)
+	}
+	if violations := st.Details()[0].(*errdetails.BadRequest).GetFieldViolations(); len(violations) != 2 ||
+		violations[0].GetField() != "age" || violations[1].GetField() != "name" {
+		t.Errorf("expected violations for age and name, got %v", violations)
+	}
+
+	for _, id := range []int64{1, 42} {
+		if _, err := client.Update(ctx, &studentpb.UpdateStudentRequest{Id: id, Name: "Nobody", Age: 20}); status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound updating student %d, got %v", id, err)
+		}
+	}
+
+	if _, err := client.List(context.Background(), &studentpb.ListStudentsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without credentials, got %v", err)
+	}
+
+	// a viewer can neither delete nor look in the trash, and is told which permission it's missing.
+	plain, key, _ := student.GenerateAPIKey("viewer", []string{student.ScopeStudentsRead})
+	_, _ = store.CreateAPIKey(context.Background(), key)
+	viewer := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", plain)
+
+	for _, call := range []func() error{
+		func() error { _, err := client.Delete(viewer, &studentpb.DeleteStudentRequest{Id: 1}); return err },
+		func() error { _, err := client.List(viewer, &studentpb.ListStudentsRequest{OnlyDeleted: true}); return err },
+	} {
+		st := status.Convert(call())
+		if st.Code() != codes.PermissionDenied || len(st.Details()) != 1 {
+			t.Fatalf("expected PermissionDenied with details, got %v", st)
+		}
+		if info := st.Details()[0].(*errdetails.ErrorInfo); info.GetMetadata()["permission"] != student.ScopeStudentsDelete {
+			t.Errorf("expected the missing permission in the details, got %v", info)
+		}
+	}
+
+	denials, _ := store.ListAuditEvents(context.Background(), student.AuditFilter{Action: student.AuditAccessDenied})
+	if len(denials) != 2 {
+		t.Errorf("expected 2 denials to be audited, got %d", len(denials))
+	}
+}
+
+func TestGRPC_Watch(t *testing.T) {
+	store, conn, ctx := newGRPCTest(t)
+	client := studentpb.NewStudentServiceClient(conn)
+
+	if err := store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+
+	watch, err := client.Watch(ctx, &studentpb.WatchStudentsRequest{})
+	if err != nil {
+		t.Fatalf("Error opening watch: %v", err)
+	}
+	next := func() *studentpb.WatchEvent {
+		t.Helper()
+		for {
+			we, err := watch.Recv()
+			if err != nil {
+				t.Fatalf("Error receiving watch event: %v", err)
+			}
+			if we.GetType() != studentpb.WatchEvent_BOOKMARK {
+				return we
+			}
+		}
+	}
+
+	if we := next(); we.GetType() != studentpb.WatchEvent_ADDED || we.GetObject().GetId() != 1 || we.GetResourceVersion() != 1 {
+		t.Errorf("expected the existing student to be ADDED, got %v", we)
+	}
+
+	if _, err := client.Update(ctx, &studentpb.UpdateStudentRequest{Id: 1, Name: "Swagnik Dutta", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+	if we := next(); we.GetType() != studentpb.WatchEvent_MODIFIED || we.GetObject().GetName() != "Swagnik Dutta" || we.GetResourceVersion() != 2 {
+		t.Errorf("expected the update to be MODIFIED, got %v", we)
+	}
+
+	if _, err := client.Delete(ctx, &studentpb.DeleteStudentRequest{Id: 1}); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+	if we := next(); we.GetType() != studentpb.WatchEvent_DELETED || we.GetResourceVersion() != 3 {
+		t.Errorf("expected the delete to be DELETED, got %v", we)
+	}
+}
+
+func TestGRPC_Failure_WatchCompacted(t *testing.T) {
+	store, conn, ctx := newGRPCTest(t)
+	client := studentpb.NewStudentServiceClient(conn)
+
+	for _, name := range []string{"Swagnik", "Dutta"} {
+		_ = store.CreateStudent(ctx, student.Student{Name: name, Age: 32})
+	}
+	_ = store.MarkEventsDispatched(ctx, []int64{1, 2})
+	if _, err := store.DeleteDispatchedEvents(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Error compacting outbox: %v", err)
+	}
+
+	from := int64(1)
+	watch, err := client.Watch(ctx, &studentpb.WatchStudentsRequest{ResourceVersion: &from})
+	if err == nil {
+		_, err = watch.Recv()
+	}
+	st := status.Convert(err)
+	if st.Code() != codes.FailedPrecondition || len(st.Details()) != 1 {
+		t.Fatalf("expected FailedPrecondition with details, got %v", err)
+	}
+	if info := st.Details()[0].(*errdetails.ErrorInfo); info.GetReason() != "RESOURCE_VERSION_TOO_OLD" || info.GetMetadata()["oldest_resource_version"] != "2" {
+		t.Errorf("expected the oldest resource version in the details, got %v", info)
+	}
+}
+
+func TestGRPC_HealthAndReflection(t *testing.T) {
+	_, conn, _ := newGRPCTest(t)
+	// neither needs credentials.
+	ctx := context.Background()
+
+	health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "students.v1.StudentService"})
+	if err != nil || health.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected StudentService to be SERVING, got %v, %v", health, err)
+	}
+
+	reflection, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("Error opening reflection stream: %v", err)
+	}
+	_ = reflection.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	response, err := reflection.Recv()
+	if err != nil {
+		t.Fatalf("Error listing services: %v", err)
+	}
+
+	var services []string
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	if !strings.Contains(strings.Join(services, ","), "students.v1.StudentService") {
+		t.Errorf("expected StudentService to be listed, got %v", services)
+	}
+}
+
+func TestGRPC_MultiplexedWithHTTP(t *testing.T) {
+	store, s, _, key := newAPITest(t)
+	_ = store.CreateStudent(context.Background(), student.Student{Name: "Swagnik", Age: 32})
+
+	target := httptest.NewServer(student.MultiplexGRPC(student.NewGRPCServer(s), student.NewRequestMultiplexer(s)))
+	defer target.Close()
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(target.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Error dialing grpc server: %v", err)
+	}
+	defer conn.Close()
+
+	handled := expvar.Get("grpc_server_handled_total").(*expvar.Map)
+	count := func() int64 {
+		if v, ok := handled.Get(studentpb.StudentService_Get_FullMethodName + " OK").(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	before := count()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key, "x-request-id", "req-1")
+	var header metadata.MD
+	got, err := studentpb.NewStudentServiceClient(conn).Get(ctx, &studentpb.GetStudentRequest{Id: 1}, grpc.Header(&header))
+	if err != nil || got.GetName() != "Swagnik" {
+		t.Fatalf("expected student 1 over grpc, got %v, %v", got, err)
+	}
+	if ids := header.Get("x-request-id"); len(ids) != 1 || ids[0] != "req-1" {
+		t.Errorf("expected the request id to be echoed, got %v", ids)
+	}
+	if count() != before+1 {
+		t.Errorf("expected the call to be counted")
+	}
+
+	response, err := http.Get(target.URL + "/healthcheck")
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("expected the http api on the same port, got %v, %v", response, err)
+	}
+	response.Body.Close()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func sendIdempotent(handler http.Handler, principal, key, body string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodPost, "/api/v1/students/add", strings.NewReader(body))
+	request.Header.Set("Idempotency-Key", key)
+	ctx := context.WithValue(request.Context(), student.PrincipalKey, &student.Principal{Subject: principal})
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request.WithContext(ctx))
+	return response
+}
+
+func TestIdempotent_ReplaysFirstResponse(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+	handler := s.Idempotent(http.HandlerFunc(s.CreateStudent))
+
+	body := `{"name":"Swagnik","age":32}`
+	first := sendIdempotent(handler, "client", "retry-1", body)
+	second := sendIdempotent(handler, "client", "retry-1", body)
+
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("expected both responses to be %d, got %d and %d", http.StatusCreated, first.Code, second.Code)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("expected replayed body %q, got %q", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected the second response to be marked as replayed")
+	}
+
+	students, _ := store.ListStudents(context.Background(), student.ListOptions{})
+	if len(students) != 1 {
+		t.Errorf("expected 1 student to be created, got %d", len(students))
+	}
+
+	// keys are scoped to the caller, another client can use the same one.
+	if other := sendIdempotent(handler, "other-client", "retry-1", body); other.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected another client's request not to be replayed")
+	}
+}
+
+func TestIdempotent_Failure_KeyReused(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+	handler := s.Idempotent(http.HandlerFunc(s.CreateStudent))
+
+	_ = sendIdempotent(handler, "client", "retry-1", `{"name":"Swagnik","age":32}`)
+	response := sendIdempotent(handler, "client", "retry-1", `{"name":"Someone else","age":20}`)
+
+	statusWant := http.StatusUnprocessableEntity
+	statusGot := response.Code
+
+	if statusWant != statusGot {
+		t.Errorf("expected status %d, got %d", statusWant, statusGot)
+	}
+}
+
+func TestIdempotent_ServerErrorsAreNotStored(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	calls := 0
+	handler := s.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	_ = sendIdempotent(handler, "client", "retry-1", `{}`)
+	_ = sendIdempotent(handler, "client", "retry-1", `{}`)
+
+	if calls != 2 {
+		t.Errorf("expected the failed request to be retried, handler was called %d times", calls)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func sendImport(s *student.Server, query, contentType, body string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodPost, "/api/v1/students/import"+query, strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+
+	response := httptest.NewRecorder()
+	s.ImportStudents(response, request)
+	return response
+}
+
+func decodeImportReport(t *testing.T, response *httptest.ResponseRecorder) student.ImportReport {
+	t.Helper()
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, response.Code, response.Body.String())
+	}
+
+	var report student.ImportReport
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		t.Fatalf("Error decoding report: %v", err)
+	}
+	return report
+}
+
+func countStudents(t *testing.T, store student.Store) int {
+	t.Helper()
+
+	students, err := store.ListStudents(context.Background(), student.ListOptions{})
+	if err != nil {
+		t.Fatalf("Error listing students: %v", err)
+	}
+	return len(students)
+}
+
+func TestImport_CSVCreatesValidRows(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	body := "age,name\n32,Swagnik\nthirty,Dutta\n40,\n25,Someone\n"
+	report := decodeImportReport(t, sendImport(s, "", "text/csv; charset=utf-8", body))
+
+	if report.Total != 4 || report.Created != 2 || report.Failed != 2 || !report.Committed {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	want := []struct {
+		status string
+		field  string
+	}{
+		{student.ImportRowCreated, ""},
+		{student.ImportRowFailed, "age"},
+		{student.ImportRowFailed, "name"},
+		{student.ImportRowCreated, ""},
+	}
+	for i, w := range want {
+		row := report.Rows[i]
+		if row.Row != i+1 || row.Status != w.status {
+			t.Errorf("row %d: expected %s, got %+v", i+1, w.status, row)
+		}
+		if _, ok := row.Errors[w.field]; w.field != "" && !ok {
+			t.Errorf("row %d: expected an error for %s, got %+v", i+1, w.field, row.Errors)
+		}
+	}
+	if report.Rows[0].Id == 0 || report.Rows[3].Id == 0 {
+		t.Errorf("expected created rows to carry their ids, got %+v", report.Rows)
+	}
+
+	if n := countStudents(t, store); n != 2 {
+		t.Errorf("expected 2 students, got %d", n)
+	}
+}
+
+func TestImport_NDJSONDryRun(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	body := `{"name":"Swagnik","age":32}` + "\n" + `{"name":"Dutta","age":33}` + "\n"
+	report := decodeImportReport(t, sendImport(s, "?dry_run=true", "application/x-ndjson", body))
+
+	if report.Total != 2 || report.Created != 0 || report.Committed {
+		t.Errorf("unexpected report %+v", report)
+	}
+	for _, row := range report.Rows {
+		if row.Status != student.ImportRowValid {
+			t.Errorf("expected row %d to be valid, got %+v", row.Row, row)
+		}
+	}
+	if n := countStudents(t, store); n != 0 {
+		t.Errorf("expected a dry run not to create students, got %d", n)
+	}
+}
+
+func TestImport_AllOrNothing(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	body := `{"name":"Swagnik","age":32}` + "\n" + `{"name":"Dutta","age":"old"}` + "\n"
+	report := decodeImportReport(t, sendImport(s, "?all_or_nothing=true", "application/x-ndjson", body))
+
+	if report.Created != 0 || report.Failed != 1 || report.Committed {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.Rows[0].Status != student.ImportRowValid || report.Rows[0].Id != 0 {
+		t.Errorf("expected the valid row not to be created, got %+v", report.Rows[0])
+	}
+	if n := countStudents(t, store); n != 0 {
+		t.Errorf("expected no students to be created, got %d", n)
+	}
+
+	// nothing in the audit log either.
+	events, _ := store.ListAuditEvents(context.Background(), student.AuditFilter{})
+	if len(events) != 0 {
+		t.Errorf("expected no audit events, got %d", len(events))
+	}
+}
+
+func TestImport_Failure_UnsupportedMediaType(t *testing.T) {
+	s := student.NewServer(newTestSQLiteStore(t))
+	s.Logger = NewTestLogger()
+
+	response := sendImport(s, "", "application/json", `[{"name":"Swagnik","age":32}]`)
+	if response.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status code %d, got %d", http.StatusUnsupportedMediaType, response.Code)
+	}
+}
+
+func TestImport_LargeCSVInBatches(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	var body strings.Builder
+	body.WriteString("name,age\n")
+	for range 1201 {
+		body.WriteString("Swagnik,32\n")
+	}
+	report := decodeImportReport(t, sendImport(s, "", "text/csv", body.String()))
+
+	if report.Created != 1201 || report.Rows[1200].Id != 1201 {
+		t.Errorf("expected 1201 students to be created, got %d", report.Created)
+	}
+	if n := countStudents(t, store); n != 1201 {
+		t.Errorf("expected 1201 students, got %d", n)
+	}
+}
+
+func TestImport_MalformedAfterABatch(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	var body strings.Builder
+	body.WriteString("name,age\n")
+	for range 600 {
+		body.WriteString("Swagnik,32\n")
+	}
+	body.WriteString("\"Swag\"nik,32\nDutta,33\n")
+	response := sendImport(s, "", "text/csv", body.String())
+
+	if response.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusMultiStatus, response.Code, response.Body.String())
+	}
+	var report student.ImportReport
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		t.Fatalf("Error decoding report: %v", err)
+	}
+	if report.Total != 601 || report.Created != 600 || report.Failed != 1 || !report.Committed ||
+		!strings.Contains(report.Error, "row 601") {
+		t.Errorf("unexpected report %+v", report.Error)
+	}
+	if last := report.Rows[600]; last.Status != student.ImportRowFailed || last.Errors["row"] == nil {
+		t.Errorf("expected the row reading stopped at to fail, got %+v", last)
+	}
+	if report.Rows[599].Id != 600 {
+		t.Errorf("expected the rows before it to be created, got %+v", report.Rows[599])
+	}
+	if n := countStudents(t, store); n != 600 {
+		t.Errorf("expected 600 students, got %d", n)
+	}
+
+	// when nothing was created, the import is rejected as a whole.
+	response = sendImport(s, "?all_or_nothing=true", "text/csv", body.String())
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), "row 601") {
+		t.Errorf("expected status code %d, got %d: %s", http.StatusBadRequest, response.Code, response.Body.String())
+	}
+	if n := countStudents(t, store); n != 600 {
+		t.Errorf("expected no more students, got %d", n)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/jwttest"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func newTestJWTServer(t *testing.T, issuer *jwttest.Issuer) *student.Server {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := issuer.WriteJWKS(path); err != nil {
+		t.Fatalf("Error writing jwks: %v", err)
+	}
+
+	keys, err := student.NewJWKS(path, time.Minute)
+	if err != nil {
+		t.Fatalf("Error loading jwks: %v", err)
+	}
+
+	return &student.Server{
+		JWT: &student.JWTVerifier{
+			Issuer:     issuer.Issuer,
+			Audience:   issuer.Audience,
+			RolesClaim: "realm_access.roles",
+			Keys:       keys,
+		},
+		Logger: NewTestLogger(),
+	}
+}
+
+func TestAuthenticate_Bearer_Success(t *testing.T) {
+	issuer, err := jwttest.NewIssuer("https://auth.internal", "students-api")
+	if err != nil {
+		t.Fatalf("Error creating issuer: %v", err)
+	}
+	s := newTestJWTServer(t, issuer)
+
+	token, _ := issuer.Mint("svc-grades", jwt.MapClaims{
+		"scope":        "students:read",
+		"realm_access": map[string]any{"roles": []string{"viewer"}},
+	})
+
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response := httptest.NewRecorder()
+
+	var principal *student.Principal
+	s.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = student.PrincipalFromContext(r.Context())
+	})).ServeHTTP(response, request)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.Code)
+	}
+	if principal == nil || principal.Subject != "svc-grades" {
+		t.Fatalf("expected principal for svc-grades, got %+v", principal)
+	}
+	if !principal.HasRole("viewer") {
+		t.Errorf("expected role viewer, got %v", principal.Roles)
+	}
+}
+
+func TestAuthenticate_Bearer_Failure(t *testing.T) {
+	issuer, _ := jwttest.NewIssuer("https://auth.internal", "students-api")
+	s := newTestJWTServer(t, issuer)
+
+	other, _ := jwttest.NewIssuer("https://auth.internal", "students-api")
+
+	expired, _ := issuer.Mint("svc", jwt.MapClaims{"scope": "students:read", "exp": time.Now().Add(-time.Hour).Unix()})
+	wrongAudience, _ := issuer.Mint("svc", jwt.MapClaims{"scope": "students:read", "aud": "billing-api"})
+	wrongIssuer, _ := issuer.Mint("svc", jwt.MapClaims{"scope": "students:read", "iss": "https://evil.example"})
+	wrongKey, _ := other.Mint("svc", jwt.MapClaims{"scope": "students:read"})
+
+	tests := map[string]string{
+		"expired":        expired,
+		"wrong audience": wrongAudience,
+		"wrong issuer":   wrongIssuer,
+		"wrong key":      wrongKey,
+		"garbage":        "not-a-token",
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, "/api/v1/students", nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			response := httptest.NewRecorder()
+
+			s.Authenticate(okHandler(t)).ServeHTTP(response, request)
+
+			if response.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, response.Code)
+			}
+		})
+	}
+}
+
+func TestJWKS_FromURL(t *testing.T) {
+	issuer, _ := jwttest.NewIssuer("https://auth.internal", "students-api")
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(issuer.JWKS())
+	}))
+	defer jwksServer.Close()
+
+	keys, err := student.NewJWKS(jwksServer.URL, time.Minute)
+	if err != nil {
+		t.Fatalf("Error loading jwks: %v", err)
+	}
+
+	if _, err := keys.Key(issuer.KeyID); err != nil {
+		t.Errorf("expected key %q, got error %v", issuer.KeyID, err)
+	}
+}
+
+func TestJWKS_SkipsUnusableKeys(t *testing.T) {
+	issuer, _ := jwttest.NewIssuer("https://auth.internal", "students-api")
+	// Ed25519 keys aren't supported, but are often published next to RSA ones.
+	okp := map[string]string{
+		"kty": "OKP", "crv": "Ed25519", "kid": "ed", "use": "sig", "x": "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}
+
+	var set struct {
+		Keys []any `json:"keys"`
+	}
+	_ = json.Unmarshal(issuer.JWKS(), &set)
+	set.Keys = append([]any{okp}, set.Keys...)
+	mixed, _ := json.Marshal(set)
+	only, _ := json.Marshal(map[string]any{"keys": []any{okp}})
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	_ = os.WriteFile(path, mixed, 0o600)
+	keys, err := student.NewJWKS(path, time.Minute)
+	if err != nil {
+		t.Fatalf("Error loading jwks: %v", err)
+	}
+	if _, err := keys.Key(issuer.KeyID); err != nil {
+		t.Errorf("expected key %q, got error %v", issuer.KeyID, err)
+	}
+
+	_ = os.WriteFile(path, only, 0o600)
+	if _, err := student.NewJWKS(path, time.Minute); err == nil {
+		t.Errorf("expected a jwks without a usable key to be rejected")
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+func newNegotiationTest(t *testing.T) (*student.SQLiteDataStore, *student.Server) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	ctx := context.Background()
+	for _, name := range []string{"Swagnik", "Dutta"} {
+		if err := store.CreateStudent(ctx, student.Student{Name: name, Age: 32}); err != nil {
+			t.Fatalf("Error creating student: %v", err)
+		}
+	}
+	return store, s
+}
+
+func getStudent(s *student.Server, id int, accept string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students/1", nil)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	ctx := context.WithValue(request.Context(), student.StudentIdKey, id)
+
+	response := httptest.NewRecorder()
+	s.GetStudent(response, request.WithContext(ctx))
+	return response
+}
+
+func listStudents(s *student.Server, accept string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students", nil)
+	request.Header.Set("Accept", accept)
+
+	response := httptest.NewRecorder()
+	s.ListStudents(response, request)
+	return response
+}
+
+func TestNegotiation_RendersStudent(t *testing.T) {
+	_, s := newNegotiationTest(t)
+
+	tests := []struct {
+		accept      string
+		contentType string
+		decode      func(r io.Reader, v any) error
+	}{
+		{"", "application/json", func(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) }},
+		{"application/xml", "application/xml", func(r io.Reader, v any) error { return xml.NewDecoder(r).Decode(v) }},
+		{"text/xml", "application/xml", func(r io.Reader, v any) error { return xml.NewDecoder(r).Decode(v) }},
+		{"application/yaml", "application/yaml", func(r io.Reader, v any) error { return yaml.NewDecoder(r).Decode(v) }},
+		{"application/msgpack", "application/vnd.msgpack", func(r io.Reader, v any) error { return msgpack.NewDecoder(r).Decode(v) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			response := getStudent(s, 1, tt.accept)
+			if response.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, response.Code, response.Body)
+			}
+			if ct := response.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("expected Content-Type %q, got %q", tt.contentType, ct)
+			}
+
+			var got student.Student
+			if err := tt.decode(response.Body, &got); err != nil {
+				t.Fatalf("Error decoding response: %v", err)
+			}
+			want := student.Student{Id: 1, Name: "Swagnik", Age: 32}
+			if got != want {
+				t.Errorf("expected %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestNegotiation_XMLElements(t *testing.T) {
+	_, s := newNegotiationTest(t)
+
+	body := getStudent(s, 1, "application/xml").Body.String()
+	if !strings.Contains(body, "<student><id>1</id><name>Swagnik</name><age>32</age></student>") {
+		t.Errorf("unexpected xml %s", body)
+	}
+
+	body = listStudents(s, "application/xml").Body.String()
+	if !strings.Contains(body, "<students><student><id>1</id>") {
+		t.Errorf("unexpected xml %s", body)
+	}
+}
+
+func TestNegotiation_ListAsCSV(t *testing.T) {
+	_, s := newNegotiationTest(t)
+
+	response := listStudents(s, "text/csv")
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.Code)
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+
+	records, err := csv.NewReader(response.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Error reading csv: %v", err)
+	}
+	if len(records) != 3 || strings.Join(records[2], ",") != "2,Dutta,32," {
+		t.Errorf("unexpected records %v", records)
+	}
+}
+
+func TestNegotiation_QualityValues(t *testing.T) {
+	_, s := newNegotiationTest(t)
+
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{"application/yaml;q=0.5, application/xml", "application/xml"},
+		{"text/html, */*;q=0.1", "application/json"},
+		{"application/xml, application/json", "application/xml"},
+		{"application/*, application/json;q=0", "application/xml"},
+		{"text/*", "text/csv; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			response := listStudents(s, tt.accept)
+			if ct := response.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("expected Content-Type %q, got %q", tt.contentType, ct)
+			}
+		})
+	}
+}
+
+func TestNegotiation_Failure_NotAcceptable(t *testing.T) {
+	_, s := newNegotiationTest(t)
+
+	for _, accept := range []string{"text/html", "application/json;q=0"} {
+		response := listStudents(s, accept)
+		if response.Code != http.StatusNotAcceptable {
+			t.Errorf("%s: expected status %d, got %d", accept, http.StatusNotAcceptable, response.Code)
+		}
+	}
+
+	// csv is for lists only.
+	response := getStudent(s, 1, "text/csv")
+	if response.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected status %d, got %d", http.StatusNotAcceptable, response.Code)
+	}
+
+	var body student.ErrorResponse
+	_ = json.NewDecoder(response.Body).Decode(&body)
+	if body.Error != "not_acceptable" {
+		t.Errorf("expected a not_acceptable error, got %+v", body)
+	}
+}
+
+func TestNegotiation_DecodesRequestBodies(t *testing.T) {
+	store, s := newNegotiationTest(t)
+
+	yamlBody := "name: Yaml\nage: 20\n"
+	xmlBody := "<student><name>Xml</name><age>21</age></student>"
+	msgpackBody, _ := msgpack.Marshal(student.Student{Name: "Msgpack", Age: 22})
+
+	for _, tt := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"application/yaml", []byte(yamlBody)},
+		{"application/xml; charset=utf-8", []byte(xmlBody)},
+		{"application/vnd.msgpack", msgpackBody},
+	} {
+		request, _ := http.NewRequest(http.MethodPost, "/api/v1/students", bytes.NewReader(tt.body))
+		request.Header.Set("Content-Type", tt.contentType)
+		response := httptest.NewRecorder()
+		s.CreateStudent(response, request)
+
+		if response.Code != http.StatusCreated {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.contentType, http.StatusCreated, response.Code, response.Body)
+		}
+	}
+
+	students, _ := store.ListStudents(context.Background(), student.ListOptions{})
+	var names []string
+	for _, st := range students {
+		names = append(names, st.Name)
+	}
+	if got := strings.Join(names, ","); got != "Swagnik,Dutta,Yaml,Xml,Msgpack" {
+		t.Errorf("unexpected students %s", got)
+	}
+}
+
+func TestNegotiation_Failure_UnsupportedMediaType(t *testing.T) {
+	_, s := newNegotiationTest(t)
+
+	for _, contentType := range []string{"text/plain", "text/csv"} {
+		request, _ := http.NewRequest(http.MethodPost, "/api/v1/students", strings.NewReader("name,age\nA,20\n"))
+		request.Header.Set("Content-Type", contentType)
+		response := httptest.NewRecorder()
+		s.CreateStudent(response, request)
+
+		if response.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("%s: expected status %d, got %d", contentType, http.StatusUnsupportedMediaType, response.Code)
+		}
+	}
+}
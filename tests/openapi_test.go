@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// newAPITest serves the real mux, with an API key holding every permission in the default policy.
+func newAPITest(t *testing.T) (*student.SQLiteDataStore, *student.Server, *httptest.Server, string) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+	s.Policy = student.DefaultPolicy()
+
+	plain, key, err := student.GenerateAPIKey("tests", s.Policy.Roles["admin"])
+	if err != nil {
+		t.Fatalf("Error generating api key: %v", err)
+	}
+	if _, err := store.CreateAPIKey(context.Background(), key); err != nil {
+		t.Fatalf("Error creating api key: %v", err)
+	}
+
+	target := httptest.NewServer(student.NewRequestMultiplexer(s))
+	t.Cleanup(target.Close)
+	return store, s, target, plain
+}
+
+func openAPIDocument(t *testing.T) map[string]any {
+	response := httptest.NewRecorder()
+	student.NewRequestMultiplexer(student.NewServer(newTestSQLiteStore(t))).
+		ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.Code)
+	}
+	var doc map[string]any
+	if err := json.NewDecoder(response.Body).Decode(&doc); err != nil {
+		t.Fatalf("Error decoding openapi document: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPI_RoutesMatchDocument(t *testing.T) {
+	doc := openAPIDocument(t)
+	if doc["openapi"] != "3.1.0" {
+		t.Errorf("expected an openapi 3.1.0 document, got %v", doc["openapi"])
+	}
+
+	var routes, paths []string
+	public := make(map[string]bool)
+	for _, route := range student.Routes() {
+		routes = append(routes, route.Pattern)
+		public[route.Pattern] = route.Public
+	}
+	for path := range doc["paths"].(map[string]any) {
+		paths = append(paths, path)
+	}
+	slices.Sort(routes)
+	slices.Sort(paths)
+	if !slices.Equal(routes, paths) {
+		t.Errorf("routes and documented paths differ:\nroutes: %v\npaths:  %v", routes, paths)
+	}
+
+	// every operation on a protected route needs a permission, and every permission an operation.
+	operations, err := student.OpenAPIOperations()
+	if err != nil {
+		t.Fatalf("Error reading operations: %v", err)
+	}
+	var protected []string
+	for _, op := range operations {
+		if _, path, _ := strings.Cut(op, " "); !public[path] {
+			protected = append(protected, op)
+		}
+	}
+	var permitted []string
+	for route := range student.DefaultPolicy().Permissions {
+		permitted = append(permitted, route)
+	}
+	slices.Sort(protected)
+	slices.Sort(permitted)
+	if !slices.Equal(protected, permitted) {
+		t.Errorf("documented operations and the access policy differ:\noperations: %v\npolicy:     %v", protected, permitted)
+	}
+}
+
+func TestOpenAPI_SchemasMatchTypes(t *testing.T) {
+	schemas := openAPIDocument(t)["components"].(map[string]any)["schemas"].(map[string]any)
+
+	for name, v := range map[string]any{
+		"Student":           student.Student{},
+		"ErrorResponse":     student.ErrorResponse{},
+		"Revision":          student.Revision{},
+		"SearchResult":      student.SearchResult{},
+		"StudentStats":      student.StudentStats{},
+		"AgeBucket":         student.AgeBucket{},
+		"Course":            student.Course{},
+		"Enrollment":        student.Enrollment{},
+		"StudentCourse":     student.StudentCourse{},
+		"Assessment":        student.Assessment{},
+		"Score":             student.Score{},
+		"Transcript":        student.Transcript{},
+		"TranscriptCourse":  student.TranscriptCourse{},
+		"GradedAssessment":  student.GradedAssessment{},
+		"ClassSession":      student.ClassSession{},
+		"AttendanceMark":    student.AttendanceMark{},
+		"AttendanceSummary": student.AttendanceSummary{},
+		"AuditEvent":        student.AuditEvent{},
+		"Event":             student.Event{},
+		"WatchEvent":        student.WatchEvent{},
+		"Webhook":           student.Webhook{},
+		"WebhookDelivery":   student.WebhookDelivery{},
+		"ImportReport":      student.ImportReport{},
+		"ImportRow":         student.ImportRow{},
+	} {
+		var fields []string
+		typ := reflect.TypeOf(v)
+		for i := range typ.NumField() {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if name != "" && name != "-" {
+				fields = append(fields, name)
+			}
+		}
+
+		var properties []string
+		for property := range schemas[name].(map[string]any)["properties"].(map[string]any) {
+			properties = append(properties, property)
+		}
+
+		slices.Sort(fields)
+		slices.Sort(properties)
+		if !slices.Equal(fields, properties) {
+			t.Errorf("%s: fields %v and documented properties %v differ", name, fields, properties)
+		}
+	}
+}
+
+func TestOpenAPI_Docs(t *testing.T) {
+	_, _, target, _ := newAPITest(t)
+
+	response, err := http.Get(target.URL + "/docs/")
+	if err != nil {
+		t.Fatalf("Error getting docs: %v", err)
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK || !strings.Contains(string(body), "/openapi.json") {
+		t.Errorf("expected the docs to load /openapi.json, got %d: %.200s", response.StatusCode, body)
+	}
+}
+
+func TestOpenAPI_ValidatesRequests(t *testing.T) {
+	_, s, target, key := newAPITest(t)
+	validator, err := student.LoadOpenAPIValidator()
+	if err != nil {
+		t.Fatalf("Error loading validator: %v", err)
+	}
+	s.Validator = validator
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"valid", http.MethodPost, "/api/v1/students/add", "application/json", `{"name":"Swagnik","age":32}`, http.StatusCreated},
+		{"missing field", http.MethodPost, "/api/v1/students/add", "application/json", `{"name":"Swagnik"}`, http.StatusBadRequest},
+		{"out of range", http.MethodPost, "/api/v1/students/add", "", `{"name":"Swagnik","age":200}`, http.StatusBadRequest},
+		{"unsupported body", http.MethodPost, "/api/v1/students/add", "text/plain", `Swagnik`, http.StatusUnsupportedMediaType},
+		{"path parameter", http.MethodGet, "/api/v1/students/abc", "", "", http.StatusBadRequest},
+		{"query parameter", http.MethodGet, "/api/v1/students?deleted=all", "", "", http.StatusBadRequest},
+		{"required query parameter", http.MethodPost, "/api/v1/students/1/revert", "", "", http.StatusBadRequest},
+		{"integer query parameter", http.MethodGet, "/api/v1/audit?limit=0", "", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := http.NewRequest(tt.method, target.URL+tt.path, strings.NewReader(tt.body))
+			request.Header.Set("X-API-Key", key)
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatalf("Error sending request: %v", err)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.status {
+				body, _ := io.ReadAll(response.Body)
+				t.Fatalf("expected status %d, got %d: %s", tt.status, response.StatusCode, body)
+			}
+			if tt.status == http.StatusBadRequest {
+				var body student.ErrorResponse
+				_ = json.NewDecoder(response.Body).Decode(&body)
+				if body.Error != "invalid_request" {
+					t.Errorf("expected an invalid_request error, got %+v", body)
+				}
+			}
+		})
+	}
+}
+
+// TestOpenAPI_ResponsesMatchDocument runs through the API with response validation on, failing on any response that
+// isn't documented.
+func TestOpenAPI_ResponsesMatchDocument(t *testing.T) {
+	_, s, target, key := newAPITest(t)
+	validator, err := student.LoadOpenAPIValidator()
+	if err != nil {
+		t.Fatalf("Error loading validator: %v", err)
+	}
+	validator.ValidateResponses = true
+	validator.OnResponseError = func(r *http.Request, err error) {
+		t.Errorf("%s %s: %v", r.Method, r.URL, err)
+	}
+	s.Validator = validator
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer hook.Close()
+
+	send := func(method, path string, header http.Header, body string) {
+		request, _ := http.NewRequest(method, target.URL+path, strings.NewReader(body))
+		for k, v := range header {
+			request.Header[k] = v
+		}
+		request.Header.Set("X-API-Key", key)
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("Error sending %s %s: %v", method, path, err)
+		}
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+	}
+	accept := func(mediaType string) http.Header { return http.Header{"Accept": {mediaType}} }
+
+	send(http.MethodPost, "/api/v1/students/add", nil, `{"name":"Swagnik","age":32}`)
+	send(http.MethodPost, "/api/v1/students/add", http.Header{"Content-Type": {"application/yaml"}}, "name: Dutta\nage: 33\n")
+	send(http.MethodGet, "/api/v1/students", nil, "")
+	send(http.MethodGet, "/api/v1/students", accept("text/csv"), "")
+	send(http.MethodGet, "/api/v1/students", accept("text/html"), "")
+	send(http.MethodGet, "/api/v1/students/1", nil, "")
+	send(http.MethodGet, "/api/v1/students/1", accept("application/xml"), "")
+	send(http.MethodGet, "/api/v1/students/1?fields=name&include=revisions", nil, "")
+	send(http.MethodGet, "/api/v1/students?fields=id,age", nil, "")
+	send(http.MethodGet, "/api/v1/students?fields=height", nil, "")
+	send(http.MethodGet, "/api/v1/students/1?as_of="+time.Now().Add(-time.Hour).Format(time.RFC3339), nil, "")
+	send(http.MethodGet, "/api/v1/students/99", nil, "")
+	send(http.MethodPatch, "/api/v1/students/1", nil, `{"name":"Swagnik","age":33}`)
+	send(http.MethodDelete, "/api/v1/students/2", nil, "")
+	send(http.MethodGet, "/api/v1/students?deleted=only", nil, "")
+	send(http.MethodPost, "/api/v1/students/2/restore", nil, "")
+	send(http.MethodGet, "/api/v1/students/1/revisions", nil, "")
+	send(http.MethodPost, "/api/v1/students/1/revert?revision=1", nil, "")
+	send(http.MethodGet, "/api/v1/students/1/audit", nil, "")
+	send(http.MethodGet, "/api/v1/audit?limit=5", nil, "")
+	send(http.MethodPost, "/api/v1/students/import?dry_run=true", http.Header{"Content-Type": {"text/csv"}}, "name,age\nA,20\n,0\n")
+	send(http.MethodGet, "/api/v1/students/export?format=json", nil, "")
+	send(http.MethodGet, "/api/v1/students/search?q=swag&limit=1", nil, "")
+	send(http.MethodGet, "/api/v1/students/stats?buckets=20,30", nil, "")
+	send(http.MethodPost, "/api/v1/webhooks", nil, `{"url":"`+hook.URL+`","events":["student.created"]}`)
+	send(http.MethodGet, "/api/v1/webhooks", nil, "")
+	send(http.MethodGet, "/api/v1/webhooks/1", nil, "")
+	send(http.MethodGet, "/api/v1/webhooks/1/deliveries", nil, "")
+	send(http.MethodGet, "/api/v1/webhooks/dead-letters", nil, "")
+	send(http.MethodPost, "/api/v1/webhooks/1/deliveries/9/retry", nil, "")
+	send(http.MethodDelete, "/api/v1/webhooks/1", nil, "")
+	send(http.MethodPost, "/api/v1/courses", nil, `{"code":"SRE-101","title":"SRE","capacity":30,"start_date":"2026-11-02","end_date":"2027-01-29"}`)
+	send(http.MethodPost, "/api/v1/courses", nil, `{"code":"SRE-101","title":"SRE","capacity":30,"start_date":"2026-11-02","end_date":"2027-01-29"}`)
+	send(http.MethodPost, "/api/v1/courses", nil, `{"code":""}`)
+	send(http.MethodGet, "/api/v1/courses?limit=1", nil, "")
+	send(http.MethodGet, "/api/v1/courses/1", nil, "")
+	send(http.MethodPatch, "/api/v1/courses/1", nil, `{"code":"SRE-101","title":"SRE","capacity":40,"start_date":"2026-11-02","end_date":"2027-01-29"}`)
+	send(http.MethodPost, "/api/v1/courses/1/enrollments", nil, `{"student_id":1}`)
+	send(http.MethodPost, "/api/v1/courses/1/enrollments", nil, `{"student_id":1}`)
+	send(http.MethodGet, "/api/v1/courses/1/enrollments", nil, "")
+	send(http.MethodGet, "/api/v1/courses/1/enrollments/1", nil, "")
+	send(http.MethodGet, "/api/v1/students/1/courses", nil, "")
+	send(http.MethodPost, "/api/v1/courses/1/assessments", nil, `{"title":"Midterm","weight":40,"max_score":50}`)
+	send(http.MethodPost, "/api/v1/courses/1/assessments", nil, `{"title":"","weight":0}`)
+	send(http.MethodGet, "/api/v1/courses/1/assessments", nil, "")
+	send(http.MethodGet, "/api/v1/courses/1/assessments/1", nil, "")
+	send(http.MethodPatch, "/api/v1/courses/1/assessments/1", nil, `{"title":"Midterm","weight":50,"max_score":50}`)
+	send(http.MethodPut, "/api/v1/courses/1/assessments/1/scores", nil, `{"scores":[{"student_id":1,"score":42}]}`)
+	send(http.MethodPut, "/api/v1/courses/1/assessments/1/scores", nil, `{"scores":[{"student_id":2,"score":42}]}`)
+	send(http.MethodGet, "/api/v1/courses/1/assessments/1/scores", nil, "")
+	send(http.MethodGet, "/api/v1/students/1/transcript", nil, "")
+	send(http.MethodGet, "/api/v1/students/1/transcript", accept("text/html"), "")
+	send(http.MethodGet, "/api/v1/students/1/transcript?format=pdf", nil, "")
+	send(http.MethodGet, "/api/v1/students/1/transcript", accept("image/png"), "")
+	send(http.MethodDelete, "/api/v1/courses/1/assessments/1", nil, "")
+	send(http.MethodPost, "/api/v1/courses/1/sessions", nil, `{"topic":"Introduction","starts_at":"2026-11-02T10:00:00Z"}`)
+	send(http.MethodPost, "/api/v1/courses/1/sessions", nil, `{"topic":""}`)
+	send(http.MethodGet, "/api/v1/courses/1/sessions", nil, "")
+	send(http.MethodGet, "/api/v1/courses/1/sessions/1", nil, "")
+	send(http.MethodPatch, "/api/v1/courses/1/sessions/1", nil, `{"topic":"Introduction","starts_at":"2026-11-02T11:00:00Z"}`)
+	send(http.MethodPut, "/api/v1/courses/1/sessions/1/attendance", nil, `{"status":"present"}`)
+	send(http.MethodPut, "/api/v1/courses/1/sessions/1/attendance", nil, `{"marks":[{"student_id":2,"status":"absent"}]}`)
+	send(http.MethodPut, "/api/v1/courses/1/sessions/1/attendance", nil, `{"status":"asleep"}`)
+	send(http.MethodGet, "/api/v1/courses/1/sessions/1/attendance", nil, "")
+	send(http.MethodGet, "/api/v1/courses/1/attendance?below_threshold=true", nil, "")
+	send(http.MethodGet, "/api/v1/courses/1/attendance", nil, "")
+	send(http.MethodGet, "/api/v1/students/1/attendance", nil, "")
+	send(http.MethodDelete, "/api/v1/courses/1/sessions/1", nil, "")
+	send(http.MethodDelete, "/api/v1/students/1", nil, "")
+	send(http.MethodDelete, "/api/v1/courses/1/enrollments/1", nil, "")
+	send(http.MethodDelete, "/api/v1/courses/1/enrollments/1", nil, "")
+	send(http.MethodDelete, "/api/v1/courses/1", nil, "")
+	send(http.MethodGet, "/api/v1/courses/1", nil, "")
+	send(http.MethodPost, "/graphql", nil, `{"query":"{ students { nodes { id name } } }"}`)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// flakySink fails every delivery for the students in fail, and records the rest.
+type flakySink struct {
+	fail      map[int]bool
+	delivered []student.Event
+}
+
+func (s *flakySink) Deliver(_ context.Context, e student.Event) error {
+	if s.fail[e.StudentId] {
+		return errors.New("sink unavailable")
+	}
+	s.delivered = append(s.delivered, e)
+	return nil
+}
+
+func TestOutbox_DispatchesMutations(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	sink := student.NewChannelSink(10)
+	dispatcher := &student.Dispatcher{Outbox: store, Sinks: []student.EventSink{sink}, Logger: NewTestLogger()}
+
+	ctx := asActor("alice", "req-1")
+	if err := store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+	if err := store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+	if err := store.DeleteStudent(ctx, 1); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+
+	if _, err := dispatcher.DispatchPending(context.Background()); err != nil {
+		t.Fatalf("Error dispatching events: %v", err)
+	}
+
+	want := []string{student.AuditStudentCreated, student.AuditStudentUpdated, student.AuditStudentDeleted}
+	for i, typ := range want {
+		e := <-sink.C
+		if e.Type != typ || e.StudentId != 1 || e.Actor != "alice" || e.RequestId != "req-1" {
+			t.Errorf("event %d: expected %s by alice, got %+v", i, typ, e)
+		}
+		if e.Student == nil {
+			t.Errorf("event %d: expected the student's state", i)
+		}
+	}
+
+	// delivered events aren't dispatched again.
+	pending, err := store.PendingEvents(context.Background(), 10, nil)
+	if err != nil {
+		t.Fatalf("Error listing pending events: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected no pending events, got %d", len(pending))
+	}
+}
+
+func TestOutbox_FailedDeliveryIsRetriedInOrder(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	sink := &flakySink{fail: map[int]bool{1: true}}
+	dispatcher := &student.Dispatcher{Outbox: store, Sinks: []student.EventSink{sink}, Logger: NewTestLogger()}
+
+	ctx := context.Background()
+	for _, name := range []string{"Swagnik", "Dutta"} {
+		if err := store.CreateStudent(ctx, student.Student{Name: name, Age: 32}); err != nil {
+			t.Fatalf("Error creating student: %v", err)
+		}
+	}
+	if err := store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+
+	if _, err := dispatcher.DispatchPending(ctx); err != nil {
+		t.Fatalf("Error dispatching events: %v", err)
+	}
+	// a failing student doesn't hold up the others.
+	if len(sink.delivered) != 1 || sink.delivered[0].StudentId != 2 {
+		t.Fatalf("expected only student 2's event to be delivered, got %+v", sink.delivered)
+	}
+
+	sink.fail = nil
+	if _, err := dispatcher.DispatchPending(ctx); err != nil {
+		t.Fatalf("Error dispatching events: %v", err)
+	}
+	if len(sink.delivered) != 3 {
+		t.Fatalf("expected 3 delivered events, got %d", len(sink.delivered))
+	}
+	if sink.delivered[1].Type != student.AuditStudentCreated || sink.delivered[2].Type != student.AuditStudentUpdated {
+		t.Errorf("expected student 1's events in order, got %+v", sink.delivered[1:])
+	}
+}
+
+func TestOutbox_FailingStudentsDontStarveTheOthers(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	sink := &flakySink{fail: map[int]bool{1: true}}
+	dispatcher := &student.Dispatcher{Outbox: store, Sinks: []student.EventSink{sink}, Logger: NewTestLogger(), BatchSize: 2}
+
+	ctx := context.Background()
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32})
+	for age := 33; age <= 36; age++ {
+		_ = store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: age})
+	}
+	_ = store.CreateStudent(ctx, student.Student{Name: "Dutta", Age: 32})
+
+	// student 1's events fill more than a batch, but student 2's still get through in the same pass.
+	n, err := dispatcher.DispatchPending(ctx)
+	if err != nil {
+		t.Fatalf("Error dispatching events: %v", err)
+	}
+	if n != 1 || len(sink.delivered) != 1 || sink.delivered[0].StudentId != 2 {
+		t.Errorf("expected only student 2's event to be delivered, got %d: %+v", n, sink.delivered)
+	}
+}
+
+func TestOutbox_RunStopsWhileDeliveriesFail(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	sink := &flakySink{fail: map[int]bool{1: true, 2: true, 3: true}}
+	dispatcher := &student.Dispatcher{Outbox: store, Sinks: []student.EventSink{sink}, Logger: NewTestLogger(),
+		Interval: time.Hour, BatchSize: 2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i, name := range []string{"Swagnik", "Dutta", "Someone"} {
+		_ = store.CreateStudent(ctx, student.Student{Name: name, Age: 32})
+		_ = store.UpdateStudent(ctx, i+1, student.Student{Name: name, Age: 33})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the dispatcher to stop once its context is done")
+	}
+}
+
+func TestOutbox_FileSink(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink, err := student.NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Error opening file sink: %v", err)
+	}
+	defer sink.Close()
+	dispatcher := &student.Dispatcher{Outbox: store, Sinks: []student.EventSink{sink}, Logger: NewTestLogger()}
+
+	ctx := context.Background()
+	for _, name := range []string{"Swagnik", "Dutta"} {
+		if err := store.CreateStudent(ctx, student.Student{Name: name, Age: 32}); err != nil {
+			t.Fatalf("Error creating student: %v", err)
+		}
+	}
+	if _, err := dispatcher.DispatchPending(ctx); err != nil {
+		t.Fatalf("Error dispatching events: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Error opening events file: %v", err)
+	}
+	defer f.Close()
+
+	var ids []int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e student.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Error decoding event %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, e.Id)
+	}
+	if len(ids) != 2 || ids[0] >= ids[1] {
+		t.Errorf("expected 2 events in id order, got ids %v", ids)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/jwttest"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/mocks"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+	"go.uber.org/mock/gomock"
+)
+
+// newProtectedMux registers a stub handler behind Authenticate and Authorize on the same patterns as the real mux,
+// since the policy matches on them.
+func newProtectedMux(s *student.Server) http.Handler {
+	stub := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/students", s.Authenticate(s.Authorize(stub)))
+	mux.Handle("/api/v1/students/add", s.Authenticate(s.Authorize(stub)))
+	mux.Handle("/api/v1/students/{id}", s.Authenticate(s.Authorize(stub)))
+	mux.Handle("/api/v1/unlisted", s.Authenticate(s.Authorize(stub)))
+	return mux
+}
+
+func TestAuthorize_Roles(t *testing.T) {
+	issuer, _ := jwttest.NewIssuer("https://auth.internal", "students-api")
+	s := newTestJWTServer(t, issuer)
+	s.Policy = student.DefaultPolicy()
+	mux := newProtectedMux(s)
+
+	tests := []struct {
+		role       string
+		method     string
+		path       string
+		statusWant int
+	}{
+		{"viewer", http.MethodGet, "/api/v1/students", http.StatusOK},
+		{"viewer", http.MethodGet, "/api/v1/students/1", http.StatusOK},
+		{"viewer", http.MethodPost, "/api/v1/students/add", http.StatusForbidden},
+		{"editor", http.MethodPost, "/api/v1/students/add", http.StatusOK},
+		{"editor", http.MethodPatch, "/api/v1/students/1", http.StatusOK},
+		{"editor", http.MethodDelete, "/api/v1/students/1", http.StatusForbidden},
+		{"admin", http.MethodDelete, "/api/v1/students/1", http.StatusOK},
+		{"admin", http.MethodGet, "/api/v1/unlisted", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			token, _ := issuer.Mint("user-1", jwt.MapClaims{"realm_access": map[string]any{"roles": []string{tt.role}}})
+
+			request, _ := http.NewRequest(tt.method, tt.path, nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			response := httptest.NewRecorder()
+
+			mux.ServeHTTP(response, request)
+
+			if tt.statusWant != response.Code {
+				t.Errorf("expected status %d, got %d", tt.statusWant, response.Code)
+			}
+		})
+	}
+}
+
+func TestAuthorize_Failure_MissingScope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	plain, key, _ := student.GenerateAPIKey("dashboard", []string{student.ScopeStudentsRead, student.ScopeStudentsWrite})
+
+	request, _ := http.NewRequest(http.MethodDelete, "/api/v1/students/1", nil)
+	request.Header.Set("X-API-Key", plain)
+	response := httptest.NewRecorder()
+
+	mockKeys := mocks.NewMockAPIKeyStore(ctrl)
+	mockKeys.EXPECT().GetAPIKeyByHash(gomock.Any(), key.Hash).Return(&key, nil)
+	mockKeys.EXPECT().TouchAPIKey(gomock.Any(), key.Id).Return(nil)
+
+	s := &student.Server{
+		Keys:   mockKeys,
+		Logger: NewTestLogger(),
+	}
+	newProtectedMux(s).ServeHTTP(response, request)
+
+	statusWant := http.StatusForbidden
+	statusGot := response.Code
+
+	if statusWant != statusGot {
+		t.Fatalf("expected status %d, got %d", statusWant, statusGot)
+	}
+
+	var body student.ErrorResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Fatalf("expected a json error body: %v", err)
+	}
+	if body.Error != "forbidden" || body.Details["permission"] != student.ScopeStudentsDelete {
+		t.Errorf("unexpected error body %+v", body)
+	}
+}
+
+func TestParsePolicy_Invalid(t *testing.T) {
+	_, err := student.ParsePolicy([]byte(`{"permissions": {"/api/v1/students": "students:read"}}`))
+	if err == nil {
+		t.Errorf("expected an error for a route without a method")
+	}
+}
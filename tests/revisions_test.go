@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func TestRevisions_HistoryAndRevert(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := asActor("editor", "req-1")
+
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32})
+	time.Sleep(10 * time.Millisecond)
+	asOfCreate := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	_ = store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: 33})
+	_ = store.UpdateStudent(ctx, 1, student.Student{Name: "Oops", Age: 0})
+
+	revisions, err := store.ListRevisions(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Error listing revisions: %v", err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(revisions))
+	}
+	if revisions[2].Revision != 3 || revisions[2].Student.Name != "Oops" || revisions[2].Action != student.AuditStudentUpdated {
+		t.Errorf("unexpected latest revision %+v", revisions[2])
+	}
+
+	then, err := store.GetStudentAsOf(context.Background(), 1, asOfCreate)
+	if err != nil {
+		t.Fatalf("Error reading student as of creation: %v", err)
+	}
+	if then.Age != 32 {
+		t.Errorf("expected age 32 as of creation, got %d", then.Age)
+	}
+
+	if _, err := store.GetStudentAsOf(context.Background(), 1, asOfCreate.Add(-time.Hour)); err == nil {
+		t.Errorf("expected no student before it was created")
+	}
+
+	reverted, err := store.RevertStudent(ctx, 1, 2, false)
+	if err != nil {
+		t.Fatalf("Error reverting student: %v", err)
+	}
+	if reverted.Name != "Swagnik" || reverted.Age != 33 {
+		t.Errorf("expected revert to revision 2, got %+v", reverted)
+	}
+
+	revisions, _ = store.ListRevisions(context.Background(), 1)
+	if len(revisions) != 4 || revisions[3].Action != student.AuditStudentReverted {
+		t.Errorf("expected the revert to be recorded as revision 4, got %+v", revisions)
+	}
+
+	if _, err := store.RevertStudent(ctx, 1, 42, false); err == nil {
+		t.Errorf("expected reverting to a missing revision to fail")
+	}
+}
+
+func TestRevisions_RevertUndoesDelete(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := asActor("admin", "req-1")
+
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32})
+	_ = store.DeleteStudent(ctx, 1)
+
+	if _, err := store.GetStudentAsOf(ctx, 1, time.Now().Add(time.Second)); err == nil {
+		t.Errorf("expected a deleted student not to be found as of now")
+	}
+
+	if _, err := store.RevertStudent(ctx, 1, 1, true); err != nil {
+		t.Fatalf("Error reverting student: %v", err)
+	}
+	if _, err := store.GetStudent(ctx, 1); err != nil {
+		t.Errorf("expected reverted student to be live again, got %v", err)
+	}
+}
+
+func TestRevisions_RevertNeedsDeleteToMoveTheTrash(t *testing.T) {
+	store, s, target, admin := newAPITest(t)
+	editor, key, _ := student.GenerateAPIKey("editor", s.Policy.Roles["editor"])
+	_, _ = store.CreateAPIKey(context.Background(), key)
+
+	ctx := asActor("admin", "req-1")
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32})
+	_ = store.DeleteStudent(ctx, 1)
+
+	revert := func(key string, revision int) int {
+		url := fmt.Sprintf("%s/api/v1/students/1/revert?revision=%d", target.URL, revision)
+		code, _ := sendWithKey(t, http.MethodPost, url, key, "")
+		return code
+	}
+
+	// an editor can't bring a student back from the trash by reverting it, nor send one there.
+	if code := revert(editor, 1); code != http.StatusForbidden {
+		t.Errorf("expected status %d reverting out of the trash, got %d", http.StatusForbidden, code)
+	}
+	if _, err := store.GetStudent(ctx, 1); err == nil {
+		t.Errorf("expected the student to stay in the trash")
+	}
+
+	if code := revert(admin, 1); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if code := revert(editor, 2); code != http.StatusForbidden {
+		t.Errorf("expected status %d reverting into the trash, got %d", http.StatusForbidden, code)
+	}
+	if _, err := store.GetStudent(ctx, 1); err != nil {
+		t.Errorf("expected the student to stay live, got %v", err)
+	}
+
+	// reverts that leave the student where it is are fine.
+	if code := revert(editor, 3); code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, code)
+	}
+}
+
+func TestRevisions_Retention(t *testing.T) {
+	t.Setenv("REVISION_RETENTION_COUNT", "2")
+	store := newTestSQLiteStore(t)
+	ctx := asActor("editor", "req-1")
+
+	_ = store.CreateStudent(ctx, student.Student{Name: "A", Age: 1})
+	for age := 2; age <= 5; age++ {
+		_ = store.UpdateStudent(ctx, 1, student.Student{Name: "A", Age: age})
+	}
+
+	revisions, _ := store.ListRevisions(ctx, 1)
+	if len(revisions) != 2 || revisions[0].Revision != 4 || revisions[1].Revision != 5 {
+		t.Errorf("expected revisions 4 and 5 to be kept, got %+v", revisions)
+	}
+}
+
+func TestGetStudent_AsOf_Handler(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	_ = store.CreateStudent(asActor("editor", "req-1"), student.Student{Name: "Swagnik", Age: 32})
+	time.Sleep(10 * time.Millisecond)
+	asOf := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	_ = store.UpdateStudent(asActor("editor", "req-2"), 1, student.Student{Name: "Swagnik", Age: 40})
+
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students/1?as_of="+asOf.Format(time.RFC3339Nano), nil)
+	ctx := context.WithValue(request.Context(), student.StudentIdKey, 1)
+	response := httptest.NewRecorder()
+	s.GetStudent(response, request.WithContext(ctx))
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.Code)
+	}
+
+	var got student.Student
+	_ = json.NewDecoder(response.Body).Decode(&got)
+	if got.Age != 32 {
+		t.Errorf("expected age 32, got %d", got.Age)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/mocks"
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSearchStudents_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	results := []student.SearchResult{
+		{Student: student.Student{Id: 7, Name: "Swagnik Dutta", Age: 32}, Rank: 0.9, Snippet: "<mark>Swagnik</mark> Dutta"},
+		{Student: student.Student{Id: 3, Name: "Swagnika", Age: 28}, Rank: 0.4, Snippet: "<mark>Swagnika</mark>"},
+	}
+	mockSearch := mocks.NewMockSearchStore(ctrl)
+	mockSearch.EXPECT().SearchStudents(gomock.Any(), "swagnik", student.ListOptions{AfterId: 2, Limit: 2}).Return(results, nil)
+
+	s := &student.Server{Store: mocks.NewMockStore(ctrl), Search: mockSearch, Logger: NewTestLogger()}
+	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students/search?q=+swagnik+&after_id=2&limit=2", nil)
+	response := httptest.NewRecorder()
+	s.SearchStudents(response, request)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, response.Code)
+	}
+	var got []student.SearchResult
+	if err := json.NewDecoder(response.Body).Decode(&got); err != nil {
+		t.Fatalf("Error decoding results: %v", err)
+	}
+	if len(got) != 2 || got[0].Student.Id != 7 || got[0].Snippet != "<mark>Swagnik</mark> Dutta" {
+		t.Errorf("expected the results in rank order, got %+v", got)
+	}
+}
+
+func TestSearchStudents_Failure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tests := []struct {
+		name   string
+		search student.SearchStore
+		path   string
+		status int
+	}{
+		{"missing query", mocks.NewMockSearchStore(ctrl), "/api/v1/students/search?q=+", http.StatusBadRequest},
+		{"invalid limit", mocks.NewMockSearchStore(ctrl), "/api/v1/students/search?q=a&limit=0", http.StatusBadRequest},
+		{"not supported", nil, "/api/v1/students/search?q=a", http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &student.Server{Store: mocks.NewMockStore(ctrl), Search: tt.search, Logger: NewTestLogger()}
+			request, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			response := httptest.NewRecorder()
+			s.SearchStudents(response, request)
+
+			if response.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, response.Code)
+			}
+		})
+	}
+}
@@ -42,7 +42,7 @@ func TestCreateStudent_Success(t *testing.T) {
 	response := httptest.NewRecorder()
 
 	mockStore := mocks.NewMockStore(ctrl)
-	mockStore.EXPECT().CreateStudent(payload).Return(nil)
+	mockStore.EXPECT().CreateStudent(gomock.Any(), payload).Return(nil)
 
 	s := &student.Server{
 		Store: mockStore,
@@ -78,7 +78,7 @@ func TestCreateStudent_Failure_NotFound(t *testing.T) {
 	response := httptest.NewRecorder()
 
 	mockStore := mocks.NewMockStore(ctrl)
-	// mockStore.EXPECT().CreateStudent(payload).Return(10, nil)
+	// mockStore.EXPECT().CreateStudent(gomock.Any(), payload).Return(10, nil)
 
 	s := &student.Server{
 		Store: mockStore,
@@ -145,14 +145,14 @@ func TestGetStudent_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	studentId := 100
-	mockResponse := student.Student{100, "Swagnik", 32}
+	mockResponse := student.Student{Id: 100, Name: "Swagnik", Age: 32}
 
 	request, _ := http.NewRequest(http.MethodGet, "/api/v1/students/"+strconv.Itoa(studentId), nil)
 	ctx := context.WithValue(request.Context(), student.StudentIdKey, studentId)
 	response := httptest.NewRecorder()
 
 	mockStore := mocks.NewMockStore(ctrl)
-	mockStore.EXPECT().GetStudent(studentId).Return(&mockResponse, nil)
+	mockStore.EXPECT().GetStudent(gomock.Any(), studentId).Return(&mockResponse, nil)
 
 	s := &student.Server{
 		Store: mockStore,
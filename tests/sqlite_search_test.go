@@ -0,0 +1,106 @@
+//go:build sqlite_fts5
+
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func searchNames(t *testing.T, store *student.SQLiteDataStore, q string, opts student.ListOptions) []string {
+	t.Helper()
+
+	results, err := store.SearchStudents(context.Background(), q, opts)
+	if err != nil {
+		t.Fatalf("Error searching %q: %v", q, err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Student.Name)
+	}
+	return names
+}
+
+func TestSQLiteSearch_RanksAndPages(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+	for _, name := range []string{"Asha Rao", "Rao Rao", "Bikram Das", "Asha Das", "Raoul"} {
+		_ = store.CreateStudent(ctx, student.Student{Name: name, Age: 20})
+	}
+
+	// the name that says rao twice ranks first.
+	all := searchNames(t, store, "rao", student.ListOptions{})
+	if len(all) != 3 || all[0] != "Rao Rao" {
+		t.Fatalf("expected Rao Rao first, got %v", all)
+	}
+
+	var names []string
+	opts := student.ListOptions{Limit: 1}
+	for range 4 {
+		results, err := store.SearchStudents(ctx, "rao", opts)
+		if err != nil {
+			t.Fatalf("Error searching: %v", err)
+		}
+		if len(results) == 0 {
+			break
+		}
+		names = append(names, results[0].Student.Name)
+		opts.AfterId = results[0].Student.Id
+	}
+	if !slices.Equal(names, all) {
+		t.Errorf("expected the pages to follow the ranking %v, got %v", all, names)
+	}
+
+	if names := searchNames(t, store, "asha das", student.ListOptions{}); len(names) != 1 || names[0] != "Asha Das" {
+		t.Errorf("expected every word to match, got %v", names)
+	}
+}
+
+func TestSQLiteSearch_StaysInSync(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32})
+	_ = store.CreateStudent(ctx, student.Student{Name: "Dutta", Age: 32})
+
+	results, err := store.SearchStudents(ctx, "swag", student.ListOptions{})
+	if err != nil || len(results) != 1 || results[0].Snippet != "<mark>Swagnik</mark>" {
+		t.Fatalf("expected Swagnik to be highlighted, got %+v %v", results, err)
+	}
+
+	_ = store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik Dutta", Age: 32})
+	_ = store.DeleteStudent(ctx, 2)
+	if names := searchNames(t, store, "dutta", student.ListOptions{}); len(names) != 1 || names[0] != "Swagnik Dutta" {
+		t.Errorf("expected only the renamed student, got %v", names)
+	}
+
+	// FTS5 syntax is searched for as it is.
+	if names := searchNames(t, store, `dutta OR "swag* NEAR(`, student.ListOptions{}); len(names) != 0 {
+		t.Errorf("expected nothing to match, got %v", names)
+	}
+}
+
+func TestSQLiteSearch_EscapesSnippets(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik <img src=x onerror=alert(1)>", Age: 32})
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik \x02onerror", Age: 32})
+
+	results, err := store.SearchStudents(ctx, "swagnik", student.ListOptions{})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("expected both students to match, got %+v %v", results, err)
+	}
+	snippets := make(map[int]string)
+	for _, r := range results {
+		snippets[r.Student.Id] = r.Snippet
+	}
+	if got, want := snippets[1], "<mark>Swagnik</mark> &lt;img src=x onerror=alert(1)&gt;"; got != want {
+		t.Errorf("expected the name to be escaped, %q, got %q", want, got)
+	}
+	// a name can't mark itself.
+	if got, want := snippets[2], "Swagnik onerror"; got != want {
+		t.Errorf("expected the snippet to be left unmarked, %q, got %q", want, got)
+	}
+}
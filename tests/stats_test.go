@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func TestStudentStats_Success(t *testing.T) {
+	store, _, target, key := newAPITest(t)
+
+	status, body := getWithKey(t, target.URL+"/api/v1/students/stats", key, "")
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, status, body)
+	}
+	want := `{"count":0,"min_age":null,"max_age":null,"mean_age":null,"median_age":null,"age_histogram":[{"to":18,"count":0},{"from":18,"to":25,"count":0},{"from":25,"to":35,"count":0},{"from":35,"to":45,"count":0},{"from":45,"to":55,"count":0},{"from":55,"count":0}]}`
+	if body != want {
+		t.Errorf("expected empty stats %s, got %s", want, body)
+	}
+
+	ctx := context.Background()
+	for _, age := range []int{17, 20, 22, 31, 40, 99} {
+		_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: age})
+	}
+	// deleted students aren't counted.
+	_ = store.DeleteStudent(ctx, 6)
+
+	status, body = getWithKey(t, target.URL+"/api/v1/students/stats?buckets=20,30", key, "")
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, status, body)
+	}
+	want = `{"count":5,"min_age":17,"max_age":40,"mean_age":26,"median_age":22,"age_histogram":[{"to":20,"count":1},{"from":20,"to":30,"count":2},{"from":30,"count":2}]}`
+	if body != want {
+		t.Errorf("expected %s, got %s", want, body)
+	}
+
+	_ = store.DeleteStudent(ctx, 5)
+	_, body = getWithKey(t, target.URL+"/api/v1/students/stats?buckets=21", key, "")
+	var stats student.StudentStats
+	if err := json.Unmarshal([]byte(body), &stats); err != nil {
+		t.Fatalf("Error decoding stats: %v", err)
+	}
+	if stats.Count != 4 || *stats.MedianAge != 21 || stats.AgeHistogram[0].Count != 2 {
+		t.Errorf("expected the median between the middle two ages, got %s", body)
+	}
+}
+
+func TestStudentStats_Failure_InvalidBuckets(t *testing.T) {
+	_, _, target, key := newAPITest(t)
+
+	for _, buckets := range []string{"30,20", "20,20", "-1", "a"} {
+		status, body := getWithKey(t, target.URL+"/api/v1/students/stats?buckets="+buckets, key, "")
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d: %s", buckets, http.StatusBadRequest, status, body)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+func TestSoftDelete_RestoreAndPurge(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := asActor("admin", "req-1")
+
+	_ = store.CreateStudent(ctx, student.Student{Name: "A", Age: 20})
+	_ = store.CreateStudent(ctx, student.Student{Name: "B", Age: 21})
+
+	if err := store.DeleteStudent(ctx, 1); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+
+	if _, err := store.GetStudent(ctx, 1); err == nil {
+		t.Errorf("expected deleted student to be hidden from GetStudent")
+	}
+	if err := store.DeleteStudent(ctx, 1); err == nil {
+		t.Errorf("expected deleting a deleted student to fail")
+	}
+
+	live, _ := store.ListStudents(ctx, student.ListOptions{})
+	if len(live) != 1 || live[0].Id != 2 {
+		t.Errorf("expected only student 2 to be listed, got %+v", live)
+	}
+
+	trash, _ := store.ListStudents(ctx, student.ListOptions{OnlyDeleted: true})
+	if len(trash) != 1 || trash[0].Id != 1 || trash[0].DeletedAt == nil {
+		t.Errorf("expected student 1 in the trash, got %+v", trash)
+	}
+
+	if err := store.RestoreStudent(ctx, 1); err != nil {
+		t.Fatalf("Error restoring student: %v", err)
+	}
+	if _, err := store.GetStudent(ctx, 1); err != nil {
+		t.Errorf("expected restored student to be found, got %v", err)
+	}
+	if err := store.RestoreStudent(ctx, 2); err == nil {
+		t.Errorf("expected restoring a live student to fail")
+	}
+
+	_ = store.DeleteStudent(ctx, 1)
+
+	// nothing has been deleted for long enough yet.
+	n, err := store.PurgeStudents(ctx, time.Now().Add(-time.Hour))
+	if err != nil || n != 0 {
+		t.Fatalf("expected nothing to be purged, got %d (%v)", n, err)
+	}
+
+	n, err = store.PurgeStudents(ctx, time.Now().Add(time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 student to be purged, got %d (%v)", n, err)
+	}
+	if err := store.RestoreStudent(ctx, 1); err == nil {
+		t.Errorf("expected a purged student to be gone for good")
+	}
+
+	events, _ := store.ListAuditEvents(context.Background(), student.AuditFilter{Action: student.AuditStudentPurged})
+	if len(events) != 1 {
+		t.Errorf("expected the purge to be audited, got %d events", len(events))
+	}
+}
+
+func TestListStudents_Trash_AdminOnly(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+
+	tests := map[string]struct {
+		roles      []string
+		statusWant int
+	}{
+		"viewer": {[]string{"viewer"}, http.StatusForbidden},
+		"admin":  {[]string{"admin"}, http.StatusOK},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, "/api/v1/students?deleted=only", nil)
+			ctx := context.WithValue(request.Context(), student.PrincipalKey, &student.Principal{Subject: name, Roles: tt.roles})
+			response := httptest.NewRecorder()
+
+			s.ListStudents(response, request.WithContext(ctx))
+
+			if tt.statusWant != response.Code {
+				t.Errorf("expected status %d, got %d", tt.statusWant, response.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// openWatch starts a watch and returns a channel of its events, skipping bookmarks.
+func openWatch(t *testing.T, url string) (*http.Response, <-chan student.WatchEvent) {
+	t.Helper()
+
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Error opening watch: %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+
+	events := make(chan student.WatchEvent, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			var we student.WatchEvent
+			if err := json.Unmarshal(scanner.Bytes(), &we); err != nil || we.Type == student.WatchBookmark {
+				continue
+			}
+			events <- we
+		}
+	}()
+	return response, events
+}
+
+func nextWatchEvent(t *testing.T, events <-chan student.WatchEvent) student.WatchEvent {
+	t.Helper()
+
+	select {
+	case we, ok := <-events:
+		if !ok {
+			t.Fatalf("watch ended")
+		}
+		return we
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a watch event")
+	}
+	return student.WatchEvent{}
+}
+
+func TestWatch_FromListResourceVersion(t *testing.T) {
+	store, _, target := newEventsTest(t, (*student.Server).ListStudents, nil)
+
+	ctx := context.Background()
+	if err := store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+
+	list, err := http.Get(target.URL)
+	if err != nil {
+		t.Fatalf("Error listing students: %v", err)
+	}
+	list.Body.Close()
+	resourceVersion := list.Header.Get(student.ResourceVersionHeader)
+	if resourceVersion != "1" {
+		t.Fatalf("expected resource version 1, got %q", resourceVersion)
+	}
+
+	_, events := openWatch(t, target.URL+"?watch=true&resourceVersion="+resourceVersion)
+	if err := store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+	if err := store.DeleteStudent(ctx, 1); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+
+	for _, want := range []struct {
+		typ             string
+		resourceVersion int64
+	}{
+		{student.WatchModified, 2},
+		{student.WatchDeleted, 3},
+	} {
+		we := nextWatchEvent(t, events)
+		if we.Type != want.typ || we.ResourceVersion != want.resourceVersion || we.Object == nil || we.Object.Id != 1 {
+			t.Errorf("expected %s at %d, got %+v", want.typ, want.resourceVersion, we)
+		}
+	}
+}
+
+func TestWatch_WithoutResourceVersionStartsWithList(t *testing.T) {
+	store, _, target := newEventsTest(t, (*student.Server).ListStudents, nil)
+
+	ctx := context.Background()
+	for _, name := range []string{"Swagnik", "Dutta"} {
+		if err := store.CreateStudent(ctx, student.Student{Name: name, Age: 32}); err != nil {
+			t.Fatalf("Error creating student: %v", err)
+		}
+	}
+
+	_, events := openWatch(t, target.URL+"?watch=true")
+	for id := 1; id <= 2; id++ {
+		we := nextWatchEvent(t, events)
+		if we.Type != student.WatchAdded || we.ResourceVersion != 2 || we.Object.Id != id {
+			t.Errorf("expected student %d ADDED at 2, got %+v", id, we)
+		}
+	}
+
+	if err := store.CreateStudent(ctx, student.Student{Name: "Someone", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+	if we := nextWatchEvent(t, events); we.Type != student.WatchAdded || we.ResourceVersion != 3 {
+		t.Errorf("expected ADDED at 3, got %+v", we)
+	}
+}
+
+func TestWatch_RevertsInAndOutOfTheTrash(t *testing.T) {
+	store, _, target := newEventsTest(t, (*student.Server).ListStudents, nil)
+
+	ctx := context.Background()
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32})
+	_ = store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: 33})
+	_ = store.DeleteStudent(ctx, 1)
+
+	_, events := openWatch(t, target.URL+"?watch=true&resourceVersion=3")
+	// reverting a deleted student to a deleted revision leaves it out of the list, reverting it to a live one brings it
+	// back, and reverting it to a deleted one again takes it out.
+	for _, revision := range []int{3, 1, 3} {
+		if _, err := store.RevertStudent(ctx, 1, revision, true); err != nil {
+			t.Fatalf("Error reverting student: %v", err)
+		}
+	}
+
+	for _, want := range []struct {
+		typ             string
+		resourceVersion int64
+	}{
+		{student.WatchAdded, 5},
+		{student.WatchDeleted, 6},
+	} {
+		we := nextWatchEvent(t, events)
+		if we.Type != want.typ || we.ResourceVersion != want.resourceVersion || we.Object == nil || we.Object.Id != 1 {
+			t.Errorf("expected %s at %d, got %+v", want.typ, want.resourceVersion, we)
+		}
+	}
+}
+
+func TestWatch_Failure_Compacted(t *testing.T) {
+	store, _, target := newEventsTest(t, (*student.Server).ListStudents, nil)
+
+	ctx := context.Background()
+	for _, name := range []string{"Swagnik", "Dutta"} {
+		if err := store.CreateStudent(ctx, student.Student{Name: name, Age: 32}); err != nil {
+			t.Fatalf("Error creating student: %v", err)
+		}
+	}
+	if err := store.MarkEventsDispatched(ctx, []int64{1, 2}); err != nil {
+		t.Fatalf("Error marking events dispatched: %v", err)
+	}
+	if _, err := store.DeleteDispatchedEvents(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Error compacting outbox: %v", err)
+	}
+
+	response, err := http.Get(target.URL + "?watch=true&resourceVersion=1")
+	if err != nil {
+		t.Fatalf("Error opening watch: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusGone {
+		t.Errorf("expected status code %d, got %d", http.StatusGone, response.StatusCode)
+	}
+
+	// the resource version of a fresh list can still be watched from.
+	list, err := http.Get(target.URL)
+	if err != nil {
+		t.Fatalf("Error listing students: %v", err)
+	}
+	list.Body.Close()
+
+	watch, _ := openWatch(t, target.URL+"?watch=true&resourceVersion="+list.Header.Get(student.ResourceVersionHeader))
+	if watch.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, watch.StatusCode)
+	}
+}
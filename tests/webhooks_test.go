@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/swagnikdutta/one2n-sre-bootcamp/student"
+)
+
+// webhookReceiver records the deliveries it receives, and answers them with status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rec *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, r)
+	rec.bodies = append(rec.bodies, body)
+	w.WriteHeader(rec.status)
+}
+
+// registerWebhook creates a webhook through the API and returns its id and secret.
+func registerWebhook(t *testing.T, s *student.Server, body string) (int, string) {
+	t.Helper()
+
+	request, _ := http.NewRequest(http.MethodPost, "/api/v1/webhooks", strings.NewReader(body))
+	response := httptest.NewRecorder()
+	s.WebhooksHandler(response, request)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, response.Code, response.Body.String())
+	}
+
+	var created struct {
+		Id     int    `json:"id"`
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&created); err != nil {
+		t.Fatalf("Error decoding webhook: %v", err)
+	}
+	return created.Id, created.Secret
+}
+
+func newWebhookTest(t *testing.T) (*student.SQLiteDataStore, *student.Server, *student.Dispatcher) {
+	store := newTestSQLiteStore(t)
+	s := student.NewServer(store)
+	s.Logger = NewTestLogger()
+	dispatcher := &student.Dispatcher{
+		Outbox: store,
+		Sinks:  []student.EventSink{&student.WebhookSink{Webhooks: store}},
+		Logger: s.Logger,
+	}
+	return store, s, dispatcher
+}
+
+func TestWebhooks_DeliversSignedEvents(t *testing.T) {
+	store, s, dispatcher := newWebhookTest(t)
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+
+	webhookId, secret := registerWebhook(t, s,
+		`{"url":"`+target.URL+`","events":["student.created"],"secret":"topsecret"}`)
+	if secret != "topsecret" {
+		t.Errorf("expected the secret to be returned on creation, got %q", secret)
+	}
+
+	ctx := context.Background()
+	if err := store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+	// not subscribed to updates.
+	if err := store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: 33}); err != nil {
+		t.Fatalf("Error updating student: %v", err)
+	}
+	if _, err := dispatcher.DispatchPending(ctx); err != nil {
+		t.Fatalf("Error dispatching events: %v", err)
+	}
+
+	deliverer := &student.WebhookDeliverer{Webhooks: store, Logger: s.Logger}
+	if _, err := deliverer.DeliverDue(ctx); err != nil {
+		t.Fatalf("Error delivering webhooks: %v", err)
+	}
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(receiver.requests))
+	}
+	request, body := receiver.requests[0], receiver.bodies[0]
+	if request.Header.Get(student.WebhookEventHeader) != student.AuditStudentCreated {
+		t.Errorf("expected event header %q, got %q", student.AuditStudentCreated,
+			request.Header.Get(student.WebhookEventHeader))
+	}
+
+	want := student.SignWebhook("topsecret", request.Header.Get(student.WebhookTimestampHeader), body)
+	if !hmac.Equal([]byte(want), []byte(request.Header.Get(student.WebhookSignatureHeader))) {
+		t.Errorf("expected signature %q, got %q", want, request.Header.Get(student.WebhookSignatureHeader))
+	}
+
+	var e student.Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		t.Fatalf("Error decoding event: %v", err)
+	}
+	if e.Type != student.AuditStudentCreated || e.Student == nil || e.Student.Name != "Swagnik" {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	deliveries, err := store.ListWebhookDeliveries(ctx, webhookId)
+	if err != nil {
+		t.Fatalf("Error listing deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != student.WebhookDeliveryDelivered ||
+		deliveries[0].ResponseStatus != http.StatusNoContent {
+		t.Errorf("expected a single delivered delivery, got %+v", deliveries)
+	}
+}
+
+func TestWebhooks_RetriesThenDeadLetters(t *testing.T) {
+	store, s, dispatcher := newWebhookTest(t)
+	receiver := &webhookReceiver{status: http.StatusServiceUnavailable}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+
+	webhookId, _ := registerWebhook(t, s, `{"url":"`+target.URL+`","events":["student.deleted"]}`)
+
+	ctx := context.Background()
+	if err := store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32}); err != nil {
+		t.Fatalf("Error creating student: %v", err)
+	}
+	if err := store.DeleteStudent(ctx, 1); err != nil {
+		t.Fatalf("Error deleting student: %v", err)
+	}
+	if _, err := dispatcher.DispatchPending(ctx); err != nil {
+		t.Fatalf("Error dispatching events: %v", err)
+	}
+
+	deliverer := &student.WebhookDeliverer{
+		Webhooks:    store,
+		Logger:      s.Logger,
+		MaxAttempts: 3,
+		Backoff:     10 * time.Millisecond,
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		dead, err := store.ListDeadWebhookDeliveries(ctx)
+		if err != nil {
+			t.Fatalf("Error listing dead letters: %v", err)
+		}
+		if len(dead) == 1 {
+			if dead[0].Attempts != 3 || dead[0].ResponseStatus != http.StatusServiceUnavailable {
+				t.Errorf("expected 3 failed attempts, got %+v", dead[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the delivery to be dead-lettered, after %d attempts", len(receiver.requests))
+		}
+
+		if _, err := deliverer.DeliverDue(ctx); err != nil {
+			t.Fatalf("Error delivering webhooks: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(receiver.requests) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(receiver.requests))
+	}
+
+	// redriving a dead letter delivers it once the receiver is back.
+	deliveries, _ := store.ListWebhookDeliveries(ctx, webhookId)
+	request, _ := http.NewRequest(http.MethodPost, "/", nil)
+	request.SetPathValue("id", strconv.Itoa(webhookId))
+	request.SetPathValue("deliveryId", strconv.FormatInt(deliveries[0].Id, 10))
+	response := httptest.NewRecorder()
+	s.RetryWebhookDelivery(response, request)
+	if response.Code != http.StatusAccepted {
+		t.Fatalf("expected status code %d, got %d", http.StatusAccepted, response.Code)
+	}
+
+	receiver.status = http.StatusOK
+	if _, err := deliverer.DeliverDue(ctx); err != nil {
+		t.Fatalf("Error delivering webhooks: %v", err)
+	}
+	deliveries, _ = store.ListWebhookDeliveries(ctx, webhookId)
+	if deliveries[0].Status != student.WebhookDeliveryDelivered {
+		t.Errorf("expected the redriven delivery to be delivered, got %+v", deliveries[0])
+	}
+}
+
+func TestWebhooks_DeliversRestoresAndReverts(t *testing.T) {
+	store, s, dispatcher := newWebhookTest(t)
+	receiver := &webhookReceiver{status: http.StatusOK}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+
+	registerWebhook(t, s, `{"url":"`+target.URL+`","events":["student.updated","student.restored","student.reverted"]}`)
+
+	ctx := context.Background()
+	_ = store.CreateStudent(ctx, student.Student{Name: "Swagnik", Age: 32})
+	_ = store.UpdateStudent(ctx, 1, student.Student{Name: "Swagnik", Age: 33})
+	_ = store.DeleteStudent(ctx, 1)
+	if err := store.RestoreStudent(ctx, 1); err != nil {
+		t.Fatalf("Error restoring student: %v", err)
+	}
+	if _, err := store.RevertStudent(ctx, 1, 1, false); err != nil {
+		t.Fatalf("Error reverting student: %v", err)
+	}
+	if _, err := dispatcher.DispatchPending(ctx); err != nil {
+		t.Fatalf("Error dispatching events: %v", err)
+	}
+	deliverer := &student.WebhookDeliverer{Webhooks: store, Logger: s.Logger}
+	if _, err := deliverer.DeliverDue(ctx); err != nil {
+		t.Fatalf("Error delivering webhooks: %v", err)
+	}
+
+	var events []string
+	for _, request := range receiver.requests {
+		events = append(events, request.Header.Get(student.WebhookEventHeader))
+	}
+	if got, want := strings.Join(events, " "), "student.updated student.restored student.reverted"; got != want {
+		t.Errorf("expected %s to be delivered, got %s", want, got)
+	}
+}
+
+func TestWebhooks_Failure_InvalidWebhook(t *testing.T) {
+	_, s, _ := newWebhookTest(t)
+
+	for _, body := range []string{
+		`{"url":"not a url","events":["student.created"]}`,
+		`{"url":"https://example.com/hook","events":[]}`,
+		`{"url":"https://example.com/hook","events":["student.graduated"]}`,
+	} {
+		request, _ := http.NewRequest(http.MethodPost, "/api/v1/webhooks", strings.NewReader(body))
+		response := httptest.NewRecorder()
+		s.WebhooksHandler(response, request)
+
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", body, http.StatusBadRequest, response.Code)
+		}
+	}
+}